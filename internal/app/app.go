@@ -2,17 +2,18 @@ package app
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
-	"html/template"
 	"net/http"
-	"strconv"
 	"sync"
-	
+	"time"
+
 	"github.com/gin-gonic/gin"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"gorm.io/gorm"
-	
+
 	"shop-bot/internal/bot"
+	"shop-bot/internal/bot/messages"
 	"shop-bot/internal/broadcast"
 	"shop-bot/internal/cache"
 	"shop-bot/internal/config"
@@ -20,35 +21,10 @@ import (
 	logger "shop-bot/internal/log"
 	"shop-bot/internal/store"
 	"shop-bot/internal/ticket"
+	"shop-bot/internal/tmplfuncs"
 	"shop-bot/internal/worker"
 )
 
-// toFloat64 converts interface{} to float64
-func toFloat64(v interface{}) (float64, error) {
-	switch val := v.(type) {
-	case float64:
-		return val, nil
-	case float32:
-		return float64(val), nil
-	case int:
-		return float64(val), nil
-	case int32:
-		return float64(val), nil
-	case int64:
-		return float64(val), nil
-	case uint:
-		return float64(val), nil
-	case uint32:
-		return float64(val), nil
-	case uint64:
-		return float64(val), nil
-	case string:
-		return strconv.ParseFloat(val, 64)
-	default:
-		return 0, fmt.Errorf("cannot convert %T to float64", v)
-	}
-}
-
 // Application holds all application components
 type Application struct {
 	Config      *config.Config
@@ -63,6 +39,11 @@ type Application struct {
 
 	httpServer  *http.Server
 	wg          sync.WaitGroup
+
+	// webhookJobs bounds concurrent webhook update processing; handleWebhook
+	// enqueues here instead of spawning a goroutine per update.
+	webhookJobs   chan tgbotapi.Update
+	webhookWG     sync.WaitGroup
 }
 
 // New creates a new application instance
@@ -117,6 +98,7 @@ func New(cfg *config.Config, db *gorm.DB) (*Application, error) {
 		Broadcast:   broadcastService,
 		RetryWorker: retryWorker,
 		OrderMaintenanceWorker: orderMaintenanceWorker,
+		webhookJobs: make(chan tgbotapi.Update, cfg.WebhookWorkers*4),
 	}
 	
 	// Initialize ticket service if bot is available
@@ -128,12 +110,40 @@ func New(cfg *config.Config, db *gorm.DB) (*Application, error) {
 	
 	// Initialize HTTP admin server with access to bot
 	app.AdminServer = httpadmin.NewServerWithApp(cfg.AdminToken, app)
-	
+
+	// Layer admin-edited message overrides on top of the embedded catalog
+	if err := messages.GetManager().Reload(db); err != nil {
+		logger.Warn("Failed to load message overrides", "error", err)
+	}
+
 	return app, nil
 }
 
 // Start starts all application components
 func (app *Application) Start(ctx context.Context) error {
+	// Reservation sweeper runs independently of transport mode: webhook
+	// updates never pass through Bot.Start, so it can't live there.
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.Bot.RunReservationSweeper(ctx)
+	}()
+
+	// Auto-payment scheduler also runs independently of transport mode, for
+	// the same reason as the reservation sweeper above.
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.Bot.RunAutoPaymentScheduler(ctx)
+	}()
+
+	// Refund arbitration scheduler, same reasoning.
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.Bot.RunRefundArbitrationScheduler(ctx)
+	}()
+
 	// Start bot (polling or webhook mode)
 	if !app.Config.UseWebhook {
 		app.wg.Add(1)
@@ -145,11 +155,34 @@ func (app *Application) Start(ctx context.Context) error {
 			}
 		}()
 	} else {
-		// In webhook mode, just set the webhook
-		if err := app.Bot.SetWebhook(app.Config.WebhookURL + "/webhook/" + app.Bot.GetAPI().Token); err != nil {
-			return fmt.Errorf("failed to set webhook: %w", err)
+		// In webhook mode, register the webhook with Telegram; if that
+		// can't succeed after retrying, fall back to long-polling instead
+		// of coming up unable to receive any updates at all.
+		webhookURL := app.Config.WebhookURL + "/telegram/webhook"
+		if err := app.Bot.RegisterWebhook(ctx, webhookURL); err != nil {
+			logger.Error("Failed to register webhook, falling back to long-polling", "error", err)
+			app.Config.UseWebhook = false
+			app.wg.Add(1)
+			go func() {
+				defer app.wg.Done()
+				if err := app.Bot.Start(ctx); err != nil {
+					logger.Error("Bot stopped with error", "error", err)
+				}
+			}()
+		} else {
+			logger.Info("Webhook registered", "url", webhookURL)
+
+			// Bounded worker pool for webhook updates, so a burst of
+			// deliveries can't spawn unlimited goroutines.
+			workers := app.Config.WebhookWorkers
+			if workers <= 0 {
+				workers = 1
+			}
+			for i := 0; i < workers; i++ {
+				app.webhookWG.Add(1)
+				go app.runWebhookWorker(ctx)
+			}
 		}
-		logger.Info("Webhook set", "url", app.Config.WebhookURL)
 	}
 	
 	// Start HTTP server
@@ -174,7 +207,17 @@ func (app *Application) Start(ctx context.Context) error {
 		logger.Info("Starting order maintenance worker")
 		app.OrderMaintenanceWorker.Start(ctx)
 	}()
-	
+
+	// Start the Telegram send queue, so jobs enqueued via the admin
+	// "test notifier" endpoint and broadcast.Service.Send (both insert
+	// store.TelegramSendJob rows) actually get drained.
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		logger.Info("Starting Telegram send queue")
+		app.AdminServer.StartTelegramQueue(ctx)
+	}()
+
 	return nil
 }
 
@@ -210,90 +253,147 @@ func (app *Application) setupRouter() *gin.Engine {
 	
 	// Get currency settings
 	_, currencySymbol := store.GetCurrencySettings(app.DB, app.Config)
-	
+
 	// Add template functions
-	r.SetFuncMap(template.FuncMap{
-		"divf": func(a, b interface{}) float64 {
-			af, _ := toFloat64(a)
-			bf, _ := toFloat64(b)
-			if bf == 0 {
-				return 0
-			}
-			return af / bf
-		},
-		"addf": func(a, b interface{}) float64 {
-			af, _ := toFloat64(a)
-			bf, _ := toFloat64(b)
-			return af + bf
-		},
-		"subf": func(a, b interface{}) float64 {
-			af, _ := toFloat64(a)
-			bf, _ := toFloat64(b)
-			return af - bf
-		},
-		"int": func(a interface{}) int {
-			f, _ := toFloat64(a)
-			return int(f)
-		},
-		"seq": func(start, end int) []int {
-			var result []int
-			for i := start; i <= end; i++ {
-				result = append(result, i)
-			}
-			return result
-		},
-		"currency": func() string {
-			return currencySymbol
-		},
-		"plus": func(a, b int) int {
-			return a + b
-		},
-		"minus": func(a, b int) int {
-			return a - b
-		},
-		"multiply": func(a, b int) int {
-			return a * b
-		},
-	})
-	
+	r.SetFuncMap(tmplfuncs.FuncMap(currencySymbol))
+
 	// Load HTML templates
 	r.LoadHTMLGlob("templates/*.html")
 	
 	// Add all admin routes
 	app.AdminServer.SetupRoutes(r)
 	
-	// Add webhook route if enabled
+	// Add webhook route if enabled. /telegram/webhook is the canonical path
+	// used by RegisterWebhook; /webhook/:token is kept for callers still
+	// pointed at the old path-token scheme.
 	if app.Config.UseWebhook {
+		r.POST("/telegram/webhook", app.handleWebhook)
 		r.POST("/webhook/:token", app.handleWebhook)
 	}
-	
+
+	// Readiness: DB and cache connectivity, not just "process is alive"
+	// (the admin router already exposes /healthz and /metrics).
+	r.GET("/readyz", app.handleReadyz)
+
 	return r
 }
 
-// handleWebhook handles Telegram webhook updates
+// handleReadyz reports whether the app can actually serve traffic: the
+// database accepts a ping and, if configured, so does the Redis cache.
+func (app *Application) handleReadyz(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	if sqlDB, err := app.DB.DB(); err != nil || sqlDB.PingContext(c.Request.Context()) != nil {
+		ready = false
+		checks["database"] = "down"
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if err := app.Cache.Ping(c.Request.Context()); err != nil {
+		ready = false
+		checks["cache"] = "down"
+	} else {
+		checks["cache"] = "ok"
+	}
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"ready": ready, "checks": checks})
+}
+
+// handleWebhook handles Telegram webhook updates. It verifies the path
+// token and (if configured) the X-Telegram-Bot-Api-Secret-Token header,
+// drops updates already seen within the dedupe window, and hands the rest
+// to the bounded webhook worker pool instead of spawning a goroutine per
+// request.
 func (app *Application) handleWebhook(c *gin.Context) {
-	token := c.Param("token")
-	if token != app.Bot.GetAPI().Token {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+	// The legacy /webhook/:token path authenticates via the path segment;
+	// the canonical /telegram/webhook path (no :token param) relies solely
+	// on the secret-token header that RegisterWebhook always sets.
+	if token := c.Param("token"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(app.Bot.GetAPI().Token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
 	}
-	
+
+	if secret := app.Config.WebhookSecret; secret != "" {
+		header := c.GetHeader("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(header), []byte(secret)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+
 	var update tgbotapi.Update
 	if err := c.ShouldBindJSON(&update); err != nil {
 		logger.Error("Failed to parse webhook update", "error", err)
 		c.AbortWithStatus(http.StatusBadRequest)
 		return
 	}
-	
-	// Log webhook update
-	logger.Info("Received webhook update", "update_id", update.UpdateID, 
+
+	logger.Info("Received webhook update", "update_id", update.UpdateID,
 		"has_message", update.Message != nil,
 		"has_callback", update.CallbackQuery != nil)
-	
-	// Process update asynchronously
-	go app.Bot.HandleWebhookUpdate(update)
-	
-	c.Status(http.StatusOK)
+
+	ttl := time.Duration(app.Config.WebhookDedupeTTLSeconds) * time.Second
+	dedupeKey := fmt.Sprintf("webhook:dedupe:%d", update.UpdateID)
+	fresh, err := app.Cache.SetNX(c.Request.Context(), dedupeKey, 1, ttl)
+	if err != nil {
+		logger.Warn("Webhook dedupe check failed, processing anyway", "error", err, "update_id", update.UpdateID)
+	} else if !fresh {
+		logger.Info("Dropping duplicate webhook update", "update_id", update.UpdateID)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	select {
+	case app.webhookJobs <- update:
+		c.Status(http.StatusOK)
+	default:
+		logger.Warn("Webhook worker pool saturated, rejecting update", "update_id", update.UpdateID)
+		c.AbortWithStatus(http.StatusTooManyRequests)
+	}
+}
+
+// runWebhookWorker drains webhookJobs until the channel is closed (on
+// Shutdown), processing each update on the bot and emitting a tracing span
+// tagged with update_id, chat_id, and handler name.
+func (app *Application) runWebhookWorker(ctx context.Context) {
+	defer app.webhookWG.Done()
+
+	for update := range app.webhookJobs {
+		app.traceWebhookUpdate(update)
+	}
+}
+
+// traceWebhookUpdate processes a single update, logging a start/end span so
+// webhook latency and outcome can be traced per update_id.
+func (app *Application) traceWebhookUpdate(update tgbotapi.Update) {
+	chatID := int64(0)
+	handler := "unknown"
+	switch {
+	case update.Message != nil:
+		chatID = update.Message.Chat.ID
+		handler = "message"
+	case update.CallbackQuery != nil:
+		chatID = update.CallbackQuery.Message.Chat.ID
+		handler = "callback_query"
+	case update.InlineQuery != nil:
+		handler = "inline_query"
+	}
+
+	start := time.Now()
+	logger.Info("webhook span start", "update_id", update.UpdateID, "chat_id", chatID, "handler", handler)
+
+	app.Bot.HandleWebhookUpdate(update)
+
+	logger.Info("webhook span end", "update_id", update.UpdateID, "chat_id", chatID, "handler", handler,
+		"duration_ms", time.Since(start).Milliseconds())
 }
 
 // Wait waits for all components to finish
@@ -304,19 +404,39 @@ func (app *Application) Wait() {
 // Shutdown gracefully shuts down the application
 func (app *Application) Shutdown(ctx context.Context) error {
 	logger.Info("Shutting down application...")
-	
+
 	// Shutdown HTTP server
 	if app.httpServer != nil {
 		if err := app.httpServer.Shutdown(ctx); err != nil {
 			logger.Error("HTTP server shutdown error", "error", err)
 		}
 	}
-	
+
+	// Drain in-flight webhook updates, but don't wait past ctx's deadline.
+	if app.Config.UseWebhook {
+		if err := app.Bot.DeregisterWebhook(app.Config.WebhookDropPendingOnShutdown); err != nil {
+			logger.Warn("Failed to deregister webhook on shutdown", "error", err)
+		}
+
+		close(app.webhookJobs)
+		drained := make(chan struct{})
+		go func() {
+			app.webhookWG.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+			logger.Info("Webhook workers drained")
+		case <-ctx.Done():
+			logger.Warn("Webhook worker drain deadline exceeded, some updates may be dropped")
+		}
+	}
+
 	// Close cache
 	if app.Cache != nil {
 		app.Cache.Close()
 	}
-	
+
 	return nil
 }
 
@@ -0,0 +1,30 @@
+// Package tan generates the opaque nonces and numeric codes used by
+// httpadmin's challenge/solve flow for sensitive settings changes.
+package tan
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateNonce returns a random 32-byte hex token identifying a single
+// pending challenge to the client.
+func GenerateNonce() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GenerateNumericCode returns a random 6-digit code to send to the admin
+// via Telegram, zero-padded so it's always 6 characters.
+func GenerateNumericCode() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate code: %w", err)
+	}
+	n := (uint32(buf[0])<<24 | uint32(buf[1])<<16 | uint32(buf[2])<<8 | uint32(buf[3])) % 1000000
+	return fmt.Sprintf("%06d", n), nil
+}
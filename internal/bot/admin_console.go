@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/auth/tan"
+	"shop-bot/internal/broadcast"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// telegramLinkTokenTTL bounds how long a /link token stays valid before
+// the admin has to request a new one.
+const telegramLinkTokenTTL = 10 * time.Minute
+
+// lookupAdminByChat returns the active AdminUser bound to telegramID, the
+// same check twofactor.go's handlers already make before acting on an
+// admin command (router.go's handlers match telegram_id without is_active).
+func (b *Bot) lookupAdminByChat(telegramID int64) (*store.AdminUser, error) {
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", telegramID, true).First(&admin).Error; err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// handleAdminStats is "/stats": a quick ops snapshot of pending/paid order
+// counts and open ticket count.
+func (b *Bot) handleAdminStats(message *tgbotapi.Message) {
+	if _, err := b.lookupAdminByChat(message.From.ID); err != nil {
+		return
+	}
+
+	pending, _ := store.CountOrdersByStatus(b.db, "pending")
+	paid, _ := store.CountOrdersByStatus(b.db, "paid")
+	refunded, _ := store.CountOrdersByStatus(b.db, "refunded")
+	openTickets, _ := store.ListOpenTickets(b.db)
+
+	text := fmt.Sprintf(
+		"📊 Stats\npending orders: %d\npaid orders: %d\nrefunded orders: %d\nopen tickets: %d",
+		pending, paid, refunded, len(openTickets),
+	)
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+}
+
+// handleAdminOrders is "/orders [status]": the 10 most recent orders,
+// optionally filtered to one status.
+func (b *Bot) handleAdminOrders(message *tgbotapi.Message) {
+	if _, err := b.lookupAdminByChat(message.From.ID); err != nil {
+		return
+	}
+
+	status := strings.TrimSpace(strings.TrimPrefix(message.Text, "/orders"))
+	orders, err := store.ListRecentOrders(b.db, status, 10)
+	if err != nil {
+		logger.Error("Failed to list recent orders for /orders", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to load orders."))
+		return
+	}
+	if len(orders) == 0 {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "No orders found."))
+		return
+	}
+
+	var lines []string
+	for _, o := range orders {
+		lines = append(lines, fmt.Sprintf("#%d - %s", o.ID, o.Status))
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, strings.Join(lines, "\n")))
+}
+
+// handleAdminPending is "/pending", a shortcut for "/orders pending".
+func (b *Bot) handleAdminPending(message *tgbotapi.Message) {
+	message.Text = "/orders pending"
+	b.handleAdminOrders(message)
+}
+
+// handleAdminBan is "/ban <telegram_id>".
+func (b *Bot) handleAdminBan(message *tgbotapi.Message) {
+	admin, err := b.lookupAdminByChat(message.From.ID)
+	if err != nil {
+		return
+	}
+
+	arg := strings.TrimSpace(strings.TrimPrefix(message.Text, "/ban"))
+	telegramID, parseErr := strconv.ParseInt(arg, 10, 64)
+	if parseErr != nil || arg == "" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /ban <telegram_id>"))
+		return
+	}
+
+	if err := store.BanUser(b.db, telegramID); err != nil {
+		logger.Error("Failed to ban user", "error", err, "telegram_id", telegramID, "admin_id", admin.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to ban user."))
+		return
+	}
+	logger.Audit("User banned via Telegram admin console", "telegram_id", telegramID, "admin_id", admin.ID)
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("User %d banned.", telegramID)))
+}
+
+// handleAdminBroadcast is "/broadcast <message>": an immediate one-off
+// send to every user, with no A/B variants or draft step - for that, use
+// the web UI's broadcast campaigns (see httpadmin/broadcast.go).
+func (b *Bot) handleAdminBroadcast(message *tgbotapi.Message) {
+	admin, err := b.lookupAdminByChat(message.From.ID)
+	if err != nil {
+		return
+	}
+
+	body := strings.TrimSpace(strings.TrimPrefix(message.Text, "/broadcast"))
+	if body == "" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /broadcast <message>"))
+		return
+	}
+	if b.broadcast == nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Broadcast service not configured."))
+		return
+	}
+
+	sent, failed, err := b.broadcast.Send(0, broadcast.Template{Variants: map[string]string{"default": body}}, nil)
+	if err != nil {
+		logger.Error("Failed to send /broadcast", "error", err, "admin_id", admin.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Broadcast failed."))
+		return
+	}
+	logger.Audit("Ad-hoc broadcast sent via Telegram admin console", "admin_id", admin.ID, "sent", sent, "failed", failed)
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Sent to %d user(s), %d failed.", sent, failed)))
+}
+
+// handleLinkCommand is "/link": it issues a one-time token the admin pastes
+// into the web UI's account settings to bind this Telegram chat to their
+// dashboard account (see httpadmin.handleTelegramLink). Unlike the other
+// commands here, it doesn't require an existing AdminUser.TelegramID
+// binding - that's the whole point of this command.
+func (b *Bot) handleLinkCommand(message *tgbotapi.Message) {
+	token, err := tan.GenerateNonce()
+	if err != nil {
+		logger.Error("Failed to generate /link token", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to generate a link token, try again later."))
+		return
+	}
+
+	entry := &store.TelegramLinkToken{
+		Token:            token,
+		TelegramID:       message.From.ID,
+		TelegramUsername: message.From.UserName,
+		ExpiresAt:        time.Now().Add(telegramLinkTokenTTL),
+	}
+	if err := store.CreateTelegramLinkToken(b.db, entry); err != nil {
+		logger.Error("Failed to save /link token", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to generate a link token, try again later."))
+		return
+	}
+
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(
+		"Paste this code into the admin dashboard's \"Link Telegram\" screen within %d minutes:\n\n%s",
+		int(telegramLinkTokenTTL.Minutes()), token,
+	)))
+}
@@ -0,0 +1,371 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/bot/messages"
+	"shop-bot/internal/money"
+	"shop-bot/internal/payment"
+	"shop-bot/internal/store"
+)
+
+// autoPaymentPeriods maps the period callback suffix used throughout this
+// file to the number of seconds it represents, so the top-up and
+// auto-purchase menus can share one set of period buttons.
+var autoPaymentPeriods = []struct {
+	key     string
+	seconds int
+	label   string
+}{
+	{"daily", store.PeriodDaily, "autopay_period_daily"},
+	{"weekly", store.PeriodWeekly, "autopay_period_weekly"},
+	{"monthly", store.PeriodMonthly, "autopay_period_monthly"},
+}
+
+func periodSecondsForKey(key string) (int, bool) {
+	for _, p := range autoPaymentPeriods {
+		if p.key == key {
+			return p.seconds, true
+		}
+	}
+	return 0, false
+}
+
+// RunAutoPaymentScheduler periodically claims due subscriptions and settles
+// each one, regardless of transport mode (polling or webhook), so callers
+// should start it once as its own goroutine alongside RunReservationSweeper.
+func (b *Bot) RunAutoPaymentScheduler(ctx context.Context) {
+	interval := time.Duration(b.config.AutoPaymentSchedulerIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.processDueAutoPayments(ctx)
+		}
+	}
+}
+
+func (b *Bot) processDueAutoPayments(ctx context.Context) {
+	due, err := store.ClaimDueAutoPayments(ctx, b.db, b.config.AutoPaymentBatchSize)
+	if err != nil {
+		logger.Error("Failed to claim due auto payments", "error", err)
+		return
+	}
+
+	for _, autoPayment := range due {
+		b.runAutoPayment(ctx, autoPayment)
+	}
+}
+
+// runAutoPayment settles one due subscription: an auto-purchase deducts from
+// balance and delivers immediately if it covers the price, an auto-topup
+// always needs payment. Either way, whatever isn't covered by balance is
+// sent to the user as a DM with one "Pay Now" button per provider.
+func (b *Bot) runAutoPayment(ctx context.Context, autoPayment store.AutoPayment) {
+	var user store.User
+	if err := b.db.First(&user, autoPayment.UserID).Error; err != nil {
+		logger.Warn("Auto payment: user not found", "error", err, "auto_payment_id", autoPayment.ID)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, "")
+	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
+
+	var order *store.Order
+	var name string
+	var err error
+
+	if autoPayment.ProductID != nil {
+		product, productErr := store.GetProduct(b.db, *autoPayment.ProductID)
+		if productErr != nil {
+			logger.Warn("Auto payment: product not found", "error", productErr, "auto_payment_id", autoPayment.ID)
+			return
+		}
+		name = product.Name
+		order, err = store.CreateOrderWithBalance(b.db, user.ID, product.ID, autoPayment.AmountCents, true, "")
+	} else {
+		name = b.msg.Get(lang, "btn_deposit")
+		order, err = store.CreateDepositOrder(b.db, user.ID, autoPayment.AmountCents)
+	}
+	if err != nil {
+		logger.Error("Auto payment: failed to create order", "error", err, "auto_payment_id", autoPayment.ID)
+		return
+	}
+
+	if autoPayment.ProductID != nil && order.PaymentAmount == 0 {
+		code, claimErr := store.ClaimOneCodeTx(ctx, b.db, *autoPayment.ProductID, order.ID)
+		if claimErr != nil {
+			logger.Error("Auto payment: failed to claim code", "error", claimErr, "order_id", order.ID)
+			b.db.Model(order).Update("status", "failed_delivery")
+			return
+		}
+		now := time.Now()
+		b.db.Model(order).Updates(map[string]interface{}{"status": "delivered", "delivered_at": &now})
+
+		deliveryMsg := b.msg.Format(lang, "order_paid", map[string]interface{}{
+			"OrderID":     order.ID,
+			"ProductName": name,
+			"Code":        code,
+		})
+		b.api.Send(tgbotapi.NewMessage(user.TgUserID, deliveryMsg))
+		if err := store.RecordAutoPaymentRun(b.db, autoPayment.ID); err != nil {
+			logger.Error("Auto payment: failed to record run", "error", err, "auto_payment_id", autoPayment.ID)
+		}
+		logger.Info("Auto payment settled from balance", "auto_payment_id", autoPayment.ID, "order_id", order.ID)
+		return
+	}
+
+	outTradeNo := fmt.Sprintf("AUTO%d-%d", order.ID, time.Now().UnixNano())
+	if err := b.db.Model(&store.Order{}).Where("id = ?", order.ID).Update("epay_out_trade_no", outTradeNo).Error; err != nil {
+		logger.Error("Auto payment: failed to update out_trade_no", "error", err, "order_id", order.ID)
+	}
+
+	notifyURL := fmt.Sprintf("%s/payment/epay/notify", b.config.BaseURL)
+	returnURL := fmt.Sprintf("%s/payment/return", b.config.BaseURL)
+	paymentButtons := b.createPaymentButtons(ctx, lang, payment.OrderParams{
+		OutTradeNo:  outTradeNo,
+		Name:        name,
+		AmountCents: order.PaymentAmount,
+		NotifyURL:   notifyURL,
+		ReturnURL:   returnURL,
+		Param:       fmt.Sprintf("autopay_%d", autoPayment.ID),
+	})
+	if len(paymentButtons) == 0 {
+		logger.Warn("Auto payment: no payment provider configured, skipping notification", "auto_payment_id", autoPayment.ID)
+		return
+	}
+
+	autoPaymentMsg := b.msg.Format(lang, "auto_payment_due", map[string]interface{}{
+		"Currency": currencySymbol,
+		"Amount":   money.Format(order.PaymentAmountDecimal()),
+		"Name":     name,
+		"OrderID":  order.ID,
+	})
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, button := range paymentButtons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	msg := tgbotapi.NewMessage(user.TgUserID, autoPaymentMsg)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.api.Send(msg)
+
+	if err := store.RecordAutoPaymentRun(b.db, autoPayment.ID); err != nil {
+		logger.Error("Auto payment: failed to record run", "error", err, "auto_payment_id", autoPayment.ID)
+	}
+	logger.Info("Auto payment sent for payment", "auto_payment_id", autoPayment.ID, "order_id", order.ID)
+}
+
+// handleSubscriptions lists the user's auto top-ups and auto-purchases with
+// inline pause/resume and cancel buttons.
+func (b *Bot) handleSubscriptions(message *tgbotapi.Message) {
+	user, err := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
+	if err != nil {
+		logger.Error("Failed to get user", "error", err)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
+
+	autoPayments, err := store.GetActiveAutoPaymentsForUser(b.db, user.ID)
+	if err != nil {
+		logger.Error("Failed to list auto payments", "error", err, "user_id", user.ID)
+		b.sendError(message.Chat.ID, b.msg.Get(lang, "failed_to_process"))
+		return
+	}
+	if len(autoPayments) == 0 {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "no_subscriptions")))
+		return
+	}
+
+	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
+	for _, autoPayment := range autoPayments {
+		name := b.msg.Get(lang, "btn_deposit")
+		if autoPayment.ProductID != nil {
+			if product, err := store.GetProduct(b.db, *autoPayment.ProductID); err == nil {
+				name = product.Name
+			}
+		}
+
+		statusKey := "subscription_status_active"
+		toggleLabel := b.msg.Get(lang, "subscription_pause")
+		if !autoPayment.Enabled {
+			statusKey = "subscription_status_paused"
+			toggleLabel = b.msg.Get(lang, "subscription_resume")
+		}
+
+		text := b.msg.Format(lang, "subscription_line", map[string]interface{}{
+			"ID":       autoPayment.ID,
+			"Name":     name,
+			"Currency": currencySymbol,
+			"Amount":   money.Format(money.FromCents(autoPayment.AmountCents)),
+			"Status":   b.msg.Get(lang, statusKey),
+		})
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(toggleLabel, fmt.Sprintf("autopay_toggle:%d", autoPayment.ID)),
+				tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "subscription_cancel"), fmt.Sprintf("autopay_cancel:%d", autoPayment.ID)),
+			),
+		)
+
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		msg.ReplyMarkup = keyboard
+		b.api.Send(msg)
+	}
+}
+
+// handleAutoPaymentCallback routes every "autopay_..." callback: the
+// top-up/auto-purchase setup menus, and the pause/resume/cancel actions
+// surfaced by /subscriptions.
+func (b *Bot) handleAutoPaymentCallback(callback *tgbotapi.CallbackQuery) {
+	user, err := store.GetOrCreateUser(b.db, callback.From.ID, callback.From.UserName)
+	if err != nil {
+		logger.Error("Failed to get user", "error", err)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, callback.From.LanguageCode)
+
+	switch {
+	case callback.Data == "autopay_topup_menu":
+		b.showAutoTopupAmountMenu(callback, lang)
+	case strings.HasPrefix(callback.Data, "autopay_topup_amount:"):
+		amountStr := strings.TrimPrefix(callback.Data, "autopay_topup_amount:")
+		amountCents, convErr := strconv.Atoi(amountStr)
+		if convErr != nil {
+			return
+		}
+		b.showAutoPaymentPeriodMenu(callback, lang, fmt.Sprintf("autopay_topup_create:%d", amountCents))
+	case strings.HasPrefix(callback.Data, "autopay_topup_create:"):
+		parts := strings.Split(strings.TrimPrefix(callback.Data, "autopay_topup_create:"), ":")
+		if len(parts) != 2 {
+			return
+		}
+		amountCents, convErr := strconv.Atoi(parts[0])
+		if convErr != nil {
+			return
+		}
+		periodSeconds, ok := periodSecondsForKey(parts[1])
+		if !ok {
+			return
+		}
+		b.createAutoPayment(callback, lang, user.ID, amountCents, periodSeconds, nil)
+	case strings.HasPrefix(callback.Data, "autopay_buy_menu:"):
+		productIDStr := strings.TrimPrefix(callback.Data, "autopay_buy_menu:")
+		productID, convErr := strconv.ParseUint(productIDStr, 10, 32)
+		if convErr != nil {
+			return
+		}
+		b.showAutoPaymentPeriodMenu(callback, lang, fmt.Sprintf("autopay_buy_create:%d", productID))
+	case strings.HasPrefix(callback.Data, "autopay_buy_create:"):
+		parts := strings.Split(strings.TrimPrefix(callback.Data, "autopay_buy_create:"), ":")
+		if len(parts) != 2 {
+			return
+		}
+		productID64, convErr := strconv.ParseUint(parts[0], 10, 32)
+		if convErr != nil {
+			return
+		}
+		periodSeconds, ok := periodSecondsForKey(parts[1])
+		if !ok {
+			return
+		}
+		product, productErr := store.GetProduct(b.db, uint(productID64))
+		if productErr != nil {
+			b.sendError(callback.Message.Chat.ID, b.msg.Get(lang, "product_not_found"))
+			return
+		}
+		productID := uint(productID64)
+		b.createAutoPayment(callback, lang, user.ID, product.PriceCents, periodSeconds, &productID)
+	case strings.HasPrefix(callback.Data, "autopay_toggle:"):
+		b.toggleAutoPayment(callback, lang, user.ID)
+	case strings.HasPrefix(callback.Data, "autopay_cancel:"):
+		b.cancelAutoPayment(callback, lang, user.ID)
+	}
+}
+
+func (b *Bot) showAutoTopupAmountMenu(callback *tgbotapi.CallbackQuery, lang string) {
+	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s10", currencySymbol), "autopay_topup_amount:1000"),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s20", currencySymbol), "autopay_topup_amount:2000"),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s50", currencySymbol), "autopay_topup_amount:5000"),
+		),
+	)
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, b.msg.Get(lang, "autopay_topup_choose_amount"))
+	msg.ReplyMarkup = keyboard
+	b.api.Send(msg)
+}
+
+func (b *Bot) showAutoPaymentPeriodMenu(callback *tgbotapi.CallbackQuery, lang string, createPrefix string) {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range autoPaymentPeriods {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, p.label), fmt.Sprintf("%s:%s", createPrefix, p.key)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(row...))
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, b.msg.Get(lang, "autopay_choose_period"))
+	msg.ReplyMarkup = keyboard
+	b.api.Send(msg)
+}
+
+func (b *Bot) createAutoPayment(callback *tgbotapi.CallbackQuery, lang string, userID uint, amountCents int, periodSeconds int, productID *uint) {
+	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
+	autoPayment, err := store.CreateAutoPayment(b.db, userID, amountCents, currencySymbol, periodSeconds, productID, nil)
+	if err != nil {
+		logger.Error("Failed to create auto payment", "error", err, "user_id", userID)
+		b.sendError(callback.Message.Chat.ID, b.msg.Get(lang, "failed_to_process"))
+		return
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+	confirmMsg := b.msg.Format(lang, "autopay_created", map[string]interface{}{
+		"ID":       autoPayment.ID,
+		"Currency": currencySymbol,
+		"Amount":   money.Format(money.FromCents(amountCents)),
+	})
+	b.api.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, confirmMsg))
+}
+
+func (b *Bot) toggleAutoPayment(callback *tgbotapi.CallbackQuery, lang string, userID uint) {
+	idStr := strings.TrimPrefix(callback.Data, "autopay_toggle:")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return
+	}
+
+	autoPayment, err := store.GetAutoPayment(b.db, uint(id), userID)
+	if err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "subscription_not_found")))
+		return
+	}
+
+	if err := store.SetAutoPaymentEnabled(b.db, autoPayment.ID, userID, !autoPayment.Enabled); err != nil {
+		logger.Error("Failed to toggle auto payment", "error", err, "auto_payment_id", autoPayment.ID)
+		return
+	}
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+func (b *Bot) cancelAutoPayment(callback *tgbotapi.CallbackQuery, lang string, userID uint) {
+	idStr := strings.TrimPrefix(callback.Data, "autopay_cancel:")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return
+	}
+
+	if err := store.CancelAutoPayment(b.db, uint(id), userID); err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "subscription_not_found")))
+		return
+	}
+	b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "subscription_cancelled")))
+}
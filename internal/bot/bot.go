@@ -2,6 +2,8 @@ package bot
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -10,73 +12,510 @@ import (
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
 	logger "shop-bot/internal/log"
+	"shop-bot/internal/money"
 	"shop-bot/internal/store"
+	"shop-bot/internal/payment"
 	"shop-bot/internal/payment/epay"
 	"shop-bot/internal/config"
+	"shop-bot/internal/bot/dispatch"
+	"shop-bot/internal/bot/fsm"
 	"shop-bot/internal/bot/messages"
 	"shop-bot/internal/metrics"
+	"shop-bot/internal/bot/group"
 	"shop-bot/internal/broadcast"
 	"shop-bot/internal/notification"
+	"shop-bot/internal/notify"
+	"shop-bot/internal/ratelimit"
+	"shop-bot/internal/search"
+	"shop-bot/internal/secrets"
 	"gorm.io/gorm"
 )
 
+// groupAdminCacheTTL bounds how long a group's administrator set is cached
+// before an admin-only group command re-checks getChatAdministrators.
+const groupAdminCacheTTL = 5 * time.Minute
+
+// fsmSessionTTL bounds how long an abandoned conversation (e.g. a custom
+// deposit amount prompt nobody replies to) lingers before it's treated as expired.
+const fsmSessionTTL = 30 * time.Minute
+
+// StateHandler handles a message from a user currently in session.State,
+// registered via Bot.OnState.
+type StateHandler func(b *Bot, message *tgbotapi.Message, session *fsm.Session)
+
 type Bot struct {
 	api       *tgbotapi.BotAPI
 	db        *gorm.DB
-	epay      *epay.Client
+	// paymentProviders holds every configured payment.Provider (epay,
+	// crypto, ...); checkout renders one "Pay Now" button per provider so a
+	// single gateway outage doesn't block purchases entirely.
+	paymentProviders []payment.Provider
 	config    *config.Config
 	msg       *messages.Manager
 	broadcast *broadcast.Service
 	notification *notification.Service
 	ticketService TicketService // Remove pointer - interface should not be pointer
-	
-	// User state management
-	userStates     map[int64]string
-	userStatesMutex sync.RWMutex
+	productIndex *search.Index
+
+	// notifiers holds every configured notify.Notifier, keyed by channel
+	// name ("telegram", "email", "slack", "webhook"). "telegram", "slack"
+	// and "webhook" are always present; "email" is only registered when
+	// SMTP is configured.
+	notifiers map[string]notify.Notifier
+
+	// Conversation state management
+	fsm           *fsm.Engine
+	stateHandlers map[string]StateHandler
+
+	// dispatcher routes commands, callback queries, and text messages to
+	// registered handlers through a shared middleware chain.
+	dispatcher *dispatch.Dispatcher
+
+	// Flood protection: generalLimiter throttles every update by user ID,
+	// actionLimiter applies a stricter bucket on top of that for expensive
+	// callbacks (buy:, deposit_). rateLimitWarned remembers when a user was
+	// last sent the "slow down" message, so repeated throttled taps don't
+	// each get their own reply.
+	generalLimiter      *ratelimit.Limiter
+	actionLimiter       *ratelimit.Limiter
+	rateLimitWarned     map[int64]time.Time
+	rateLimitWarnedMu   sync.Mutex
+	rateLimitWarnWindow time.Duration
+
+	// confirmBuyIdempotency collapses a fast double tap on a "confirm
+	// purchase" button into a single order instead of racing CreateOrder/
+	// ClaimOneCodeTx twice.
+	confirmBuyIdempotency *ratelimit.IdempotencyCache
+
+	// groupRouter dispatches group/supergroup commands (/price, /stock, /link)
+	// separately from the private-chat dispatcher above.
+	groupRouter *group.Router
+
+	// Reporter receives errors returned by dispatched handlers (including
+	// recovered panics), so they surface somewhere instead of being
+	// silently dropped when a handler runs off the update loop's goroutine.
+	// Defaults to logging the error; callers may override it.
+	Reporter func(error)
 }
 
 // TicketService interface to avoid circular imports
 type TicketService interface {
 	GetTicketByUserMessage(userID int64) (*store.Ticket, error)
 	AddMessage(ticketID uint, senderType string, senderID int64, senderName, content string, messageID int) error
+	// AddRichMessage is like AddMessage but contentHTML carries the message's
+	// Telegram entities (bold/italic/links/code/mentions) converted to
+	// Telegram-flavored HTML, so the stored transcript preserves formatting.
+	AddRichMessage(ticketID uint, senderType string, senderID int64, senderName, contentHTML string, messageID int) error
 	CreateTicket(userID int64, username, subject, category, content string) (*store.Ticket, error)
 }
 
 func New(token string, db *gorm.DB, cfg *config.Config) (*Bot, error) {
+	if err := logger.Init(logger.Config{
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxAgeDays: cfg.LogMaxAgeDays,
+		MaxBackups: cfg.LogMaxBackups,
+		Level:      cfg.LogLevel,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to init logger: %w", err)
+	}
+
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot api: %w", err)
 	}
 
-	// Initialize epay client if configured
-	var epayClient *epay.Client
+	// Wire up encryption-at-rest for sensitive system settings before
+	// anything reads/writes them below. No MASTER_KEY means settings stay
+	// plaintext, matching the behavior before this existed.
+	if cfg.MasterKeySource != "" {
+		if secretsManager, err := secrets.NewManager(cfg.MasterKeySource); err != nil {
+			logger.Error("Failed to initialize secrets manager, settings will be stored in plaintext", "error", err)
+		} else {
+			store.ConfigureSecrets(secretsManager)
+		}
+	}
+
+	// Initialize every configured payment provider. Each is independent -
+	// missing config for one just leaves it out, rather than failing startup.
+	var paymentProviders []payment.Provider
 	if cfg.EpayPID != "" && cfg.EpayKey != "" && cfg.EpayGateway != "" {
-		epayClient = epay.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway)
-		logger.Info("Epay client initialized",
+		paymentProviders = append(paymentProviders, payment.NewEpayProvider(epay.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway)))
+		logger.Info("Epay provider initialized",
 			"pid", cfg.EpayPID,
 			"gateway", cfg.EpayGateway,
 			"base_url", cfg.BaseURL)
 	} else {
-		logger.Warn("Epay client not initialized - missing configuration",
+		logger.Warn("Epay provider not initialized - missing configuration",
 			"has_pid", cfg.EpayPID != "",
 			"has_key", cfg.EpayKey != "",
 			"has_gateway", cfg.EpayGateway != "")
 	}
-	
+	if cfg.OKXAPIKey != "" && cfg.OKXAPISecret != "" && cfg.OKXPassphrase != "" {
+		paymentProviders = append(paymentProviders, payment.NewCryptoProvider(payment.CryptoConfig{
+			APIKey:     cfg.OKXAPIKey,
+			APISecret:  cfg.OKXAPISecret,
+			Passphrase: cfg.OKXPassphrase,
+			BaseURL:    cfg.OKXBaseURL,
+			Currency:   cfg.OKXCurrency,
+		}))
+		logger.Info("Crypto payment provider initialized", "currency", cfg.OKXCurrency, "base_url", cfg.OKXBaseURL)
+	}
+	if cfg.AlipayAppID != "" && cfg.AlipayPrivateKey != "" && cfg.AlipayPublicKey != "" {
+		alipayProvider, err := payment.NewAlipayProvider(payment.AlipayConfig{
+			AppID:           cfg.AlipayAppID,
+			PrivateKey:      cfg.AlipayPrivateKey,
+			AlipayPublicKey: cfg.AlipayPublicKey,
+			IsProduction:    cfg.AlipayIsProduction,
+		})
+		if err != nil {
+			logger.Error("Failed to initialize Alipay provider", "error", err)
+		} else {
+			paymentProviders = append(paymentProviders, alipayProvider)
+			logger.Info("Alipay provider initialized", "app_id", cfg.AlipayAppID)
+		}
+	}
+	if cfg.WechatMchID != "" && cfg.WechatPrivateKey != "" && cfg.WechatAPIv3Key != "" {
+		wechatProvider, err := payment.NewWechatProvider(payment.WechatConfig{
+			AppID:         cfg.WechatAppID,
+			MchID:         cfg.WechatMchID,
+			SerialNo:      cfg.WechatSerialNo,
+			PrivateKeyPEM: cfg.WechatPrivateKey,
+			APIv3Key:      cfg.WechatAPIv3Key,
+		})
+		if err != nil {
+			logger.Error("Failed to initialize WeChat Pay provider", "error", err)
+		} else {
+			paymentProviders = append(paymentProviders, wechatProvider)
+			logger.Info("WeChat Pay provider initialized", "mch_id", cfg.WechatMchID)
+		}
+	}
+
 	// Initialize notification service
 	notificationService := notification.NewService(api, cfg, db)
 
-	return &Bot{
-		api:    api,
-		db:     db,
-		epay:   epayClient,
-		config: cfg,
-		msg:    messages.GetManager(),
-		broadcast: broadcast.NewService(db, api),
-		notification: notificationService,
-		userStates: make(map[int64]string),
-	}, nil
+	// Register every configured ticket-reply notifier. Telegram is always
+	// available; email/Slack/webhook are per-user opt-in channels (see
+	// store.UserChannel) and email doubles as the automatic fallback when a
+	// Telegram delivery fails.
+	notifiers := map[string]notify.Notifier{
+		"telegram": notify.NewTelegramNotifier(api),
+		"slack":    notify.NewSlackNotifier(),
+		"webhook":  notify.NewWebhookNotifier(),
+	}
+	if cfg.SMTPHost != "" {
+		notifiers["email"] = notify.NewEmailNotifier(notify.EmailConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	} else {
+		logger.Warn("Email notifier not initialized - SMTP_HOST not configured")
+	}
+
+	b := &Bot{
+		api:           api,
+		db:            db,
+		paymentProviders: paymentProviders,
+		config:        cfg,
+		msg:           messages.GetManager(),
+		broadcast:     broadcast.NewService(db, api),
+		notification:  notificationService,
+		notifiers:     notifiers,
+		productIndex:  search.NewIndex(),
+		fsm:           fsm.NewEngine(db, fsmSessionTTL),
+		stateHandlers: make(map[string]StateHandler),
+
+		generalLimiter:        ratelimit.NewLimiter(cfg.RateLimitBurst, cfg.RateLimitRefillPerSec),
+		actionLimiter:         ratelimit.NewLimiter(cfg.RateLimitActionBurst, cfg.RateLimitActionRefillPerSec),
+		rateLimitWarned:       make(map[int64]time.Time),
+		rateLimitWarnWindow:   time.Duration(cfg.RateLimitWarnWindowSeconds) * time.Second,
+		confirmBuyIdempotency: ratelimit.NewIdempotencyCache(10 * time.Second),
+	}
+
+	if err := b.RefreshProductIndex(); err != nil {
+		logger.Warn("Failed to build initial product search index", "error", err)
+	}
+
+	b.OnState("awaiting_deposit_amount", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleCustomDepositAmount(message)
+	})
+
+	b.OnState("awaiting_refund_reason", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleRefundReason(message, session)
+	})
+	b.OnState("awaiting_refund_partial_amount", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleRefundPartialAmount(message, session)
+	})
+	b.OnState("awaiting_refund_reject_reason", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleRefundRejectReason(message, session)
+	})
+	b.OnState("awaiting_router_reply", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleRouterReplyText(message, session)
+	})
+	b.OnState("awaiting_2fa_confirm", func(bot *Bot, message *tgbotapi.Message, session *fsm.Session) {
+		bot.handleTwoFactorConfirm(message)
+	})
+
+	b.Reporter = func(err error) {
+		logger.Error("Unhandled dispatcher error", "error", err)
+	}
+
+	b.dispatcher = dispatch.New()
+	b.dispatcher.Use(loggingMiddleware)
+	b.dispatcher.Use(metricsMiddleware)
+	b.dispatcher.Use(b.i18nMiddleware)
+	b.dispatcher.Use(b.adminCheckMiddleware)
+	b.dispatcher.Use(b.rateLimitMiddleware)
+	b.dispatcher.SetReporter(func(err error) { b.Reporter(err) })
+	b.registerHandlers()
+
+	b.groupRouter = group.NewRouter(api, api.Self.UserName, groupAdminCacheTTL)
+	b.groupRouter.SetReporter(func(err error) { b.Reporter(err) })
+	b.registerGroupCommands()
+
+	return b, nil
+}
+
+// registerHandlers wires the built-in commands, callback queries, and text
+// messages into the dispatcher. Feature packages can register additional
+// routes the same way via b.dispatcher (or, once exposed, Bot.Handle/
+// Bot.HandleCallback/Bot.HandleText) without touching this function.
+func (b *Bot) registerHandlers() {
+	b.dispatcher.Handle("start", b.wrapMessage(b.handleStart))
+	b.dispatcher.Handle("find", b.wrapMessage(b.handleFind))
+	b.dispatcher.Handle("cancel", b.wrapMessage(b.handleCancelCommand))
+	b.dispatcher.Handle("subscriptions", b.wrapMessage(b.handleSubscriptions))
+	b.dispatcher.Handle("reissueinvite", b.wrapMessage(b.handleReissueInvite))
+	b.dispatcher.Handle("refund", b.wrapMessage(b.handleRefundCommand))
+	b.dispatcher.Handle("reply", b.wrapMessage(b.handleAdminRouterCommand))
+	b.dispatcher.Handle("close", b.wrapMessage(b.handleAdminRouterCommand))
+	b.dispatcher.Handle("assign", b.wrapMessage(b.handleAdminRouterCommand))
+	b.dispatcher.Handle("list", b.wrapMessage(b.handleAdminRouterCommand))
+	b.dispatcher.Handle("search", b.wrapMessage(b.handleAdminRouterCommand))
+	b.dispatcher.Handle("2fa_enroll", b.wrapMessage(b.handleTwoFactorEnroll))
+	b.dispatcher.Handle("stats", b.wrapMessage(b.handleAdminStats))
+	b.dispatcher.Handle("orders", b.wrapMessage(b.handleAdminOrders))
+	b.dispatcher.Handle("pending", b.wrapMessage(b.handleAdminPending))
+	b.dispatcher.Handle("ban", b.wrapMessage(b.handleAdminBan))
+	b.dispatcher.Handle("broadcast", b.wrapMessage(b.handleAdminBroadcast))
+	b.dispatcher.Handle("link", b.wrapMessage(b.handleLinkCommand))
+
+	b.dispatcher.HandleCallback("*", b.wrapCallback(b.handleCallbackQuery))
+
+	b.dispatcher.HandleDefaultText(b.wrapMessage(b.handleTextMessage))
+}
+
+// registerGroupCommands wires the commands the bot answers inside groups
+// and supergroups. These are deliberately separate from registerHandlers:
+// private-chat flows (buy, deposit, tickets, ...) have no business leaking
+// into a public vendor group.
+func (b *Bot) registerGroupCommands() {
+	b.groupRouter.Handle("price", b.handleGroupPriceCommand)
+	b.groupRouter.Handle("stock", b.handleGroupStockCommand)
+	b.groupRouter.Handle("link", b.handleGroupLinkCommand, group.AdminOnly())
+}
+
+// OnGroupCommand registers a handler for /name in groups and supergroups,
+// with the "@botname" suffix Telegram appends there already stripped. Pass
+// group.AdminOnly() to gate the command behind a cached admin check.
+func (b *Bot) OnGroupCommand(name string, handler group.HandlerFunc, opts ...group.Option) {
+	b.groupRouter.Handle(name, handler, opts...)
+}
+
+// handleGroupMessage routes a group/supergroup message to the group command
+// subrouter; non-command chatter is ignored so the bot doesn't talk over
+// the group's own conversation.
+func (b *Bot) handleGroupMessage(message *tgbotapi.Message) {
+	b.groupRouter.Dispatch(message)
+}
+
+// handleGroupPriceCommand answers "/price" with the active catalog's prices.
+func (b *Bot) handleGroupPriceCommand(ctx *group.Context) error {
+	products, err := store.GetActiveProducts(b.db)
+	if err != nil {
+		return fmt.Errorf("failed to load products: %w", err)
+	}
+	if len(products) == 0 {
+		return ctx.Reply(b.api, b.msg.Get("ru", "no_products"))
+	}
+
+	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
+
+	var lines []string
+	for _, product := range products {
+		lines = append(lines, fmt.Sprintf("%s — %s%.2f", product.Name, currencySymbol, float64(product.PriceCents)/100))
+	}
+	return ctx.Reply(b.api, strings.Join(lines, "\n"))
+}
+
+// handleGroupStockCommand answers "/stock <product>" with the matching
+// product's live stock count, fuzzy-matched the same way /find does.
+func (b *Bot) handleGroupStockCommand(ctx *group.Context) error {
+	query := strings.TrimSpace(ctx.Args)
+	if query == "" {
+		return ctx.Reply(b.api, b.msg.Get("ru", "group_stock_usage"))
+	}
+
+	results := b.productIndex.Search(query, 1)
+	if len(results) == 0 {
+		return ctx.Reply(b.api, b.msg.Format("ru", "product_not_found", nil))
+	}
+
+	stock, err := store.CountAvailableCodes(b.db, results[0].Item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count stock: %w", err)
+	}
+
+	return ctx.Reply(b.api, fmt.Sprintf("%s: %d", results[0].Item.Text, stock))
+}
+
+// handleGroupLinkCommand answers "/link" (admin-only) with a deep link
+// straight into a private chat with the bot, for vendors to drop into their
+// group's pinned message without exposing the buy flow itself in-group.
+func (b *Bot) handleGroupLinkCommand(ctx *group.Context) error {
+	link := fmt.Sprintf("https://t.me/%s?start=group", b.api.Self.UserName)
+	return ctx.Reply(b.api, link)
+}
+
+// wrapMessage adapts a plain message handler into a dispatch.HandlerFunc.
+func (b *Bot) wrapMessage(h func(*tgbotapi.Message)) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		if ctx.Update.Message == nil {
+			return nil
+		}
+		h(ctx.Update.Message)
+		return nil
+	}
+}
+
+// wrapCallback adapts a plain callback-query handler into a dispatch.HandlerFunc.
+func (b *Bot) wrapCallback(h func(*tgbotapi.CallbackQuery)) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		if ctx.Update.CallbackQuery == nil {
+			return nil
+		}
+		h(ctx.Update.CallbackQuery)
+		return nil
+	}
+}
+
+// Handle registers a handler for the "/"+command slash command, so feature
+// packages can add commands without editing registerHandlers.
+func (b *Bot) Handle(command string, handler dispatch.HandlerFunc) {
+	b.dispatcher.Handle(command, handler)
+}
+
+// HandleCallback registers a handler for callback data matching pattern
+// (which may end in "*" for a prefix match, e.g. "buy:*").
+func (b *Bot) HandleCallback(pattern string, handler dispatch.HandlerFunc) {
+	b.dispatcher.HandleCallback(pattern, handler)
+}
+
+// HandleText registers a handler for text messages where matcher(text) is true.
+func (b *Bot) HandleText(matcher func(text string) bool, handler dispatch.HandlerFunc) {
+	b.dispatcher.HandleText(matcher, handler)
+}
+
+// OnState registers handler to run for messages received while a user is in
+// state. Registering the same state twice replaces the previous handler.
+func (b *Bot) OnState(state string, handler StateHandler) {
+	b.stateHandlers[state] = handler
+}
+
+// RefreshProductIndex rebuilds the fuzzy search index from the current active
+// product catalog. Call this on startup and after any product mutation.
+func (b *Bot) RefreshProductIndex() error {
+	products, err := store.GetActiveProducts(b.db)
+	if err != nil {
+		return fmt.Errorf("failed to load products for search index: %w", err)
+	}
+
+	items := make([]search.Item, 0, len(products))
+	for _, p := range products {
+		items = append(items, search.Item{ID: p.ID, Kind: "product", Text: p.Name})
+	}
+
+	b.productIndex.Build(items)
+	return nil
+}
+
+// handleFind answers "/find <query>" with the best-matching products for
+// query, ranked by fuzzy score. With no query it falls back to the full
+// product list (the same flow as /buy).
+func (b *Bot) handleFind(message *tgbotapi.Message) {
+	user, _ := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
+	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
+
+	query := strings.TrimSpace(message.CommandArguments())
+	if query == "" {
+		b.handleBuy(message)
+		return
+	}
+
+	results := b.productIndex.Search(query, 10)
+	if len(results) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, b.msg.Format(lang, "search_no_results", map[string]string{"Query": query}))
+		b.api.Send(msg)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, r := range results {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(r.Item.Text, fmt.Sprintf("buy_product_%d", r.Item.ID)),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, b.msg.Format(lang, "search_results", map[string]string{"Query": query}))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.api.Send(msg)
+}
+
+// handleInlineQuery answers "@botname <query>" typed in any chat with the
+// best-matching products, so users can share a product without opening the
+// bot's own chat.
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	results := b.productIndex.Search(query.Query, 10)
+
+	articles := make([]interface{}, 0, len(results))
+	for _, r := range results {
+		articles = append(articles, tgbotapi.NewInlineQueryResultArticle(
+			fmt.Sprintf("product_%d", r.Item.ID),
+			r.Item.Text,
+			fmt.Sprintf("/buy %s", r.Item.Text),
+		))
+	}
+
+	inlineConfig := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       articles,
+		CacheTime:     30,
+	}
+	if _, err := b.api.Request(inlineConfig); err != nil {
+		logger.Error("Failed to answer inline query", "error", err, "query", query.Query)
+	}
+}
+
+// handleCancelCommand is the global "/cancel" escape hatch: it reliably
+// drops whatever conversation state the user is in, regardless of which
+// flow they're stuck in.
+func (b *Bot) handleCancelCommand(message *tgbotapi.Message) {
+	user, _ := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
+	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
+
+	_, hadSession := b.fsm.Get(message.From.ID)
+	b.clearUserState(message.From.ID)
+
+	key := "nothing_to_cancel"
+	if hadSession {
+		key = "state_cancelled"
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, key)))
 }
 
 // SetTicketService sets the ticket service for the bot
@@ -98,6 +537,67 @@ func (b *Bot) Start(ctx context.Context) error {
 	return b.startPolling(ctx)
 }
 
+// RunReservationSweeper periodically releases code reservations whose TTL
+// has passed, cancels the pending orders that held them, and notifies the
+// affected buyers, so an abandoned "pay now" link doesn't hold stock
+// forever. It runs regardless of transport mode (polling or webhook), so
+// callers should start it once as its own goroutine.
+func (b *Bot) RunReservationSweeper(ctx context.Context) {
+	interval := time.Duration(b.config.ReservationSweepIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.sweepExpiredReservations()
+		}
+	}
+}
+
+func (b *Bot) sweepExpiredReservations() {
+	expired, err := store.ReleaseExpiredReservations(b.db)
+	if err != nil {
+		logger.Error("Failed to sweep expired reservations", "error", err)
+		return
+	}
+
+	for _, reservation := range expired {
+		var order store.Order
+		if err := b.db.First(&order, reservation.OrderID).Error; err != nil {
+			logger.Warn("Sweeper: order not found for expired reservation", "error", err, "order_id", reservation.OrderID)
+			continue
+		}
+		if order.Status != "pending" {
+			continue
+		}
+
+		if err := b.db.Model(&order).Update("status", "cancelled").Error; err != nil {
+			logger.Error("Sweeper: failed to cancel expired order", "error", err, "order_id", order.ID)
+			continue
+		}
+
+		logger.Info("Released expired reservation", "order_id", order.ID)
+		b.notifyOrderExpired(order)
+	}
+}
+
+// notifyOrderExpired tells the buyer their reservation timed out and the
+// order was cancelled, so they know to start over if they still want it.
+func (b *Bot) notifyOrderExpired(order store.Order) {
+	var user store.User
+	if err := b.db.First(&user, order.UserID).Error; err != nil {
+		logger.Warn("Sweeper: user not found for expired order", "error", err, "order_id", order.ID)
+		return
+	}
+
+	lang := messages.GetUserLanguage(user.Language, "")
+	text := b.msg.Format(lang, "order_expired", map[string]interface{}{"OrderID": order.ID})
+	b.sendOrEditOrderMessage(user.TgUserID, order.ID, text, "")
+}
+
 func (b *Bot) startPolling(ctx context.Context) error {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -127,42 +627,77 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 	logger.Info("Processing update", "update_id", update.UpdateID,
 		"has_message", update.Message != nil,
 		"has_callback", update.CallbackQuery != nil)
-	
-	// Handle callback queries (inline keyboard buttons)
+
 	if update.CallbackQuery != nil {
 		metrics.BotMessagesReceived.WithLabelValues("callback").Inc()
-		b.handleCallbackQuery(update.CallbackQuery)
+		b.dispatcher.Dispatch(update)
 		return
 	}
-	
-	// Handle regular messages
+
+	// Handle inline queries (typing "@botname <query>" in any chat) - not
+	// routed through the dispatcher, which only knows commands/callbacks/text.
+	if update.InlineQuery != nil {
+		metrics.BotMessagesReceived.WithLabelValues("inline_query").Inc()
+		observeHandler("inline_query", func() { b.handleInlineQuery(update.InlineQuery) })
+		return
+	}
+
+	// chat_member updates tell us when a buyer actually uses an invite_link
+	// product's grant, so it can be marked consumed - also not a command/
+	// callback/text, so it bypasses the dispatcher.
+	if update.ChatMember != nil {
+		metrics.BotMessagesReceived.WithLabelValues("chat_member").Inc()
+		observeHandler("chat_member", func() { b.handleChatMemberUpdate(update.ChatMember) })
+		return
+	}
+
+	// An edited message propagates a ticket reply/message edit to the
+	// relayed copy on the other side - also bypasses the dispatcher, which
+	// only knows about fresh messages.
+	if update.EditedMessage != nil {
+		metrics.BotMessagesReceived.WithLabelValues("edited_message").Inc()
+		observeHandler("edited_message", func() { b.handleEditedMessage(update.EditedMessage) })
+		return
+	}
+
 	if update.Message == nil {
 		return
 	}
 
-	// Check if it's a group message
+	// Group messages go to the group command subrouter and don't go through
+	// the command/callback/text dispatcher.
 	if update.Message.Chat.IsGroup() || update.Message.Chat.IsSuperGroup() {
 		metrics.BotMessagesReceived.WithLabelValues("group").Inc()
-		b.handleGroupMessage(update.Message)
+		observeHandler("group_message", func() { b.handleGroupMessage(update.Message) })
 		return
 	}
 
-	// Handle commands
 	if update.Message.IsCommand() {
 		metrics.BotMessagesReceived.WithLabelValues("command").Inc()
-		switch update.Message.Command() {
-		case "start":
-			b.handleStart(update.Message)
-		}
-		return
-	}
-	
-	// Handle text messages (ReplyKeyboard buttons)
-	if update.Message.Text != "" {
+	} else if update.Message.Text != "" {
 		metrics.BotMessagesReceived.WithLabelValues("text").Inc()
-		logger.Info("Handling text message", "text", update.Message.Text, "from", update.Message.From.ID)
-		b.handleTextMessage(update.Message)
 	}
+
+	b.dispatcher.Dispatch(update)
+}
+
+// observeHandler runs fn while recording its duration and outcome under
+// handlerName, recovering a panic into an "error" outcome so metrics stay
+// accurate even when a handler misbehaves.
+func observeHandler(handlerName string, fn func()) {
+	start := time.Now()
+
+	defer func() {
+		outcome := "ok"
+		if r := recover(); r != nil {
+			outcome = "error"
+			logger.Error("Handler panicked", "handler", handlerName, "panic", r)
+		}
+		metrics.BotHandlerDuration.WithLabelValues(handlerName).Observe(time.Since(start).Seconds())
+		metrics.BotUpdatesHandled.WithLabelValues(handlerName, outcome).Inc()
+	}()
+
+	fn()
 }
 
 func (b *Bot) handleStart(message *tgbotapi.Message) {
@@ -223,11 +758,11 @@ func (b *Bot) handleStart(message *tgbotapi.Message) {
 	logger.Info("User started bot", "user_id", user.ID, "tg_user_id", user.TgUserID)
 }
 
-// clearUserState clears the user's current state
+// clearUserState drops the user's current conversation state, if any.
 func (b *Bot) clearUserState(userID int64) {
-	b.userStatesMutex.Lock()
-	delete(b.userStates, userID)
-	b.userStatesMutex.Unlock()
+	if err := b.fsm.Cancel(userID); err != nil {
+		logger.Warn("Failed to cancel FSM session", "error", err, "user_id", userID)
+	}
 }
 
 func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
@@ -305,15 +840,13 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 		return
 	}
 
-	// Check if user is in custom deposit state (after checking button texts)
-	b.userStatesMutex.RLock()
-	userState, hasState := b.userStates[message.From.ID]
-	b.userStatesMutex.RUnlock()
-
-	if hasState && userState == "awaiting_deposit_amount" {
-		// Handle custom deposit amount
-		b.handleCustomDepositAmount(message)
-		return
+	// Check if the user is mid-flow (after checking button texts, which always
+	// take priority so users can bail out of a flow via the main menu).
+	if session, ok := b.fsm.Get(message.From.ID); ok {
+		if handler, ok := b.stateHandlers[session.State]; ok {
+			handler(b, message, session)
+			return
+		}
 	}
 
 	// Check if it's a recharge card code (starts with specific prefix)
@@ -340,7 +873,8 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 				username = fmt.Sprintf("User %d", message.From.ID)
 			}
 
-			err := b.ticketService.AddMessage(ticket.ID, "user", message.From.ID, username, message.Text, message.MessageID)
+			contentHTML := entitiesToHTML(message.Text, message.Entities)
+			err := b.ticketService.AddRichMessage(ticket.ID, "user", message.From.ID, username, contentHTML, message.MessageID)
 			if err != nil {
 				logger.Error("Failed to add message to ticket", "error", err, "ticket_id", ticket.ID)
 			} else {
@@ -567,7 +1101,59 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 		}
 	} else if strings.HasPrefix(callback.Data, "deposit_") {
 		b.handleDepositCallback(callback)
+	} else if strings.HasPrefix(callback.Data, "cancel_order:") {
+		orderIDStr := strings.TrimPrefix(callback.Data, "cancel_order:")
+		orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+		if err == nil {
+			b.handleCancelOrder(callback, uint(orderID))
+		}
+	} else if strings.HasPrefix(callback.Data, "autopay_") {
+		b.handleAutoPaymentCallback(callback)
+	} else if strings.HasPrefix(callback.Data, "refund_") {
+		b.handleRefundCallback(callback)
+	} else if strings.HasPrefix(callback.Data, "router_") {
+		b.handleRouterCallback(callback)
+	}
+}
+
+// handleCancelOrder lets a buyer free their reserved code immediately,
+// instead of waiting for the reservation to expire and the sweeper to
+// release it. A no-op (besides the acknowledgement) if the order has
+// already been paid, delivered, or cancelled.
+func (b *Bot) handleCancelOrder(callback *tgbotapi.CallbackQuery, orderID uint) {
+	user, err := store.GetOrCreateUser(b.db, callback.From.ID, callback.From.UserName)
+	if err != nil {
+		logger.Error("Failed to get user", "error", err)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, callback.From.LanguageCode)
+
+	var order store.Order
+	if err := b.db.Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+		logger.Warn("Cancel order: order not found", "error", err, "order_id", orderID, "user_id", user.ID)
+		return
+	}
+
+	if order.Status != "pending" {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "order_already_finalized")))
+		return
+	}
+
+	if err := store.ReleaseReservationForOrder(b.db, order.ID); err != nil {
+		logger.Error("Failed to release reservation", "error", err, "order_id", order.ID)
 	}
+	if err := store.ReleaseVoucherRedemptionForOrder(b.db, order.ID); err != nil {
+		logger.Error("Failed to release voucher redemption", "error", err, "order_id", order.ID)
+	}
+	if err := b.db.Model(&order).Update("status", "cancelled").Error; err != nil {
+		logger.Error("Failed to cancel order", "error", err, "order_id", order.ID)
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, b.msg.Format(lang, "order_cancelled", map[string]interface{}{
+		"OrderID": order.ID,
+	}))
+	b.api.Send(msg)
 }
 
 func (b *Bot) handleBuyProduct(callback *tgbotapi.CallbackQuery, productID uint) {
@@ -610,25 +1196,24 @@ func (b *Bot) handleBuyProduct(callback *tgbotapi.CallbackQuery, productID uint)
 	// Check if user has balance and offer to use it
 	if balance > 0 {
 		// Calculate how much balance can be used
-		balanceUsed := 0
-		paymentAmount := product.PriceCents
-		
-		if balance >= product.PriceCents {
-			balanceUsed = product.PriceCents
-			paymentAmount = 0
-		} else {
-			balanceUsed = balance
-			paymentAmount = product.PriceCents - balance
+		price := money.FromCents(product.PriceCents)
+		balanceDec := money.FromCents(balance)
+		balanceUsedDec := price
+		paymentAmountDec := decimal.Zero
+
+		if balanceDec.LessThan(price) {
+			balanceUsedDec = balanceDec
+			paymentAmountDec = price.Sub(balanceDec)
 		}
-		
+
 		// Ask user if they want to use balance
 		balanceMsg := b.msg.Format(lang, "use_balance_prompt", map[string]interface{}{
 			"Currency": currencySymbol,
-			"Balance": fmt.Sprintf("%.2f", float64(balance)/100),
+			"Balance": money.Format(balanceDec),
 			"Product": product.Name,
-			"Price": fmt.Sprintf("%.2f", float64(product.PriceCents)/100),
-			"BalanceUsed": fmt.Sprintf("%.2f", float64(balanceUsed)/100),
-			"ToPay": fmt.Sprintf("%.2f", float64(paymentAmount)/100),
+			"Price": money.Format(price),
+			"BalanceUsed": money.Format(balanceUsedDec),
+			"ToPay": money.Format(paymentAmountDec),
 		})
 		
 		// Create inline keyboard for balance usage choice
@@ -637,6 +1222,9 @@ func (b *Bot) handleBuyProduct(callback *tgbotapi.CallbackQuery, productID uint)
 				tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "use_balance_yes"), fmt.Sprintf("confirm_buy:%d:1", productID)),
 				tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "use_balance_no"), fmt.Sprintf("confirm_buy:%d:0", productID)),
 			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "btn_autopay_buy"), fmt.Sprintf("autopay_buy_menu:%d", productID)),
+			),
 		)
 		
 		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, balanceMsg)
@@ -650,6 +1238,15 @@ func (b *Bot) handleBuyProduct(callback *tgbotapi.CallbackQuery, productID uint)
 }
 
 func (b *Bot) handleConfirmBuy(callback *tgbotapi.CallbackQuery, productID uint, useBalance bool) {
+	// A fast double tap on the confirm button can race CreateOrder/
+	// ClaimOneCodeTx into creating two orders for the same click; collapse
+	// repeats of the same (user, product, message) within a short window
+	// into a no-op.
+	idempotencyKey := fmt.Sprintf("%d:%d:%d", callback.From.ID, productID, callback.Message.MessageID)
+	if !b.confirmBuyIdempotency.Claim(idempotencyKey) {
+		return
+	}
+
 	// Get user
 	user, err := store.GetOrCreateUser(b.db, callback.From.ID, callback.From.UserName)
 	if err != nil {
@@ -672,7 +1269,7 @@ func (b *Bot) handleConfirmBuy(callback *tgbotapi.CallbackQuery, productID uint,
 	// Create order with or without balance
 	var order *store.Order
 	if useBalance {
-		order, err = store.CreateOrderWithBalance(b.db, user.ID, product.ID, product.PriceCents, true)
+		order, err = store.CreateOrderWithBalance(b.db, user.ID, product.ID, product.PriceCents, true, "")
 	} else {
 		order, err = store.CreateOrder(b.db, user.ID, product.ID, product.PriceCents)
 	}
@@ -691,6 +1288,11 @@ func (b *Bot) handleConfirmBuy(callback *tgbotapi.CallbackQuery, productID uint,
 
 	// If payment amount is 0 (fully paid with balance), deliver immediately
 	if order.PaymentAmount == 0 {
+		if product.Kind == "invite_link" {
+			b.deliverInviteLink(context.Background(), callback.Message.Chat.ID, lang, order, product)
+			return
+		}
+
 		// Try to claim and deliver code
 		ctx := context.Background()
 		code, err := store.ClaimOneCodeTx(ctx, b.db, product.ID, order.ID)
@@ -723,15 +1325,27 @@ func (b *Bot) handleConfirmBuy(callback *tgbotapi.CallbackQuery, productID uint,
 			"ProductName": product.Name,
 			"Code":        code,
 		})
-		
-		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, deliveryMsg)
-		msg.ParseMode = "Markdown"
-		b.api.Send(msg)
-		
+
+		b.sendOrEditOrderMessage(callback.Message.Chat.ID, order.ID, deliveryMsg, "Markdown")
+
 		logger.Info("Order paid with balance and delivered", "order_id", order.ID, "user_id", user.ID, "product_id", product.ID)
 		return
 	}
 
+	// Reserve one code for this order now, rather than only claiming on
+	// payment, so the stock count shown to other buyers is truthful and two
+	// users can't both "succeed" against the last unit while this order is
+	// pending payment. The reservation is released by the sweeper if it
+	// expires, or by the user via the "Cancel order" button.
+	reservationTTL := time.Duration(b.config.ReservationTTLMinutes) * time.Minute
+	if err := store.ReserveCodeForOrder(context.Background(), b.db, product.ID, order.ID, reservationTTL); err != nil {
+		logger.Warn("Failed to reserve code for order", "error", err, "order_id", order.ID, "product_id", product.ID)
+		b.db.Model(order).Update("status", "cancelled")
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, b.msg.Get(lang, "out_of_stock"))
+		b.api.Send(msg)
+		return
+	}
+
 	// Generate out_trade_no for payment with nanosecond precision to avoid duplicates
 	outTradeNo := fmt.Sprintf("%d-%d", order.ID, time.Now().UnixNano())
 
@@ -740,63 +1354,73 @@ func (b *Bot) handleConfirmBuy(callback *tgbotapi.CallbackQuery, productID uint,
 		logger.Error("Failed to update order out_trade_no", "error", err, "order_id", order.ID)
 	}
 
+	// Create an order with every configured payment provider (allows user to
+	// choose payment method).
+	notifyURL := fmt.Sprintf("%s/payment/epay/notify", b.config.BaseURL)
+	returnURL := fmt.Sprintf("%s/payment/return", b.config.BaseURL)
+
+	paymentButtons := b.createPaymentButtons(context.Background(), lang, payment.OrderParams{
+		OutTradeNo:  outTradeNo,
+		Name:        product.Name,
+		AmountCents: order.PaymentAmount, // Use payment amount after balance deduction
+		NotifyURL:   notifyURL,
+		ReturnURL:   returnURL,
+		Param:       fmt.Sprintf("user_%d", user.ID), // Store user ID for reference
+	})
+
 	// Check if payment is configured
-	if b.epay == nil {
+	if len(paymentButtons) == 0 {
 		orderMsg := b.msg.Format(lang, "order_created", map[string]interface{}{
 			"Currency":    currencySymbol,
 			"ProductName": product.Name,
-			"Price":       fmt.Sprintf("%.2f", float64(order.PaymentAmount)/100),
+			"Price":       money.Format(order.PaymentAmountDecimal()),
 			"OrderID":     order.ID,
 		})
-		
+
 		if order.BalanceUsed > 0 {
 			orderMsg += "\n" + b.msg.Format(lang, "balance_used_info", map[string]interface{}{
 				"Currency":    currencySymbol,
-				"BalanceUsed": fmt.Sprintf("%.2f", float64(order.BalanceUsed)/100),
+				"BalanceUsed": money.Format(order.BalanceUsedDecimal()),
 			})
 		}
-		
+
 		orderMsg += "\n\n" + b.msg.Get(lang, "payment_not_configured")
-		
+
 		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, orderMsg)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "cancel_order_button"), fmt.Sprintf("cancel_order:%d", order.ID)),
+			),
+		)
 		b.api.Send(msg)
 		return
 	}
 
-	// Create payment order using submit URL (allows user to choose payment method)
-	notifyURL := fmt.Sprintf("%s/payment/epay/notify", b.config.BaseURL)
-	returnURL := fmt.Sprintf("%s/payment/return", b.config.BaseURL)
-
-	// Create submit URL for payment page
-	payURL := b.epay.CreateSubmitURL(epay.CreateOrderParams{
-		OutTradeNo: outTradeNo,
-		Name:       product.Name,
-		Money:      float64(order.PaymentAmount) / 100, // Use payment amount after balance deduction
-		NotifyURL:  notifyURL,
-		ReturnURL:  returnURL,
-		Param:      fmt.Sprintf("user_%d", user.ID), // Store user ID for reference
-	})
-
 	// Send payment message with inline button
 	orderMsg := b.msg.Format(lang, "order_created", map[string]interface{}{
 		"ProductName": product.Name,
-		"Price":       fmt.Sprintf("%.2f", float64(order.PaymentAmount)/100),
+		"Price":       money.Format(order.PaymentAmountDecimal()),
 		"OrderID":     order.ID,
 	})
-	
+
 	if order.BalanceUsed > 0 {
 		orderMsg += "\n" + b.msg.Format(lang, "balance_used_info", map[string]interface{}{
-			"BalanceUsed": fmt.Sprintf("%.2f", float64(order.BalanceUsed)/100),
+			"BalanceUsed": money.Format(order.BalanceUsedDecimal()),
 		})
 	}
 
-	// Send payment message with inline button
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL(b.msg.Get(lang, "pay_now"), payURL),
-		),
-	)
-	
+	// Send payment message with one inline button per provider, plus a way
+	// to free the reserved code immediately instead of waiting out the
+	// reservation TTL.
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, button := range paymentButtons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "cancel_order_button"), fmt.Sprintf("cancel_order:%d", order.ID)),
+	))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, orderMsg)
 	msg.ReplyMarkup = keyboard
 	b.api.Send(msg)
@@ -831,6 +1455,9 @@ func (b *Bot) handleDeposit(message *tgbotapi.Message) {
 			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("ğŸ’µ %s100", currencySymbol), "deposit_100"),
 			tgbotapi.NewInlineKeyboardButtonData("ğŸ”¢ "+b.msg.Get(lang, "custom_amount"), "deposit_custom"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.msg.Get(lang, "btn_autopay_topup"), "autopay_topup_menu"),
+		),
 	)
 	
 	msg := tgbotapi.NewMessage(message.Chat.ID, depositMsg)
@@ -853,11 +1480,11 @@ func (b *Bot) handleDepositCallback(callback *tgbotapi.CallbackQuery) {
 	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
 	
 	// Check if payment is configured
-	if b.epay == nil {
+	if len(b.paymentProviders) == 0 {
 		b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "payment_not_configured")))
 		return
 	}
-	
+
 	// Parse deposit amount
 	var amountCents int
 	switch callback.Data {
@@ -871,9 +1498,9 @@ func (b *Bot) handleDepositCallback(callback *tgbotapi.CallbackQuery) {
 		amountCents = 10000
 	case "deposit_custom":
 		// Set user state to awaiting deposit amount
-		b.userStatesMutex.Lock()
-		b.userStates[callback.From.ID] = "awaiting_deposit_amount"
-		b.userStatesMutex.Unlock()
+		if err := b.fsm.Enter(callback.From.ID, "awaiting_deposit_amount", nil); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", callback.From.ID)
+		}
 		
 		customMsg := b.msg.Get(lang, "custom_amount_instruction")
 		if customMsg == "custom_amount_instruction" {
@@ -908,35 +1535,40 @@ func (b *Bot) handleDepositCallback(callback *tgbotapi.CallbackQuery) {
 	// Create payment order using submit URL (allows user to choose payment method)
 	notifyURL := fmt.Sprintf("%s/payment/epay/notify", b.config.BaseURL)
 	returnURL := fmt.Sprintf("%s/payment/return", b.config.BaseURL)
-	
-	// Create submit URL for payment page
-	payURL := b.epay.CreateSubmitURL(epay.CreateOrderParams{
-		OutTradeNo: outTradeNo,
-		Name:       fmt.Sprintf("å……å€¼ %s%.2f", currencySymbol, float64(amountCents)/100),
-		Money:      float64(amountCents) / 100,
-		NotifyURL:  notifyURL,
-		ReturnURL:  returnURL,
-		Param:      fmt.Sprintf("deposit_%d", user.ID),
+
+	depositAmount := money.FromCents(amountCents)
+
+	paymentButtons := b.createPaymentButtons(context.Background(), lang, payment.OrderParams{
+		OutTradeNo:  outTradeNo,
+		Name:        fmt.Sprintf("å……å€¼ %s%s", currencySymbol, money.Format(depositAmount)),
+		AmountCents: amountCents,
+		NotifyURL:   notifyURL,
+		ReturnURL:   returnURL,
+		Param:       fmt.Sprintf("deposit_%d", user.ID),
 	})
-	
+	if len(paymentButtons) == 0 {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.msg.Get(lang, "payment_not_configured")))
+		return
+	}
+
 	// Send payment message
 	depositMsg := b.msg.Format(lang, "deposit_order_created", map[string]interface{}{
 		"Currency": currencySymbol,
-		"Amount":  fmt.Sprintf("%.2f", float64(amountCents)/100),
+		"Amount":  money.Format(depositAmount),
 		"OrderID": order.ID,
 	})
-	
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL(b.msg.Get(lang, "pay_now"), payURL),
-		),
-	)
-	
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, button := range paymentButtons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
 	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, depositMsg)
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "Markdown"
 	b.api.Send(msg)
-	
+
 	logger.Info("Deposit order created", "order_id", order.ID, "user_id", user.ID, "amount", amountCents)
 }
 
@@ -1042,6 +1674,33 @@ func (b *Bot) sendError(chatID int64, text string) {
 	b.api.Send(msg)
 }
 
+// createPaymentButtons creates an order with every configured payment
+// provider and returns one "Pay Now" button per provider that succeeded, so
+// a single provider outage doesn't block checkout entirely. When more than
+// one provider is active, the buyer sees a button per gateway and picks one
+// themselves instead of the bot choosing for them.
+//
+// Each provider gets its own NotifyURL keyed by its Name(), overriding
+// whatever params.NotifyURL the caller passed in, so the generic
+// /payment/notify/:gateway route in httpadmin knows which provider to
+// dispatch an inbound notification to without guessing from its payload shape.
+func (b *Bot) createPaymentButtons(ctx context.Context, lang string, params payment.OrderParams) []tgbotapi.InlineKeyboardButton {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, provider := range b.paymentProviders {
+		providerParams := params
+		providerParams.NotifyURL = fmt.Sprintf("%s/payment/notify/%s", b.config.BaseURL, provider.Name())
+
+		payURL, _, err := provider.CreateOrder(ctx, providerParams)
+		if err != nil {
+			logger.Warn("Payment provider failed to create order", "provider", provider.Name(), "error", err, "out_trade_no", params.OutTradeNo)
+			continue
+		}
+		label := b.msg.Format(lang, "pay_now_provider", map[string]interface{}{"Provider": provider.Name()})
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonURL(label, payURL))
+	}
+	return buttons
+}
+
 // UpdateInlineStock updates the stock numbers in an inline keyboard message
 func (b *Bot) UpdateInlineStock(chatID int64, messageID int) error {
 	// Get active products
@@ -1088,42 +1747,120 @@ func (b *Bot) GetBroadcastService() *broadcast.Service {
 	return b.broadcast
 }
 
-// SetWebhook sets the webhook URL
+// SetWebhook sets the webhook URL. Deprecated: use RegisterWebhook, which
+// also sets allowed_updates/max_connections and retries on failure.
 func (b *Bot) SetWebhook(webhookURL string) error {
 	webhook, err := tgbotapi.NewWebhook(webhookURL)
 	if err != nil {
 		return fmt.Errorf("failed to create webhook: %w", err)
 	}
-	
+	webhook.SecretToken = b.config.WebhookSecret
+
 	_, err = b.api.Request(webhook)
 	if err != nil {
 		return fmt.Errorf("failed to set webhook: %w", err)
 	}
-	
+
 	logger.Info("Webhook set successfully", "url", webhookURL)
 	return nil
 }
 
-// RemoveWebhook removes the webhook
+// RemoveWebhook removes the webhook. Deprecated: use DeregisterWebhook,
+// which makes drop_pending_updates configurable.
 func (b *Bot) RemoveWebhook() error {
 	deleteWebhook := tgbotapi.DeleteWebhookConfig{
 		DropPendingUpdates: false,
 	}
-	
+
 	_, err := b.api.Request(deleteWebhook)
 	if err != nil {
 		return fmt.Errorf("failed to remove webhook: %w", err)
 	}
-	
+
 	logger.Info("Webhook removed successfully")
 	return nil
 }
 
+// RegisterWebhook sets the webhook at webhookURL with a per-boot secret
+// token (generated if WebhookSecret isn't configured), allowed_updates
+// derived from the dispatcher's registered routes, max_connections, and an
+// optional self-signed certificate. It retries up to
+// WebhookSetupMaxAttempts times with a short backoff; if every attempt
+// fails, it returns an error so the caller can fall back to long-polling.
+func (b *Bot) RegisterWebhook(ctx context.Context, webhookURL string) error {
+	if b.config.WebhookSecret == "" {
+		secret, err := randomSecretToken()
+		if err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		b.config.WebhookSecret = secret
+		logger.Info("Generated per-boot webhook secret token")
+	}
+
+	var webhook tgbotapi.WebhookConfig
+	var err error
+	if b.config.WebhookCertPath != "" {
+		webhook, err = tgbotapi.NewWebhookWithCert(webhookURL, tgbotapi.FilePath(b.config.WebhookCertPath))
+	} else {
+		webhook, err = tgbotapi.NewWebhook(webhookURL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to build webhook config: %w", err)
+	}
+
+	webhook.SecretToken = b.config.WebhookSecret
+	webhook.MaxConnections = b.config.WebhookMaxConnections
+	webhook.AllowedUpdates = append(b.dispatcher.AllowedUpdates(), "inline_query", "chat_member")
+
+	attempts := b.config.WebhookSetupMaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if _, err := b.api.Request(webhook); err != nil {
+			lastErr = err
+			logger.Warn("setWebhook attempt failed", "attempt", attempt, "max_attempts", attempts, "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+			continue
+		}
+
+		logger.Info("Webhook registered", "url", webhookURL, "max_connections", webhook.MaxConnections, "allowed_updates", webhook.AllowedUpdates)
+		return nil
+	}
+
+	return fmt.Errorf("setWebhook failed after %d attempts: %w", attempts, lastErr)
+}
+
+// DeregisterWebhook deletes the webhook, optionally dropping any updates
+// Telegram has queued but not yet delivered.
+func (b *Bot) DeregisterWebhook(dropPendingUpdates bool) error {
+	_, err := b.api.Request(tgbotapi.DeleteWebhookConfig{DropPendingUpdates: dropPendingUpdates})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	logger.Info("Webhook deregistered", "drop_pending_updates", dropPendingUpdates)
+	return nil
+}
+
+// randomSecretToken returns a random hex string suitable for Telegram's
+// webhook secret_token (letters, digits, "_" and "-", 1-256 chars).
+func randomSecretToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 	// Clear user state
-	b.userStatesMutex.Lock()
-	delete(b.userStates, message.From.ID)
-	b.userStatesMutex.Unlock()
+	b.clearUserState(message.From.ID)
 	
 	// Get user for language
 	user, err := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
@@ -1135,11 +1872,11 @@ func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
 	
 	// Check if payment is configured
-	if b.epay == nil {
+	if len(b.paymentProviders) == 0 {
 		b.sendError(message.Chat.ID, b.msg.Get(lang, "payment_not_configured"))
 		return
 	}
-	
+
 	// Parse amount from message
 	amountStr := strings.TrimSpace(message.Text)
 
@@ -1170,20 +1907,20 @@ func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 		return
 	}
 
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
+	amount, err := money.ParseAmount(amountStr)
+	if err != nil || !amount.IsPositive() {
 		msg := tgbotapi.NewMessage(message.Chat.ID, "âŒ è¯·è¾“å…¥æœ‰æ•ˆçš„é‡‘é¢ï¼Œä¾‹å¦‚ï¼š30\n\nğŸ’¡ å‘é€ /cancel å–æ¶ˆæ“ä½œ")
 		b.api.Send(msg)
 
 		// Set state again to allow retry
-		b.userStatesMutex.Lock()
-		b.userStates[message.From.ID] = "awaiting_deposit_amount"
-		b.userStatesMutex.Unlock()
+		if err := b.fsm.Enter(message.From.ID, "awaiting_deposit_amount", nil); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", message.From.ID)
+		}
 		return
 	}
-	
+
 	// Convert to cents
-	amountCents := int(amount * 100)
+	amountCents := money.ToCents(amount)
 	
 	// Check minimum and maximum limits
 	if amountCents < 100 { // Minimum $1
@@ -1191,9 +1928,9 @@ func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 		b.api.Send(msg)
 
 		// Set state again to allow retry
-		b.userStatesMutex.Lock()
-		b.userStates[message.From.ID] = "awaiting_deposit_amount"
-		b.userStatesMutex.Unlock()
+		if err := b.fsm.Enter(message.From.ID, "awaiting_deposit_amount", nil); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", message.From.ID)
+		}
 		return
 	}
 
@@ -1202,9 +1939,9 @@ func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 		b.api.Send(msg)
 
 		// Set state again to allow retry
-		b.userStatesMutex.Lock()
-		b.userStates[message.From.ID] = "awaiting_deposit_amount"
-		b.userStatesMutex.Unlock()
+		if err := b.fsm.Enter(message.From.ID, "awaiting_deposit_amount", nil); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", message.From.ID)
+		}
 		return
 	}
 	
@@ -1230,30 +1967,33 @@ func (b *Bot) handleCustomDepositAmount(message *tgbotapi.Message) {
 	
 	// Get currency symbol
 	_, currencySymbol := store.GetCurrencySettings(b.db, b.config)
-	
-	// Create submit URL for payment page
-	payURL := b.epay.CreateSubmitURL(epay.CreateOrderParams{
-		OutTradeNo: outTradeNo,
-		Name:       fmt.Sprintf("å……å€¼ %s%.2f", currencySymbol, float64(amountCents)/100),
-		Money:      float64(amountCents) / 100,
-		NotifyURL:  notifyURL,
-		ReturnURL:  returnURL,
-		Param:      fmt.Sprintf("deposit_%d", user.ID),
+
+	paymentButtons := b.createPaymentButtons(context.Background(), lang, payment.OrderParams{
+		OutTradeNo:  outTradeNo,
+		Name:        fmt.Sprintf("å……å€¼ %s%s", currencySymbol, money.Format(amount)),
+		AmountCents: amountCents,
+		NotifyURL:   notifyURL,
+		ReturnURL:   returnURL,
+		Param:       fmt.Sprintf("deposit_%d", user.ID),
 	})
-	
+	if len(paymentButtons) == 0 {
+		b.sendError(message.Chat.ID, b.msg.Get(lang, "payment_not_configured"))
+		return
+	}
+
 	// Send payment message
 	depositMsg := b.msg.Format(lang, "deposit_order_created", map[string]interface{}{
 		"Currency": currencySymbol,
-		"Amount":  fmt.Sprintf("%.2f", float64(amountCents)/100),
+		"Amount":  money.Format(amount),
 		"OrderID": order.ID,
 	})
-	
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL(b.msg.Get(lang, "pay_now"), payURL),
-		),
-	)
-	
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, button := range paymentButtons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+
 	msg := tgbotapi.NewMessage(message.Chat.ID, depositMsg)
 	msg.ReplyMarkup = keyboard
 	msg.ParseMode = "Markdown"
@@ -1474,53 +2214,80 @@ func (b *Bot) isAdminReplyToTicket(message *tgbotapi.Message) bool {
 	return false
 }
 
-// handleAdminTicketReply handles admin replies to ticket notifications
+// handleAdminTicketReply handles admin replies to ticket notifications. It
+// first tries resolving the ticket by reply-to-message metadata (the
+// notification's chat/message ID, recorded when it was posted), which
+// survives locale changes and the admin editing the notification; the regex
+// scrape is only a fallback for notifications posted before that tracking
+// existed.
 func (b *Bot) handleAdminTicketReply(message *tgbotapi.Message) {
 	replyText := message.ReplyToMessage.Text
 	logger.Info("Processing admin ticket reply", "reply_text", replyText)
 
-	// Extract ticket ID from the notification message - handle both markdown and plain text
-	// Try markdown format first
-	ticketIDPattern := regexp.MustCompile(`å·¥å•å·:\s*(?:\x60)?(TK-\d{8}-\d{3})(?:\x60)?`)
-	matches := ticketIDPattern.FindStringSubmatch(replyText)
+	var ticket store.Ticket
 
-	if len(matches) < 2 {
-		// Try plain text format
-		ticketIDPattern = regexp.MustCompile(`å·¥å•å·:\s*(TK-\d{8}-\d{3})`)
-		matches = ticketIDPattern.FindStringSubmatch(replyText)
-	}
+	notifiedTicketID, notifyErr := store.GetTicketIDByNotification(b.db, message.ReplyToMessage.Chat.ID, message.ReplyToMessage.MessageID)
+	if notifyErr == nil {
+		if err := b.db.First(&ticket, notifiedTicketID).Error; err != nil {
+			logger.Error("Failed to find ticket by notification lookup", "ticket_id", notifiedTicketID, "error", err)
+			errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found")
+			b.api.Send(errorMsg)
+			return
+		}
+	} else {
+		// Fall back to scraping the ticket number out of the notification
+		// text - handle both markdown and plain text formats.
+		ticketIDPattern := regexp.MustCompile(`å·¥å•å·:\s*(?:\x60)?(TK-\d{8}-\d{3})(?:\x60)?`)
+		matches := ticketIDPattern.FindStringSubmatch(replyText)
+
+		if len(matches) < 2 {
+			ticketIDPattern = regexp.MustCompile(`å·¥å•å·:\s*(TK-\d{8}-\d{3})`)
+			matches = ticketIDPattern.FindStringSubmatch(replyText)
+		}
 
-	if len(matches) < 2 {
-		logger.Error("Failed to extract ticket ID from notification", "text", replyText)
-		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ æ— æ³•è¯†åˆ«å·¥å•å· / Failed to identify ticket number")
-		b.api.Send(errorMsg)
-		return
-	}
+		if len(matches) < 2 {
+			logger.Error("Failed to extract ticket ID from notification", "text", replyText)
+			errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ æ— æ³•è¯†åˆ«å·¥å•å· / Failed to identify ticket number")
+			b.api.Send(errorMsg)
+			return
+		}
 
-	ticketNumber := matches[1]
-	logger.Info("Admin replying to ticket", "ticket_number", ticketNumber, "reply", message.Text)
+		ticketNumber := matches[1]
+		logger.Info("Admin replying to ticket (regex fallback)", "ticket_number", ticketNumber, "reply", message.Text)
 
-	// Find the ticket
-	var ticket store.Ticket
-	err := b.db.Where("ticket_id = ?", ticketNumber).First(&ticket).Error
-	if err != nil {
-		logger.Error("Failed to find ticket", "ticket_number", ticketNumber, "error", err)
-		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found")
-		b.api.Send(errorMsg)
-		return
+		if err := b.db.Where("ticket_id = ?", ticketNumber).First(&ticket).Error; err != nil {
+			logger.Error("Failed to find ticket", "ticket_number", ticketNumber, "error", err)
+			errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found")
+			b.api.Send(errorMsg)
+			return
+		}
 	}
 
 	// Get admin info
 	var admin store.AdminUser
 	telegramID := message.From.ID
-	err = b.db.Where("telegram_id = ?", telegramID).First(&admin).Error
-	if err != nil {
+	if err := b.db.Where("telegram_id = ?", telegramID).First(&admin).Error; err != nil {
 		logger.Error("Failed to find admin", "telegram_id", telegramID, "error", err)
 		return
 	}
 
-	// Add admin's reply to the ticket
-	err = b.ticketService.AddMessage(ticket.ID, "admin", message.From.ID, admin.Username, message.Text, message.MessageID)
+	// Sensitive tickets (payment-related) or a superadmin's own replies
+	// require a fresh TOTP code appended to the reply as "#code:123456", so
+	// a compromised Telegram account can't silently act on them. There is
+	// no follow-up prompt for the code: a reply without it is rejected.
+	replyBody, totpCode := extractTOTPCode(message.Text)
+	if err := b.requireTwoFactor(admin, &ticket, "ticket_reply", totpCode); err != nil {
+		logger.Warn("Admin ticket reply blocked by 2FA gate", "error", err, "admin_id", admin.ID, "ticket_id", ticket.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ "+err.Error()))
+		return
+	}
+	replyEntities := trimEntitiesToText(message.Entities, replyBody)
+
+	// Add admin's reply to the ticket, preserving any formatting (bold,
+	// links, code, ...) as Telegram-flavored HTML instead of flattening it
+	// to plain text.
+	contentHTML := entitiesToHTML(replyBody, replyEntities)
+	err := b.ticketService.AddRichMessage(ticket.ID, "admin", message.From.ID, admin.Username, contentHTML, message.MessageID)
 	if err != nil {
 		logger.Error("Failed to add admin message to ticket", "error", err, "ticket_id", ticket.ID)
 		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ å‘é€å¤±è´¥ / Failed to send message")
@@ -1528,18 +2295,20 @@ func (b *Bot) handleAdminTicketReply(message *tgbotapi.Message) {
 		return
 	}
 
-	// Send the reply to the user
-	userMsg := fmt.Sprintf("ğŸ’¬ *å®¢æœå›å¤ / Support Reply*\n\n%s", message.Text)
-	msg := tgbotapi.NewMessage(ticket.UserID, userMsg)
-	msg.ParseMode = "Markdown"
+	// Send the reply to the user, re-applying the original entities shifted
+	// past the prefix instead of relying on Markdown escaping. Fans out to
+	// the buyer's other enabled channels too, and falls back to email if
+	// the Telegram send itself fails.
+	prefix := "ğŸ’¬ å®¢æœå›å¤ / Support Reply\n\n"
+	shiftedEntities := shiftEntities(replyEntities, len([]rune(prefix)))
 
-	_, err = b.api.Send(msg)
+	telegramMessageID, err := b.deliverTicketReply(ticket, prefix+replyBody, shiftedEntities)
 	if err != nil {
-		logger.Error("Failed to send message to user", "error", err, "user_id", ticket.UserID)
 		errorMsg := tgbotapi.NewMessage(message.Chat.ID, "âŒ å‘é€å¤±è´¥ï¼Œç”¨æˆ·å¯èƒ½å·²åœæ­¢æœºå™¨äºº / Failed to send, user may have blocked the bot")
 		b.api.Send(errorMsg)
 		return
 	}
+	rememberRelayedMessage(message.MessageID, ticket.ID, ticket.UserID, telegramMessageID)
 
 	// Send confirmation to admin
 	confirmMsg := tgbotapi.NewMessage(message.Chat.ID, "âœ… æ¶ˆæ¯å·²å‘é€ç»™ç”¨æˆ· / Message sent to user")
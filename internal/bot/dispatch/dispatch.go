@@ -0,0 +1,229 @@
+// Package dispatch is a small, telebot/aiogram-style update router: features
+// register command, callback, and text handlers instead of adding another
+// branch to a growing switch statement, and every registered handler runs
+// through the same middleware chain (logging, metrics, i18n, admin checks,
+// rate limiting, panic recovery).
+package dispatch
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Context carries the update being dispatched plus whatever values
+// middleware chose to inject (language, admin status, rate-limit outcome, ...).
+type Context struct {
+	Update tgbotapi.Update
+	values map[string]interface{}
+}
+
+func newContext(update tgbotapi.Update) *Context {
+	return &Context{Update: update, values: make(map[string]interface{})}
+}
+
+// Set stores a middleware-computed value under key for downstream
+// middleware and the handler to read.
+func (c *Context) Set(key string, value interface{}) {
+	c.values[key] = value
+}
+
+// Get returns the value stored under key, if any.
+func (c *Context) Get(key string) (interface{}, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// HandlerFunc handles one dispatched update. Returning an error reports it
+// to the Dispatcher's Reporter instead of panicking or being swallowed.
+type HandlerFunc func(ctx *Context) error
+
+// Middleware wraps a HandlerFunc with cross-cutting behavior. Middleware run
+// in registration order, outermost first, around every matched handler.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+type callbackRoute struct {
+	pattern string
+	handler HandlerFunc
+}
+
+type textRoute struct {
+	matcher func(text string) bool
+	handler HandlerFunc
+}
+
+// Dispatcher routes commands, callback queries, and plain text messages to
+// registered handlers, applying a shared middleware chain to all of them.
+type Dispatcher struct {
+	commands    map[string]HandlerFunc
+	callbacks   []callbackRoute
+	texts       []textRoute
+	defaultText HandlerFunc
+	middleware  []Middleware
+	reporter    func(error)
+}
+
+// New returns an empty Dispatcher. Register routes with Handle/HandleCallback/
+// HandleText, middleware with Use, and an error sink with SetReporter.
+func New() *Dispatcher {
+	return &Dispatcher{
+		commands: make(map[string]HandlerFunc),
+		reporter: func(error) {},
+	}
+}
+
+// Use appends mw to the middleware chain. Order matters: middleware added
+// first wraps outermost, so it sees the update before later middleware and
+// after the handler returns.
+func (d *Dispatcher) Use(mw Middleware) {
+	d.middleware = append(d.middleware, mw)
+}
+
+// SetReporter installs the callback used to surface errors returned by
+// handlers (including ones recovered from a panic), so they aren't silently
+// swallowed when run from a goroutine.
+func (d *Dispatcher) SetReporter(reporter func(error)) {
+	if reporter != nil {
+		d.reporter = reporter
+	}
+}
+
+// Handle registers a handler for the exact command name (without the
+// leading "/"), e.g. Handle("start", ...).
+func (d *Dispatcher) Handle(command string, handler HandlerFunc) {
+	d.commands[strings.TrimPrefix(command, "/")] = handler
+}
+
+// HandleCallback registers a handler for callback data matching pattern.
+// pattern may end in "*" to match any callback data sharing that prefix
+// (e.g. "buy:*" matches "buy:123"); otherwise it must match exactly.
+// Routes are tried in registration order; the first match wins.
+func (d *Dispatcher) HandleCallback(pattern string, handler HandlerFunc) {
+	d.callbacks = append(d.callbacks, callbackRoute{pattern: pattern, handler: handler})
+}
+
+// HandleText registers a handler for text messages where matcher(text)
+// returns true. Routes are tried in registration order; the first match wins.
+func (d *Dispatcher) HandleText(matcher func(text string) bool, handler HandlerFunc) {
+	d.texts = append(d.texts, textRoute{matcher: matcher, handler: handler})
+}
+
+// HandleDefaultText registers the fallback handler for any text message that
+// no HandleText route matched. It's always tried last, regardless of when
+// it's registered relative to other text routes - the same guarantee a
+// HandleCallback("*", ...) route gets among callbacks.
+func (d *Dispatcher) HandleDefaultText(handler HandlerFunc) {
+	d.defaultText = handler
+}
+
+// RouteKey is the context key under which Dispatch stores the name of the
+// route that matched, for middleware (e.g. metrics) that wants to label by
+// handler name without hard-coding the route table itself.
+const RouteKey = "route"
+
+// Dispatch routes update to the first matching registered handler (command,
+// then callback, then text, in that priority), wrapped in the middleware
+// chain. It's a no-op if nothing matches.
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) {
+	handler, name, ok := d.resolve(update)
+	if !ok {
+		return
+	}
+
+	ctx := newContext(update)
+	ctx.Set(RouteKey, name)
+	if err := d.wrapped(handler)(ctx); err != nil {
+		d.reporter(err)
+	}
+}
+
+func (d *Dispatcher) resolve(update tgbotapi.Update) (HandlerFunc, string, bool) {
+	if update.Message != nil && update.Message.IsCommand() {
+		cmd := update.Message.Command()
+		h, ok := d.commands[cmd]
+		return h, "cmd:" + cmd, ok
+	}
+
+	if update.CallbackQuery != nil {
+		// A bare "*" pattern is a catch-all (e.g. bridging to a not-yet-split
+		// legacy handler) and always loses to a more specific pattern,
+		// regardless of registration order - otherwise a feature route
+		// registered after the catch-all would never be reachable.
+		var wildcard *callbackRoute
+		for i, route := range d.callbacks {
+			if route.pattern == "*" {
+				wildcard = &d.callbacks[i]
+				continue
+			}
+			if matchPattern(route.pattern, update.CallbackQuery.Data) {
+				return route.handler, "callback:" + route.pattern, true
+			}
+		}
+		if wildcard != nil {
+			return wildcard.handler, "callback:*", true
+		}
+		return nil, "", false
+	}
+
+	if update.Message != nil {
+		for i, route := range d.texts {
+			if route.matcher(update.Message.Text) {
+				return route.handler, fmt.Sprintf("text:%d", i), true
+			}
+		}
+		if d.defaultText != nil {
+			return d.defaultText, "text:default", true
+		}
+	}
+
+	return nil, "", false
+}
+
+// wrapped applies the middleware chain around handler, innermost
+// (the handler itself) to outermost (the first-registered middleware),
+// plus an always-on panic recovery layer so a misbehaving handler can't take
+// down the update loop.
+func (d *Dispatcher) wrapped(handler HandlerFunc) HandlerFunc {
+	h := recoverMiddleware(handler)
+	for i := len(d.middleware) - 1; i >= 0; i-- {
+		h = d.middleware[i](h)
+	}
+	return h
+}
+
+func recoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("handler panicked: %v", r)
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+// AllowedUpdates reports which Telegram update kinds this dispatcher can
+// actually route ("message" for commands/text, "callback_query" for
+// callbacks), for passing as setWebhook's allowed_updates so Telegram
+// doesn't bother delivering update kinds nothing here handles.
+func (d *Dispatcher) AllowedUpdates() []string {
+	var kinds []string
+	if len(d.commands) > 0 || len(d.texts) > 0 || d.defaultText != nil {
+		kinds = append(kinds, "message")
+	}
+	if len(d.callbacks) > 0 {
+		kinds = append(kinds, "callback_query")
+	}
+	return kinds
+}
+
+// matchPattern reports whether data matches pattern, where a trailing "*"
+// in pattern means "matches this prefix", and any other pattern must match
+// data exactly.
+func matchPattern(pattern, data string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(data, prefix)
+	}
+	return pattern == data
+}
@@ -0,0 +1,102 @@
+// Package fsm is a small finite-state conversation engine for multi-step bot
+// flows (custom deposit amount, ticket creation, product filtering, ...).
+// Sessions are persisted to the database, keyed by Telegram user ID, so an
+// in-progress flow survives a restart instead of silently resetting.
+package fsm
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// Context is the typed data a state carries along with it (e.g. the
+// category and subject already collected in a ticket-creation wizard).
+type Context map[string]interface{}
+
+// Session is one user's current state plus its context data.
+type Session struct {
+	UserID    int64
+	State     string
+	Data      Context
+	ExpiresAt time.Time
+}
+
+// Engine stores and transitions per-user sessions in the database.
+type Engine struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewEngine returns an Engine backed by db. ttl bounds how long a session
+// survives without a transition before Get treats it as expired.
+func NewEngine(db *gorm.DB, ttl time.Duration) *Engine {
+	return &Engine{db: db, ttl: ttl}
+}
+
+// Get returns the user's current session, or ok=false if they have none or
+// it has expired (an expired session is deleted as a side effect).
+func (e *Engine) Get(userID int64) (*Session, bool) {
+	row, err := store.GetFSMSession(e.db, userID)
+	if err != nil {
+		return nil, false
+	}
+
+	if time.Now().After(row.ExpiresAt) {
+		if delErr := store.DeleteFSMSession(e.db, userID); delErr != nil {
+			logger.Warn("Failed to delete expired FSM session", "error", delErr, "user_id", userID)
+		}
+		return nil, false
+	}
+
+	data := Context{}
+	if row.DataJSON != "" {
+		if err := json.Unmarshal([]byte(row.DataJSON), &data); err != nil {
+			logger.Warn("Failed to decode FSM session data", "error", err, "user_id", userID)
+		}
+	}
+
+	return &Session{UserID: userID, State: row.State, Data: data, ExpiresAt: row.ExpiresAt}, true
+}
+
+// Enter starts (or replaces) userID's session in state, with data as its
+// initial context.
+func (e *Engine) Enter(userID int64, state string, data Context) error {
+	if data == nil {
+		data = Context{}
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return store.UpsertFSMSession(e.db, userID, state, string(encoded), time.Now().Add(e.ttl))
+}
+
+// Set merges updates into the current session's context without changing
+// its state, extending the TTL. A no-op if the user has no active session.
+func (e *Engine) Set(userID int64, updates Context) error {
+	session, ok := e.Get(userID)
+	if !ok {
+		return nil
+	}
+	for k, v := range updates {
+		session.Data[k] = v
+	}
+	return e.Enter(userID, session.State, session.Data)
+}
+
+// Finish completes the user's flow successfully, clearing their session.
+func (e *Engine) Finish(userID int64) error {
+	return store.DeleteFSMSession(e.db, userID)
+}
+
+// Cancel aborts the user's flow, clearing their session. Functionally the
+// same as Finish; kept distinct so callers (and logs) can tell an abandoned
+// flow from a completed one.
+func (e *Engine) Cancel(userID int64) error {
+	return store.DeleteFSMSession(e.db, userID)
+}
@@ -0,0 +1,179 @@
+// Package group is a small command subrouter for group/supergroup chats. It
+// strips Telegram's "command@botname" suffix, answers in reply-only mode
+// (every response is a reply to the triggering message, never a fresh
+// message, so the bot doesn't talk over an active group conversation), and
+// gates admin-only commands behind a TTL-cached getChatAdministrators check.
+package group
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Context carries the group message that triggered a command, plus
+// whatever follows the command name as free-form arguments.
+type Context struct {
+	Message *tgbotapi.Message
+	Args    string
+	IsAdmin bool
+}
+
+// Reply sends text as a reply to the triggering message, so the bot's
+// answer is threaded rather than posted as an unprompted new message.
+func (c *Context) Reply(api *tgbotapi.BotAPI, text string) error {
+	msg := tgbotapi.NewMessage(c.Message.Chat.ID, text)
+	msg.ReplyToMessageID = c.Message.MessageID
+	_, err := api.Send(msg)
+	return err
+}
+
+// HandlerFunc handles one matched group command.
+type HandlerFunc func(ctx *Context) error
+
+type commandRoute struct {
+	handler   HandlerFunc
+	adminOnly bool
+}
+
+// Option configures a registered command route.
+type Option func(*commandRoute)
+
+// AdminOnly restricts a command to chat administrators, verified via a
+// TTL-cached getChatAdministrators call.
+func AdminOnly() Option {
+	return func(r *commandRoute) { r.adminOnly = true }
+}
+
+// Router dispatches group commands (stripped of any "@botname" suffix) to
+// registered handlers, gating admin-only ones behind a cached admin check.
+type Router struct {
+	api         *tgbotapi.BotAPI
+	botUsername string
+	commands    map[string]commandRoute
+	admins      *adminCache
+	reporter    func(error)
+}
+
+// NewRouter returns a Router that resolves "command@botname" suffixes
+// against botUsername and checks admin status via api, caching each chat's
+// admin set for adminCacheTTL.
+func NewRouter(api *tgbotapi.BotAPI, botUsername string, adminCacheTTL time.Duration) *Router {
+	return &Router{
+		api:         api,
+		botUsername: botUsername,
+		commands:    make(map[string]commandRoute),
+		admins:      newAdminCache(adminCacheTTL),
+		reporter:    func(error) {},
+	}
+}
+
+// SetReporter installs the callback used to surface errors returned by
+// handlers, instead of silently dropping them.
+func (r *Router) SetReporter(reporter func(error)) {
+	if reporter != nil {
+		r.reporter = reporter
+	}
+}
+
+// Handle registers handler for the command name (without the leading "/"),
+// e.g. Handle("price", ...). Pass AdminOnly() to gate it behind an admin check.
+func (r *Router) Handle(name string, handler HandlerFunc, opts ...Option) {
+	route := commandRoute{handler: handler}
+	for _, opt := range opts {
+		opt(&route)
+	}
+	r.commands[name] = route
+}
+
+// Dispatch routes message to its registered command handler, if any. It's a
+// no-op for non-command messages or commands with no registered handler.
+func (r *Router) Dispatch(message *tgbotapi.Message) {
+	if !message.IsCommand() {
+		return
+	}
+
+	name := r.stripBotSuffix(message.Command())
+	route, ok := r.commands[name]
+	if !ok {
+		return
+	}
+
+	ctx := &Context{Message: message, Args: message.CommandArguments()}
+
+	if route.adminOnly {
+		isAdmin, err := r.admins.IsAdmin(r.api, message.Chat.ID, message.From.ID)
+		if err != nil {
+			r.reporter(fmt.Errorf("group command %q: admin check failed: %w", name, err))
+			return
+		}
+		if !isAdmin {
+			return
+		}
+		ctx.IsAdmin = true
+	}
+
+	if err := route.handler(ctx); err != nil {
+		r.reporter(fmt.Errorf("group command %q: %w", name, err))
+	}
+}
+
+// stripBotSuffix removes a trailing "@botname" from a command, which
+// Telegram appends in groups to disambiguate which bot a command targets
+// (e.g. "/price@shop_bot" -> "price").
+func (r *Router) stripBotSuffix(command string) string {
+	if r.botUsername == "" {
+		return command
+	}
+	return strings.TrimSuffix(command, "@"+r.botUsername)
+}
+
+// adminCache remembers each chat's administrator set for a bounded time, so
+// a burst of admin-gated commands doesn't each trigger their own
+// getChatAdministrators call.
+type adminCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[int64]adminCacheEntry
+}
+
+type adminCacheEntry struct {
+	adminIDs  map[int64]bool
+	expiresAt time.Time
+}
+
+func newAdminCache(ttl time.Duration) *adminCache {
+	return &adminCache{ttl: ttl, entries: make(map[int64]adminCacheEntry)}
+}
+
+// IsAdmin reports whether userID administers chatID, refreshing the cached
+// admin set via the Bot API if it's missing or has expired.
+func (c *adminCache) IsAdmin(api *tgbotapi.BotAPI, chatID, userID int64) (bool, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[chatID]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		admins, err := api.GetChatAdministrators(tgbotapi.ChatAdministratorsConfig{
+			ChatConfig: tgbotapi.ChatConfig{ChatID: chatID},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		adminIDs := make(map[int64]bool, len(admins))
+		for _, admin := range admins {
+			adminIDs[admin.User.ID] = true
+		}
+
+		entry = adminCacheEntry{adminIDs: adminIDs, expiresAt: time.Now().Add(c.ttl)}
+		c.mu.Lock()
+		c.entries[chatID] = entry
+		c.mu.Unlock()
+	}
+
+	return entry.adminIDs[userID], nil
+}
@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/bot/messages"
+	"shop-bot/internal/store"
+)
+
+// deliverInviteLink issues a single-use Telegram invite link for an
+// invite_link product's order and DMs it to the buyer, in place of handing
+// out a stored code.
+func (b *Bot) deliverInviteLink(ctx context.Context, chatID int64, lang string, order *store.Order, product *store.Product) {
+	link, err := b.issueInviteLink(ctx, product, order.ID)
+	if err != nil {
+		logger.Error("Failed to issue invite link", "error", err, "order_id", order.ID, "product_id", product.ID)
+		b.db.Model(order).Update("status", "failed_delivery")
+		noStockMsg := b.msg.Format(lang, "no_stock", map[string]interface{}{
+			"OrderID":     order.ID,
+			"ProductName": product.Name,
+		})
+		b.api.Send(tgbotapi.NewMessage(chatID, noStockMsg))
+		return
+	}
+
+	now := time.Now()
+	b.db.Model(order).Updates(map[string]interface{}{"status": "delivered", "delivered_at": &now})
+
+	deliveryMsg := b.msg.Format(lang, "invite_link_delivered", map[string]interface{}{
+		"OrderID":     order.ID,
+		"ProductName": product.Name,
+		"InviteLink":  link,
+	})
+	msg := tgbotapi.NewMessage(chatID, deliveryMsg)
+	msg.ParseMode = "Markdown"
+	b.api.Send(msg)
+
+	logger.Info("Invite link delivered", "order_id", order.ID, "product_id", product.ID)
+}
+
+// issueInviteLink creates a single-use (MemberLimit=1), time-limited invite
+// link into product.InviteChatID and persists it as orderID's grant.
+func (b *Bot) issueInviteLink(ctx context.Context, product *store.Product, orderID uint) (string, error) {
+	ttl := time.Duration(product.InviteTTLSeconds) * time.Second
+	expiresAt := time.Now().Add(ttl)
+
+	config := tgbotapi.CreateChatInviteLinkConfig{
+		ChatConfig:         tgbotapi.ChatConfig{ChatID: product.InviteChatID},
+		MemberLimit:        1,
+		ExpireDate:         expiresAt.Unix(),
+		CreatesJoinRequest: product.InviteRequiresApproval,
+	}
+
+	result, err := b.api.Request(config)
+	if err != nil {
+		return "", fmt.Errorf("create chat invite link: %w", err)
+	}
+
+	var link tgbotapi.ChatInviteLink
+	if err := json.Unmarshal(result.Result, &link); err != nil {
+		return "", fmt.Errorf("decode invite link response: %w", err)
+	}
+
+	if _, err := store.CreateInviteGrant(b.db, orderID, product.InviteChatID, link.InviteLink, expiresAt); err != nil {
+		return "", fmt.Errorf("persist invite grant: %w", err)
+	}
+
+	return link.InviteLink, nil
+}
+
+// handleChatMemberUpdate marks an invite_link order's grant as consumed once
+// the buyer actually joins the gated chat with it.
+func (b *Bot) handleChatMemberUpdate(update *tgbotapi.ChatMemberUpdated) {
+	if update.InviteLink == nil {
+		return
+	}
+	if update.NewChatMember.Status != "member" && update.NewChatMember.Status != "restricted" {
+		return
+	}
+
+	if err := store.MarkInviteGrantConsumed(b.db, update.InviteLink.InviteLink); err != nil {
+		logger.Warn("Failed to mark invite grant consumed", "error", err, "invite_link", update.InviteLink.InviteLink)
+	}
+}
+
+// handleReissueInvite is an admin-only "/reissueinvite <orderID>" command:
+// it revokes the previously issued link (if any - Telegram invalidates it
+// separately via RevokeChatInviteLinkConfig) and issues a fresh one, for
+// when a buyer reports their original link didn't work.
+func (b *Bot) handleReissueInvite(message *tgbotapi.Message) {
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", message.From.ID, true).First(&admin).Error; err != nil {
+		return
+	}
+
+	args := message.CommandArguments()
+	orderID64, err := strconv.ParseUint(args, 10, 32)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /reissueinvite <order_id>"))
+		return
+	}
+	orderID := uint(orderID64)
+
+	var order store.Order
+	if err := b.db.First(&order, orderID).Error; err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Order not found"))
+		return
+	}
+	if order.ProductID == nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Order has no product"))
+		return
+	}
+	product, err := store.GetProduct(b.db, *order.ProductID)
+	if err != nil || product.Kind != "invite_link" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Order is not an invite_link purchase"))
+		return
+	}
+
+	if grant, err := store.GetInviteGrantByOrderID(b.db, orderID); err == nil {
+		b.api.Request(tgbotapi.RevokeChatInviteLinkConfig{
+			ChatConfig: tgbotapi.ChatConfig{ChatID: grant.ChatID},
+			InviteLink: grant.InviteLink,
+		})
+	}
+	if err := store.RevokeInviteGrant(b.db, orderID); err != nil {
+		logger.Warn("Failed to mark invite grant revoked", "error", err, "order_id", orderID)
+	}
+
+	link, err := b.issueInviteLink(context.Background(), product, orderID)
+	if err != nil {
+		logger.Error("Failed to reissue invite link", "error", err, "order_id", orderID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to reissue invite link"))
+		return
+	}
+
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("New invite link for order #%d: %s", orderID, link)))
+
+	var user store.User
+	lang := "ru"
+	if err := b.db.First(&user, order.UserID).Error; err == nil {
+		lang = messages.GetUserLanguage(user.Language, "")
+		deliveryMsg := b.msg.Format(lang, "invite_link_delivered", map[string]interface{}{
+			"OrderID":     order.ID,
+			"ProductName": product.Name,
+			"InviteLink":  link,
+		})
+		msg := tgbotapi.NewMessage(user.TgUserID, deliveryMsg)
+		msg.ParseMode = "Markdown"
+		b.api.Send(msg)
+	}
+}
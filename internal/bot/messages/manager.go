@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"embed"
 	"encoding/json"
-	"fmt"
 	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+
+	"gorm.io/gorm"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
 )
 
 //go:embed *.json
@@ -16,6 +22,10 @@ var messagesFS embed.FS
 type Manager struct {
 	messages map[string]map[string]string
 	mu       sync.RWMutex
+
+	// diskOverrideDir is where per-locale override files are looked up from,
+	// e.g. data/messages/<lang>.json. Empty disables disk overrides.
+	diskOverrideDir string
 }
 
 var (
@@ -27,112 +37,386 @@ var (
 func GetManager() *Manager {
 	managerOnce.Do(func() {
 		manager = &Manager{
-			messages: make(map[string]map[string]string),
+			messages:        make(map[string]map[string]string),
+			diskOverrideDir: "data/messages",
 		}
-		manager.loadMessages()
+		manager.messages = manager.loadEmbeddedCatalog()
 	})
 	return manager
 }
 
-func (m *Manager) loadMessages() {
-	languages := []string{"en", "zh"}
-	
-	for _, lang := range languages {
+// discoverLanguages returns every language code that has either an embedded
+// catalog file or a disk override file, so adding a new locale is just a
+// matter of dropping a <lang>.json in one of those two places.
+func (m *Manager) discoverLanguages() []string {
+	seen := make(map[string]bool)
+
+	if entries, err := messagesFS.ReadDir("."); err == nil {
+		for _, e := range entries {
+			if lang, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+				seen[lang] = true
+			}
+		}
+	}
+
+	if m.diskOverrideDir != "" {
+		if entries, err := os.ReadDir(m.diskOverrideDir); err == nil {
+			for _, e := range entries {
+				if lang, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+					seen[lang] = true
+				}
+			}
+		}
+	}
+
+	langs := make([]string, 0, len(seen))
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// loadEmbeddedCatalog reads the compiled-in catalog for every known language.
+func (m *Manager) loadEmbeddedCatalog() map[string]map[string]string {
+	catalog := make(map[string]map[string]string)
+
+	for _, lang := range m.discoverLanguages() {
+		catalog[lang] = make(map[string]string)
+
 		data, err := messagesFS.ReadFile(lang + ".json")
 		if err != nil {
-			fmt.Printf("Failed to load %s.json: %v\n", lang, err)
+			// Language only exists as a disk override file, that's fine.
 			continue
 		}
-		
+
 		var msgs map[string]string
 		if err := json.Unmarshal(data, &msgs); err != nil {
-			fmt.Printf("Failed to parse %s.json: %v\n", lang, err)
+			logger.Error("Failed to parse embedded message catalog", "language", lang, "error", err)
+			continue
+		}
+
+		catalog[lang] = msgs
+	}
+
+	return catalog
+}
+
+func (m *Manager) loadMessages() {
+	m.messages = m.loadEmbeddedCatalog()
+}
+
+// applyDiskOverrides layers data/messages/<lang>.json on top of catalog, if present.
+func (m *Manager) applyDiskOverrides(catalog map[string]map[string]string) {
+	if m.diskOverrideDir == "" {
+		return
+	}
+
+	for lang := range catalog {
+		path := filepath.Join(m.diskOverrideDir, lang+".json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // no override file for this language, that's fine
+		}
+
+		var overrides map[string]string
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			logger.Warn("Failed to parse disk message override", "path", path, "error", err)
 			continue
 		}
-		
-		m.messages[lang] = msgs
+
+		for key, value := range overrides {
+			catalog[lang][key] = value
+		}
+	}
+}
+
+// applyDBOverrides layers key/value rows pulled from the bot_messages table on top of catalog.
+func (m *Manager) applyDBOverrides(catalog map[string]map[string]string, overrides []store.BotMessage) {
+	for _, o := range overrides {
+		if _, ok := catalog[o.Lang]; !ok {
+			catalog[o.Lang] = make(map[string]string)
+		}
+		catalog[o.Lang][o.MsgKey] = o.MsgValue
 	}
 }
 
+// Reload re-reads the embedded catalog and layers disk then DB overrides on top of it,
+// swapping the active catalog atomically so Get/Format never observe a half-built map.
+// db may be nil to skip the DB layer (e.g. when called before the database is ready).
+func (m *Manager) Reload(db *gorm.DB) error {
+	catalog := m.loadEmbeddedCatalog()
+	m.applyDiskOverrides(catalog)
+
+	if db != nil {
+		overrides, err := store.GetBotMessageOverrides(db)
+		if err != nil {
+			return err
+		}
+		m.applyDBOverrides(catalog, overrides)
+	}
+
+	m.mu.Lock()
+	m.messages = catalog
+	m.mu.Unlock()
+
+	return nil
+}
+
+// SetDiskOverrideDir changes where per-locale override files are read from on Reload.
+func (m *Manager) SetDiskOverrideDir(dir string) {
+	m.diskOverrideDir = dir
+}
+
 // Get returns a message for the given key and language
 func (m *Manager) Get(lang, key string) string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	
+
 	// Fallback to English if language not found
 	if _, ok := m.messages[lang]; !ok {
 		lang = "en"
 	}
-	
+
 	if msg, ok := m.messages[lang][key]; ok {
 		return msg
 	}
-	
+
 	// Try English as fallback
 	if lang != "en" {
 		if msg, ok := m.messages["en"][key]; ok {
 			return msg
 		}
 	}
-	
+
 	return key // Return key if message not found
 }
 
 // Format returns a formatted message with template data
 func (m *Manager) Format(lang, key string, data interface{}) string {
-	msgTemplate := m.Get(lang, key)
-	
+	return renderTemplate(key, m.Get(lang, key), data)
+}
+
+// FormatPlural selects the catalog entry for key using the CLDR plural category
+// that n falls into for lang (entries are stored flat as "key.one", "key.other", ...),
+// then renders it as a template with data plus an injected "N" field.
+func (m *Manager) FormatPlural(lang, key string, n int, data interface{}) string {
+	category := pluralCategory(lang, n)
+
+	variantKey := key + "." + category
+	tmpl := m.Get(lang, variantKey)
+
+	// Fall back to the "other" category, then to a plain (non-pluralized) key.
+	if tmpl == variantKey && category != "other" {
+		variantKey = key + ".other"
+		tmpl = m.Get(lang, variantKey)
+	}
+	if tmpl == variantKey {
+		return m.Format(lang, key, withN(data, n))
+	}
+
+	return renderTemplate(variantKey, tmpl, withN(data, n))
+}
+
+// withN merges an "N" field into data when data is a map (or nil), so plural
+// templates can reference {{.N}} without every caller wiring it in by hand.
+func withN(data interface{}, n int) interface{} {
+	switch d := data.(type) {
+	case nil:
+		return map[string]interface{}{"N": n}
+	case map[string]interface{}:
+		merged := make(map[string]interface{}, len(d)+1)
+		for k, v := range d {
+			merged[k] = v
+		}
+		merged["N"] = n
+		return merged
+	default:
+		return data
+	}
+}
+
+func renderTemplate(name, tmplSrc string, data interface{}) string {
 	// If no template syntax, return as-is
-	if !strings.Contains(msgTemplate, "{{") {
-		return msgTemplate
+	if !strings.Contains(tmplSrc, "{{") {
+		return tmplSrc
 	}
-	
-	// Parse and execute template
-	tmpl, err := template.New(key).Parse(msgTemplate)
+
+	tmpl, err := template.New(name).Parse(tmplSrc)
 	if err != nil {
-		return msgTemplate
+		return tmplSrc
 	}
-	
+
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
-		return msgTemplate
+		return tmplSrc
 	}
-	
+
 	return buf.String()
 }
 
-// GetUserLanguage determines the user's language preference
+// pluralCategory picks the CLDR plural category ("zero", "one", "two", "few",
+// "many", "other") that n falls into for lang. Unlisted languages default to
+// the English-style one/other split.
+func pluralCategory(lang string, n int) string {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch lang {
+	case "zh", "ja", "ko", "vi", "th":
+		// These languages don't inflect for plural; everything is "other".
+		return "other"
+	case "ru", "uk", "pl", "cs":
+		// Russian-family rule (CLDR): mod10/mod100 based one/few/many/other.
+		mod10 := abs % 10
+		mod100 := abs % 100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && !(mod100 >= 12 && mod100 <= 14):
+			return "few"
+		case mod10 == 0 || (mod10 >= 5 && mod10 <= 9) || (mod100 >= 11 && mod100 <= 14):
+			return "many"
+		default:
+			return "other"
+		}
+	default:
+		// English-style: singular only for exactly one.
+		if abs == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
+
+// Keys returns every message key known for the given language, in map
+// iteration order, which is not defined (callers that need stable order should sort).
+func (m *Manager) Keys(lang string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.messages[lang]))
+	for k := range m.messages[lang] {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// GetUserLanguage determines the user's language preference. Priority is the
+// stored user language, then an Accept-Language-style walk of telegramLang
+// (Telegram only ever sends one tag, but this also accepts a comma-separated
+// preference list), matched against whatever locales are currently loaded.
 func GetUserLanguage(userLang string, telegramLang string) string {
-	// Priority: stored user language > telegram language > default
-	if userLang != "" {
+	available := GetManager().GetAvailableLanguages()
+
+	if userLang != "" && languageAvailable(available, userLang) {
 		return userLang
 	}
 
-	// Map Telegram language codes to our supported languages
-	// Chinese first (default)
-	if strings.HasPrefix(telegramLang, "zh") || telegramLang == "" {
-		return "zh"
+	for _, pref := range parseLanguagePreferences(telegramLang) {
+		if code, ok := matchLanguage(available, pref); ok {
+			return code
+		}
+	}
+
+	return defaultLanguage(available)
+}
+
+func parseLanguagePreferences(header string) []string {
+	parts := strings.Split(header, ",")
+	prefs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		// Strip an Accept-Language quality suffix ("en-US;q=0.8") if present.
+		if i := strings.Index(p, ";"); i >= 0 {
+			p = p[:i]
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs
+}
+
+func languageAvailable(available []Language, code string) bool {
+	for _, lang := range available {
+		if lang.Code == code {
+			return true
+		}
 	}
+	return false
+}
 
-	// English for English-speaking users
-	if strings.HasPrefix(telegramLang, "en") {
-		return "en"
+// matchLanguage finds the available language matching pref, either exactly
+// or by base-tag prefix (e.g. "en-US" matches available code "en").
+func matchLanguage(available []Language, pref string) (string, bool) {
+	base := pref
+	if i := strings.Index(base, "-"); i >= 0 {
+		base = base[:i]
 	}
 
-	// Default to Chinese
+	for _, lang := range available {
+		if lang.Code == pref || lang.Code == base {
+			return lang.Code, true
+		}
+	}
+	for _, lang := range available {
+		if strings.HasPrefix(base, lang.Code) {
+			return lang.Code, true
+		}
+	}
+	return "", false
+}
+
+func defaultLanguage(available []Language) string {
+	if languageAvailable(available, "zh") {
+		return "zh"
+	}
+	if len(available) > 0 {
+		return available[0].Code
+	}
 	return "zh"
 }
 
-// GetAvailableLanguages returns list of available languages
+// GetAvailableLanguages returns every language that currently has catalog
+// entries loaded, discovered from the embedded files and disk overrides.
 func (m *Manager) GetAvailableLanguages() []Language {
-	return []Language{
-		{Code: "en", Name: "English", Flag: "🇬🇧"},
-		{Code: "zh", Name: "中文", Flag: "🇨🇳"},
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	langs := make([]Language, 0, len(m.messages))
+	for code := range m.messages {
+		langs = append(langs, Language{Code: code, Name: languageName(code), Flag: languageFlag(code)})
+	}
+	sort.Slice(langs, func(i, j int) bool { return langs[i].Code < langs[j].Code })
+	return langs
+}
+
+var languageDisplay = map[string]Language{
+	"en": {Code: "en", Name: "English", Flag: "🇬🇧"},
+	"zh": {Code: "zh", Name: "中文", Flag: "🇨🇳"},
+	"ru": {Code: "ru", Name: "Русский", Flag: "🇷🇺"},
+}
+
+func languageName(code string) string {
+	if l, ok := languageDisplay[code]; ok {
+		return l.Name
 	}
+	return code
+}
+
+func languageFlag(code string) string {
+	if l, ok := languageDisplay[code]; ok {
+		return l.Flag
+	}
+	return "🏳️"
 }
 
 type Language struct {
 	Code string
 	Name string
 	Flag string
-}
\ No newline at end of file
+}
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/bot/dispatch"
+	"shop-bot/internal/bot/messages"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/metrics"
+	"shop-bot/internal/store"
+)
+
+// fromUser returns whichever user initiated update, regardless of whether
+// it's a message or a callback query.
+func fromUser(update tgbotapi.Update) *tgbotapi.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From
+	default:
+		return nil
+	}
+}
+
+// routeName reads back the route name Dispatch recorded on ctx.
+func routeName(ctx *dispatch.Context) string {
+	if v, ok := ctx.Get(dispatch.RouteKey); ok {
+		if name, ok := v.(string); ok {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// loggingMiddleware logs every dispatched update's route and outcome.
+func loggingMiddleware(next dispatch.HandlerFunc) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		err := next(ctx)
+		if err != nil {
+			logger.Error("Handler returned error", "route", routeName(ctx), "update_id", ctx.Update.UpdateID, "error", err)
+		} else {
+			logger.Info("Handled update", "route", routeName(ctx), "update_id", ctx.Update.UpdateID)
+		}
+		return err
+	}
+}
+
+// metricsMiddleware records handler latency and outcome, labeled by route.
+func metricsMiddleware(next dispatch.HandlerFunc) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		name := routeName(ctx)
+		start := time.Now()
+		err := next(ctx)
+
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		metrics.BotHandlerDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		metrics.BotUpdatesHandled.WithLabelValues(name, outcome).Inc()
+		return err
+	}
+}
+
+// i18nMiddleware resolves (and creates, if needed) the sending user and
+// injects their language into ctx, so handlers don't each repeat the
+// GetOrCreateUser + GetUserLanguage dance.
+func (b *Bot) i18nMiddleware(next dispatch.HandlerFunc) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		from := fromUser(ctx.Update)
+		if from != nil {
+			user, err := store.GetOrCreateUser(b.db, from.ID, from.UserName)
+			if err != nil {
+				logger.Warn("Failed to resolve user for i18n middleware", "error", err, "user_id", from.ID)
+			} else {
+				ctx.Set("user", user)
+				ctx.Set("lang", messages.GetUserLanguage(user.Language, from.LanguageCode))
+			}
+		}
+		return next(ctx)
+	}
+}
+
+// adminCheckMiddleware injects whether the sending user is an active admin,
+// so handlers (and future admin-only routes) can gate on ctx.Get("is_admin")
+// instead of re-querying AdminUser themselves.
+func (b *Bot) adminCheckMiddleware(next dispatch.HandlerFunc) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		from := fromUser(ctx.Update)
+		isAdmin := false
+		if from != nil {
+			var admin store.AdminUser
+			if err := b.db.Where("telegram_id = ? AND is_active = true", from.ID).First(&admin).Error; err == nil {
+				isAdmin = true
+			}
+		}
+		ctx.Set("is_admin", isAdmin)
+		return next(ctx)
+	}
+}
+
+// expensiveActionPrefixes are callback data prefixes that get a stricter,
+// separate token bucket on top of the general per-user one, since they
+// trigger real work (order creation, payment link generation).
+var expensiveActionPrefixes = []string{"buy:", "confirm_buy:", "deposit_"}
+
+// isExpensiveAction reports whether update is a callback whose data starts
+// with one of expensiveActionPrefixes.
+func isExpensiveAction(update tgbotapi.Update) bool {
+	if update.CallbackQuery == nil {
+		return false
+	}
+	for _, prefix := range expensiveActionPrefixes {
+		if strings.HasPrefix(update.CallbackQuery.Data, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitMiddleware throttles each Telegram user with a general per-user
+// token bucket, plus a stricter bucket for expensive actions (buy/deposit
+// callbacks) on top of that. A throttled user gets the "slow down" message
+// at most once per rateLimitWarnWindow, not once per rejected update.
+func (b *Bot) rateLimitMiddleware(next dispatch.HandlerFunc) dispatch.HandlerFunc {
+	return func(ctx *dispatch.Context) error {
+		from := fromUser(ctx.Update)
+		if from == nil {
+			return next(ctx)
+		}
+
+		key := strconv.FormatInt(from.ID, 10)
+		allowed := b.generalLimiter.Allow(key)
+		action := "general"
+		if allowed && isExpensiveAction(ctx.Update) {
+			action = "action"
+			allowed = b.actionLimiter.Allow(key)
+		}
+
+		if allowed {
+			return next(ctx)
+		}
+
+		metrics.BotRateLimited.WithLabelValues(action).Inc()
+		b.warnRateLimited(ctx, from)
+		return nil
+	}
+}
+
+// warnRateLimited sends the localized "slow down" message to from's chat,
+// at most once per rateLimitWarnWindow, so a burst of throttled taps
+// doesn't also spam the user with warnings.
+func (b *Bot) warnRateLimited(ctx *dispatch.Context, from *tgbotapi.User) {
+	b.rateLimitWarnedMu.Lock()
+	last, warned := b.rateLimitWarned[from.ID]
+	if warned && time.Since(last) < b.rateLimitWarnWindow {
+		b.rateLimitWarnedMu.Unlock()
+		return
+	}
+	b.rateLimitWarned[from.ID] = time.Now()
+	b.rateLimitWarnedMu.Unlock()
+
+	chatID := chatIDOf(ctx.Update)
+	if chatID == 0 {
+		return
+	}
+
+	lang := messages.GetUserLanguage("", from.LanguageCode)
+	if v, ok := ctx.Get("lang"); ok {
+		if l, ok := v.(string); ok {
+			lang = l
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.msg.Get(lang, "rate_limited"))
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Warn("Failed to send rate-limit warning", "error", err, "user_id", from.ID)
+	}
+}
+
+// chatIDOf returns the chat the update belongs to, for a message or a
+// callback query, or 0 if neither is set.
+func chatIDOf(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// sendOrEditOrderMessage sends text to chatID as the notification for
+// orderID, editing the message from the last time this order notified that
+// chat if one is on record instead of sending a new one - so a buyer sees
+// one evolving notification per order (pending -> paid -> delivered) rather
+// than a new message for every status change. Falls back to sending fresh
+// if there's nothing on record yet, or if the edit itself fails (the stored
+// message may have been deleted, or be too old for Telegram to edit).
+func (b *Bot) sendOrEditOrderMessage(chatID int64, orderID uint, text, parseMode string) {
+	if messageID, err := store.GetTelegramMessage(b.db, orderID, chatID); err == nil {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		edit.ParseMode = parseMode
+		if _, err := b.api.Send(edit); err == nil {
+			return
+		}
+		logger.Warn("Failed to edit order notification, sending a new one", "order_id", orderID, "chat_id", chatID)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = parseMode
+	resp, err := b.api.Send(msg)
+	if err != nil {
+		logger.Warn("Failed to send order notification", "error", err, "order_id", orderID, "chat_id", chatID)
+		return
+	}
+	if err := store.UpsertTelegramMessage(b.db, orderID, chatID, resp.MessageID); err != nil {
+		logger.Warn("Failed to record order notification message ID", "error", err, "order_id", orderID, "chat_id", chatID)
+	}
+}
@@ -0,0 +1,391 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/bot/fsm"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/bot/messages"
+	"shop-bot/internal/money"
+	"shop-bot/internal/store"
+)
+
+func (b *Bot) refundArbitrationDeadline() time.Duration {
+	return time.Duration(b.config.RefundArbitrationDeadlineHours) * time.Hour
+}
+
+// handleRefundCommand is "/refund <order_id>": it validates the order
+// belongs to the caller and has actually been paid, then asks for the
+// reason before opening the refund ticket, mirroring the deposit-custom-
+// amount flow's "ask, then finish on the next message" shape.
+func (b *Bot) handleRefundCommand(message *tgbotapi.Message) {
+	user, err := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
+	if err != nil {
+		logger.Error("Failed to get user", "error", err)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
+
+	orderID64, err := strconv.ParseUint(strings.TrimSpace(message.CommandArguments()), 10, 32)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "refund_usage")))
+		return
+	}
+	orderID := uint(orderID64)
+
+	var order store.Order
+	if err := b.db.Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "order_not_found")))
+		return
+	}
+	if order.Status != "paid" && order.Status != "delivered" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "refund_not_eligible")))
+		return
+	}
+
+	var existing store.RefundRequest
+	if err := b.db.Where("order_id = ? AND status NOT IN ?", orderID,
+		[]string{store.RefundStatusResolvedRefund, store.RefundStatusResolvedReject, store.RefundStatusExpired}).
+		First(&existing).Error; err == nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Format(lang, "refund_already_open", map[string]interface{}{
+			"OrderID": orderID,
+		})))
+		return
+	}
+
+	if err := b.fsm.Enter(message.From.ID, "awaiting_refund_reason", fsm.Context{"order_id": orderID}); err != nil {
+		logger.Warn("Failed to enter FSM session", "error", err, "user_id", message.From.ID)
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Format(lang, "refund_ask_reason", map[string]interface{}{
+		"OrderID": orderID,
+	})))
+}
+
+// handleRefundReason finishes the /refund flow once the buyer sends their
+// reason: it opens a "refund" category ticket, opens the matching
+// RefundRequest against it, and notifies every active admin with inline
+// decision buttons.
+func (b *Bot) handleRefundReason(message *tgbotapi.Message, session *fsm.Session) {
+	b.clearUserState(message.From.ID)
+
+	user, err := store.GetOrCreateUser(b.db, message.From.ID, message.From.UserName)
+	if err != nil {
+		logger.Error("Failed to get user", "error", err)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, message.From.LanguageCode)
+
+	orderIDFloat, ok := session.Data["order_id"].(float64)
+	if !ok {
+		return
+	}
+	orderID := uint(orderIDFloat)
+
+	var order store.Order
+	if err := b.db.First(&order, orderID).Error; err != nil {
+		logger.Warn("Refund: order not found", "error", err, "order_id", orderID)
+		return
+	}
+
+	reason := strings.TrimSpace(message.Text)
+	if b.ticketService == nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "refund_unavailable")))
+		return
+	}
+
+	username := message.From.UserName
+	if username == "" {
+		username = fmt.Sprintf("User %d", message.From.ID)
+	}
+	ticket, err := b.ticketService.CreateTicket(
+		message.From.ID,
+		username,
+		fmt.Sprintf("Refund request for order #%d", orderID),
+		"refund",
+		reason,
+	)
+	if err != nil {
+		logger.Error("Failed to create refund ticket", "error", err, "order_id", orderID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Get(lang, "refund_unavailable")))
+		return
+	}
+
+	amount := order.PaymentAmountDecimal().Add(order.BalanceUsedDecimal())
+	refund, err := store.CreateRefundRequest(b.db, orderID, ticket.ID, order.UserID, money.ToCents(amount), reason, b.refundArbitrationDeadline())
+	if err != nil {
+		logger.Error("Failed to create refund request", "error", err, "order_id", orderID)
+		return
+	}
+
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, b.msg.Format(lang, "refund_requested", map[string]interface{}{
+		"OrderID":  orderID,
+		"TicketID": ticket.TicketID,
+	})))
+
+	b.notifyAdminsOfRefund(refund, ticket.TicketID, order, reason)
+}
+
+func (b *Bot) notifyAdminsOfRefund(refund *store.RefundRequest, ticketNumber string, order store.Order, reason string) {
+	text := fmt.Sprintf("🧾 *Refund request*\n\nTicket: `%s`\nOrder #%d\nAmount: %s\nReason: %s",
+		ticketNumber, order.ID, money.Format(money.FromCents(refund.RequestedAmountCents)), reason)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Full refund", fmt.Sprintf("refund_approve_full:%d", refund.ID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➗ Partial refund", fmt.Sprintf("refund_approve_partial:%d", refund.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Reject", fmt.Sprintf("refund_reject:%d", refund.ID)),
+		),
+	)
+
+	b.sendToActiveAdmins(text, keyboard)
+}
+
+func (b *Bot) sendToActiveAdmins(text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	var admins []store.AdminUser
+	if err := b.db.Where("is_active = ?", true).Find(&admins).Error; err != nil {
+		logger.Error("Failed to list active admins", "error", err)
+		return
+	}
+	for _, admin := range admins {
+		msg := tgbotapi.NewMessage(admin.TelegramID, text)
+		msg.ParseMode = "Markdown"
+		msg.ReplyMarkup = keyboard
+		b.api.Send(msg)
+	}
+}
+
+// handleRefundCallback routes every "refund_..." callback surfaced on an
+// admin's refund notification.
+func (b *Bot) handleRefundCallback(callback *tgbotapi.CallbackQuery) {
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", callback.From.ID, true).First(&admin).Error; err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Admins only"))
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(callback.Data, "refund_approve_full:"):
+		id := parseRefundID(callback.Data, "refund_approve_full:")
+		if _, err := b.approveRefund(admin, id, 0); err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to approve refund"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+	case strings.HasPrefix(callback.Data, "refund_approve_partial:"):
+		id := parseRefundID(callback.Data, "refund_approve_partial:")
+		if id == 0 {
+			return
+		}
+		if err := b.fsm.Enter(callback.From.ID, "awaiting_refund_partial_amount", fsm.Context{"refund_request_id": id}); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", callback.From.ID)
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Enter the partial refund amount (e.g. 15.00):"))
+	case strings.HasPrefix(callback.Data, "refund_reject:"):
+		id := parseRefundID(callback.Data, "refund_reject:")
+		if id == 0 {
+			return
+		}
+		if err := b.fsm.Enter(callback.From.ID, "awaiting_refund_reject_reason", fsm.Context{"refund_request_id": id}); err != nil {
+			logger.Warn("Failed to enter FSM session", "error", err, "user_id", callback.From.ID)
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Enter the rejection reason:"))
+	}
+}
+
+func parseRefundID(data, prefix string) uint {
+	id, err := strconv.ParseUint(strings.TrimPrefix(data, prefix), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// approveRefund credits the buyer's balance for approvedAmountCents (the
+// full requested amount, when called from the "Full refund" button with
+// approvedAmountCents=0) and resolves the request.
+func (b *Bot) approveRefund(admin store.AdminUser, refundID uint, approvedAmountCents int) (*store.RefundRequest, error) {
+	if refundID == 0 {
+		return nil, fmt.Errorf("invalid refund request id")
+	}
+	refund, err := store.GetRefundRequest(b.db, refundID)
+	if err != nil {
+		return nil, fmt.Errorf("refund request not found: %w", err)
+	}
+	if approvedAmountCents <= 0 {
+		approvedAmountCents = refund.RequestedAmountCents
+	}
+
+	if err := store.AddBalance(b.db, refund.UserID, store.MoneyFromCents(approvedAmountCents), "refund",
+		fmt.Sprintf("Refund for order #%d", refund.OrderID), &refund.TicketID, &refund.OrderID); err != nil {
+		return nil, fmt.Errorf("credit refund balance: %w", err)
+	}
+
+	note := fmt.Sprintf("Approved by %s", admin.Username)
+	if err := store.ApproveRefundRequest(b.db, refund.ID, approvedAmountCents, note); err != nil {
+		logger.Error("Failed to mark refund approved", "error", err, "refund_id", refund.ID)
+	}
+
+	b.notifyBuyerOfRefundDecision(refund.UserID, refund.OrderID, true, approvedAmountCents, "")
+	return refund, nil
+}
+
+// rejectRefund resolves a refund request against the buyer with adminNote
+// as the reason relayed to them.
+func (b *Bot) rejectRefund(admin store.AdminUser, refundID uint, adminNote string) (*store.RefundRequest, error) {
+	refund, err := store.GetRefundRequest(b.db, refundID)
+	if err != nil {
+		return nil, fmt.Errorf("refund request not found: %w", err)
+	}
+
+	note := strings.TrimSpace(adminNote)
+	if err := store.RejectRefundRequest(b.db, refund.ID, note); err != nil {
+		return nil, fmt.Errorf("mark refund rejected: %w", err)
+	}
+	b.notifyBuyerOfRefundDecision(refund.UserID, refund.OrderID, false, 0, note)
+	return refund, nil
+}
+
+// handleRefundPartialAmount finishes the "Partial refund" admin flow once
+// they send the amount to credit.
+func (b *Bot) handleRefundPartialAmount(message *tgbotapi.Message, session *fsm.Session) {
+	b.clearUserState(message.From.ID)
+
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", message.From.ID, true).First(&admin).Error; err != nil {
+		return
+	}
+
+	refundIDFloat, ok := session.Data["refund_request_id"].(float64)
+	if !ok {
+		return
+	}
+
+	amount, err := money.ParseAmount(strings.TrimSpace(message.Text))
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Invalid amount, try again (e.g. 15.00)"))
+		return
+	}
+
+	if _, err := b.approveRefund(admin, uint(refundIDFloat), money.ToCents(amount)); err != nil {
+		logger.Error("Failed to approve partial refund", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to approve refund"))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Partial refund approved."))
+}
+
+// handleRefundRejectReason finishes the "Reject" admin flow once they send
+// the reason to relay to the buyer.
+func (b *Bot) handleRefundRejectReason(message *tgbotapi.Message, session *fsm.Session) {
+	b.clearUserState(message.From.ID)
+
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", message.From.ID, true).First(&admin).Error; err != nil {
+		return
+	}
+
+	refundIDFloat, ok := session.Data["refund_request_id"].(float64)
+	if !ok {
+		return
+	}
+
+	if _, err := b.rejectRefund(admin, uint(refundIDFloat), message.Text); err != nil {
+		logger.Error("Failed to reject refund", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to reject refund"))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Refund rejected."))
+}
+
+func (b *Bot) notifyBuyerOfRefundDecision(userID, orderID uint, approved bool, approvedAmountCents int, reason string) {
+	var user store.User
+	if err := b.db.First(&user, userID).Error; err != nil {
+		logger.Warn("Refund: buyer not found", "error", err, "user_id", userID)
+		return
+	}
+	lang := messages.GetUserLanguage(user.Language, "")
+
+	if approved {
+		msg := b.msg.Format(lang, "refund_approved", map[string]interface{}{
+			"OrderID": orderID,
+			"Amount":  money.Format(money.FromCents(approvedAmountCents)),
+		})
+		b.api.Send(tgbotapi.NewMessage(user.TgUserID, msg))
+		return
+	}
+
+	msg := b.msg.Format(lang, "refund_rejected", map[string]interface{}{
+		"OrderID": orderID,
+		"Reason":  reason,
+	})
+	b.api.Send(tgbotapi.NewMessage(user.TgUserID, msg))
+}
+
+// RunRefundArbitrationScheduler periodically escalates refund requests
+// stuck past their current stage's deadline to the arbitration group, and
+// expires requests that go unanswered even there.
+func (b *Bot) RunRefundArbitrationScheduler(ctx context.Context) {
+	interval := time.Duration(b.config.RefundSchedulerIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.processOverdueRefunds()
+		}
+	}
+}
+
+func (b *Bot) processOverdueRefunds() {
+	overdue, err := store.ClaimOverdueRefundRequests(b.db)
+	if err != nil {
+		logger.Error("Failed to list overdue refund requests", "error", err)
+		return
+	}
+
+	for _, refund := range overdue {
+		if refund.Status == store.RefundStatusArbitration {
+			if err := store.ExpireRefundRequest(b.db, refund.ID); err != nil {
+				logger.Error("Failed to expire refund request", "error", err, "refund_id", refund.ID)
+				continue
+			}
+			b.notifyBuyerOfRefundDecision(refund.UserID, refund.OrderID, false, 0, "No response from support within the arbitration window.")
+			continue
+		}
+
+		if err := store.EscalateRefundRequest(b.db, refund.ID, b.refundArbitrationDeadline()); err != nil {
+			logger.Error("Failed to escalate refund request", "error", err, "refund_id", refund.ID)
+			continue
+		}
+		if b.config.RefundArbitrationGroupChatID != 0 {
+			text := fmt.Sprintf("⚠️ *Refund escalated to arbitration*\n\nOrder #%d, amount %s, unanswered past deadline.",
+				refund.OrderID, money.Format(money.FromCents(refund.RequestedAmountCents)))
+			keyboard := tgbotapi.NewInlineKeyboardMarkup(
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("✅ Full refund", fmt.Sprintf("refund_approve_full:%d", refund.ID)),
+				),
+				tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData("➗ Partial refund", fmt.Sprintf("refund_approve_partial:%d", refund.ID)),
+					tgbotapi.NewInlineKeyboardButtonData("❌ Reject", fmt.Sprintf("refund_reject:%d", refund.ID)),
+				),
+			)
+			msg := tgbotapi.NewMessage(b.config.RefundArbitrationGroupChatID, text)
+			msg.ParseMode = "Markdown"
+			msg.ReplyMarkup = keyboard
+			b.api.Send(msg)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/bot/fsm"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/router"
+	"shop-bot/internal/store"
+)
+
+// handleAdminRouterCommand dispatches /reply, /close, /assign, /list, and
+// /search so admins can act on a ticket directly instead of reply-quoting
+// its notification message. It's registered as a dispatcher command, and
+// the dispatcher resolves commands before the text fallback that reaches
+// handleAdminTicketReply, so an admin typing one of these commands never
+// falls through to the reply-to-notification flow.
+func (b *Bot) handleAdminRouterCommand(message *tgbotapi.Message) {
+	cmd, ok := router.Parse(message)
+	if !ok {
+		return
+	}
+
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ?", message.From.ID).First(&admin).Error; err != nil {
+		logger.Error("Router command from non-admin or unknown admin", "telegram_id", message.From.ID, "error", err)
+		return
+	}
+
+	switch cmd.Kind {
+	case router.KindReply:
+		b.routerReply(message, admin, cmd.TicketNumber, cmd.Body)
+	case router.KindClose:
+		b.routerClose(message, admin, cmd.TicketNumber)
+	case router.KindAssign:
+		b.routerAssign(message, cmd.TicketNumber, cmd.Admin)
+	case router.KindList:
+		b.routerList(message)
+	case router.KindSearch:
+		b.routerSearch(message, cmd.Query)
+	}
+}
+
+func (b *Bot) routerReply(message *tgbotapi.Message, admin store.AdminUser, ticketNumber, body string) {
+	ticket, err := store.FindTicketByNumber(b.db, ticketNumber)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found: "+ticketNumber))
+		return
+	}
+	if body == "" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /reply <ticket> <message> [#code:123456]"))
+		return
+	}
+
+	body, totpCode := extractTOTPCode(body)
+	if err := b.requireTwoFactor(admin, ticket, "ticket_reply", totpCode); err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ "+err.Error()))
+		return
+	}
+
+	contentHTML := entitiesToHTML(body, nil)
+	if err := b.ticketService.AddRichMessage(ticket.ID, "admin", message.From.ID, admin.Username, contentHTML, message.MessageID); err != nil {
+		logger.Error("Failed to add router reply to ticket", "error", err, "ticket_id", ticket.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ å‘é€å¤±è´¥ / Failed to send message"))
+		return
+	}
+
+	prefix := "ğŸ’¬ å®¢æœå›å¤ / Support Reply\n\n"
+	telegramMessageID, err := b.deliverTicketReply(*ticket, prefix+body, nil)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ å‘é€å¤±è´¥ï¼Œç”¨æˆ·å¯èƒ½å·²åœæ­¢æœºå™¨äºº / Failed to send, user may have blocked the bot"))
+		return
+	}
+	rememberRelayedMessage(message.MessageID, ticket.ID, ticket.UserID, telegramMessageID)
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âœ… æ¶ˆæ¯å·²å‘é€ç»™ç”¨æˆ· / Message sent to user"))
+}
+
+func (b *Bot) routerClose(message *tgbotapi.Message, admin store.AdminUser, ticketNumber string) {
+	ticketNumber, totpCode := extractTOTPCode(ticketNumber)
+	ticket, err := store.FindTicketByNumber(b.db, ticketNumber)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found: "+ticketNumber))
+		return
+	}
+	if err := b.requireTwoFactor(admin, ticket, "ticket_close", totpCode); err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ "+err.Error()))
+		return
+	}
+	if err := store.CloseTicket(b.db, ticket.ID); err != nil {
+		logger.Error("Failed to close ticket", "error", err, "ticket_id", ticket.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ å…³é—­å¤±è´¥ / Failed to close ticket"))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("âœ… å·¥å• %s å·²å…³é—­ / Ticket %s closed", ticketNumber, ticketNumber)))
+}
+
+func (b *Bot) routerAssign(message *tgbotapi.Message, ticketNumber, admin string) {
+	ticket, err := store.FindTicketByNumber(b.db, ticketNumber)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ æ‰¾ä¸åˆ°å·¥å• / Ticket not found: "+ticketNumber))
+		return
+	}
+	if admin == "" {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /assign <ticket> @admin"))
+		return
+	}
+	if err := store.AssignTicket(b.db, ticket.ID, admin); err != nil {
+		logger.Error("Failed to assign ticket", "error", err, "ticket_id", ticket.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ åˆ†é…å¤±è´¥ / Failed to assign ticket"))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("âœ… å·¥å• %s å·²åˆ†é…ç»™ @%s / Ticket %s assigned to @%s", ticketNumber, admin, ticketNumber, admin)))
+}
+
+func (b *Bot) routerList(message *tgbotapi.Message) {
+	tickets, err := store.ListOpenTickets(b.db)
+	if err != nil {
+		logger.Error("Failed to list open tickets", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ æŸ¥è¯¢å¤±è´¥ / Failed to list tickets"))
+		return
+	}
+	if len(tickets) == 0 {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "No open tickets."))
+		return
+	}
+
+	candidates := ticketsToCandidates(tickets)
+	reply := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%d open ticket(s):", len(candidates)))
+	reply.ReplyMarkup = router.ResultKeyboard(candidates)
+	b.api.Send(reply)
+}
+
+func (b *Bot) routerSearch(message *tgbotapi.Message, query string) {
+	tickets, err := store.SearchableTickets(b.db)
+	if err != nil {
+		logger.Error("Failed to load tickets for search", "error", err)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ æŸ¥è¯¢å¤±è´¥ / Search failed"))
+		return
+	}
+
+	ranked := router.Search(query, ticketsToCandidates(tickets))
+	if len(ranked) == 0 {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No tickets matched %q.", query)))
+		return
+	}
+	if len(ranked) > 10 {
+		ranked = ranked[:10]
+	}
+
+	reply := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Results for %q:", query))
+	reply.ReplyMarkup = router.ResultKeyboard(ranked)
+	b.api.Send(reply)
+}
+
+func ticketsToCandidates(tickets []store.Ticket) []router.TicketCandidate {
+	candidates := make([]router.TicketCandidate, len(tickets))
+	for i, t := range tickets {
+		candidates[i] = router.TicketCandidate{
+			ID:       t.ID,
+			Number:   t.TicketID,
+			Subject:  t.Subject,
+			Username: t.Username,
+		}
+	}
+	return candidates
+}
+
+// handleRouterCallback handles the Reply/Close/View buttons rendered by
+// router.ResultKeyboard for /list and /search results.
+func (b *Bot) handleRouterCallback(callback *tgbotapi.CallbackQuery) {
+	data := callback.Data
+	switch {
+	case strings.HasPrefix(data, "router_reply:"):
+		ticketID := parseRefundID(data, "router_reply:")
+		if err := b.fsm.Enter(callback.From.ID, "awaiting_router_reply", fsm.Context{"ticket_id": float64(ticketID)}); err != nil {
+			logger.Error("Failed to enter awaiting_router_reply state", "error", err, "ticket_id", ticketID)
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to start reply"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Send the reply text now."))
+
+	case strings.HasPrefix(data, "router_close:"):
+		ticketID := parseRefundID(data, "router_close:")
+		if err := store.CloseTicket(b.db, ticketID); err != nil {
+			logger.Error("Failed to close ticket via router callback", "error", err, "ticket_id", ticketID)
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to close"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Closed"))
+
+	case strings.HasPrefix(data, "router_view:"):
+		ticketID := parseRefundID(data, "router_view:")
+		var ticket store.Ticket
+		if err := b.db.First(&ticket, ticketID).Error; err != nil {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "Ticket not found"))
+			return
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		b.api.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, fmt.Sprintf("#%s %s\nUser: %s\nStatus: %s", ticket.TicketID, ticket.Subject, ticket.Username, ticket.Status)))
+	}
+}
+
+func (b *Bot) handleRouterReplyText(message *tgbotapi.Message, session *fsm.Session) {
+	ticketIDFloat, _ := session.Data["ticket_id"].(float64)
+	ticketID := uint(ticketIDFloat)
+
+	var ticket store.Ticket
+	if err := b.db.First(&ticket, ticketID).Error; err != nil {
+		logger.Error("Failed to find ticket for router reply", "error", err, "ticket_id", ticketID)
+		return
+	}
+
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ?", message.From.ID).First(&admin).Error; err != nil {
+		logger.Error("Router reply from unknown admin", "telegram_id", message.From.ID, "error", err)
+		return
+	}
+
+	replyBody, totpCode := extractTOTPCode(message.Text)
+	if err := b.requireTwoFactor(admin, &ticket, "ticket_reply", totpCode); err != nil {
+		logger.Warn("Router reply blocked by 2FA gate", "error", err, "admin_id", admin.ID, "ticket_id", ticket.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âŒ "+err.Error()))
+		return
+	}
+	replyEntities := trimEntitiesToText(message.Entities, replyBody)
+
+	contentHTML := entitiesToHTML(replyBody, replyEntities)
+	if err := b.ticketService.AddRichMessage(ticket.ID, "admin", message.From.ID, admin.Username, contentHTML, message.MessageID); err != nil {
+		logger.Error("Failed to add router reply to ticket", "error", err, "ticket_id", ticket.ID)
+		return
+	}
+
+	prefix := "ğŸ’¬ å®¢æœå›å¤ / Support Reply\n\n"
+	shiftedEntities := shiftEntities(replyEntities, len([]rune(prefix)))
+	telegramMessageID, err := b.deliverTicketReply(ticket, prefix+replyBody, shiftedEntities)
+	if err != nil {
+		logger.Error("Failed to send router reply to user", "error", err, "user_id", ticket.UserID)
+		return
+	}
+	rememberRelayedMessage(message.MessageID, ticket.ID, ticket.UserID, telegramMessageID)
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "âœ… æ¶ˆæ¯å·²å‘é€ç»™ç”¨æˆ· / Message sent to user"))
+}
@@ -0,0 +1,282 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/notify"
+	"shop-bot/internal/store"
+)
+
+// deliverTicketReply sends an admin's reply to the ticket owner, primarily
+// over Telegram, and additionally fans it out to any other channel the
+// buyer has enabled (store.UserChannel) - Slack, a generic webhook, email.
+// If the Telegram send itself fails (e.g. the buyer blocked the bot), it
+// automatically falls back to email using the same rendered text, so the
+// conversation doesn't just drop. Returns the Telegram message ID (0 if the
+// Telegram send failed) for the caller's relay/edit tracking.
+func (b *Bot) deliverTicketReply(ticket store.Ticket, text string, entities []tgbotapi.MessageEntity) (telegramMessageID int, err error) {
+	ctx := context.Background()
+
+	channels, chErr := store.GetEnabledChannelsForUser(b.db, ticket.UserID)
+	if chErr != nil {
+		logger.Warn("Failed to load user channels, delivering via Telegram only", "user_id", ticket.UserID, "error", chErr)
+	}
+
+	telegramMsg := tgbotapi.NewMessage(ticket.UserID, text)
+	telegramMsg.Entities = entities
+	sentMsg, sendErr := b.api.Send(telegramMsg)
+	if sendErr != nil {
+		logger.Error("Telegram delivery failed, falling back to email", "user_id", ticket.UserID, "error", sendErr)
+		if emailChannel, lookupErr := store.GetUserChannel(b.db, ticket.UserID, "email"); lookupErr == nil {
+			if notifier, ok := b.notifiers["email"]; ok {
+				if _, fallbackErr := notifier.Send(ctx, notify.UserRef{Target: emailChannel.Target}, notify.Message{Text: text}); fallbackErr != nil {
+					logger.Error("Email fallback also failed", "user_id", ticket.UserID, "error", fallbackErr)
+				}
+			}
+		}
+		err = sendErr
+	} else {
+		telegramMessageID = sentMsg.MessageID
+	}
+
+	for _, ch := range channels {
+		if ch.Channel == "telegram" {
+			continue
+		}
+		notifier, ok := b.notifiers[ch.Channel]
+		if !ok {
+			logger.Warn("No notifier registered for enabled channel", "channel", ch.Channel, "user_id", ticket.UserID)
+			continue
+		}
+		if _, sendErr := notifier.Send(ctx, notify.UserRef{Target: ch.Target}, notify.Message{Text: text}); sendErr != nil {
+			logger.Error("Failed to deliver ticket reply on channel", "channel", ch.Channel, "user_id", ticket.UserID, "error", sendErr)
+		}
+	}
+
+	return telegramMessageID, err
+}
+
+// relayedTicketMessage remembers where a ticket message was relayed to, so
+// that when the source message is later edited we know which message to
+// edit on the other side, similar to how GoBlog persists chat/message IDs to
+// update posts later.
+type relayedTicketMessage struct {
+	ticketID        uint
+	targetChatID    int64
+	targetMessageID int
+}
+
+// relayMu/relayedMessages are keyed by the source message's ID (stable
+// across Telegram edits), scoped to the bot package since the underlying
+// ticket transcript storage lives in internal/ticket.
+var (
+	relayMu         sync.Mutex
+	relayedMessages = make(map[int]relayedTicketMessage)
+)
+
+func rememberRelayedMessage(sourceMessageID int, ticketID uint, targetChatID int64, targetMessageID int) {
+	relayMu.Lock()
+	defer relayMu.Unlock()
+	relayedMessages[sourceMessageID] = relayedTicketMessage{
+		ticketID:        ticketID,
+		targetChatID:    targetChatID,
+		targetMessageID: targetMessageID,
+	}
+}
+
+func lookupRelayedMessage(sourceMessageID int) (relayedTicketMessage, bool) {
+	relayMu.Lock()
+	defer relayMu.Unlock()
+	relay, ok := relayedMessages[sourceMessageID]
+	return relay, ok
+}
+
+// entitiesToHTML renders a message's text+entities as Telegram-flavored HTML
+// (the subset accepted with ParseMode "HTML"), so bold/italic/links/code/
+// mentions survive being stored as a ticket transcript instead of collapsing
+// to plain text. Entity offsets are in UTF-16 code units per the Bot API, so
+// the text is walked in UTF-16 units rather than runes.
+func entitiesToHTML(text string, entities []tgbotapi.MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	if len(entities) == 0 {
+		return html.EscapeString(string(utf16.Decode(units)))
+	}
+
+	type boundary struct {
+		pos      int
+		openTag  string
+		closeTag string
+		order    int
+	}
+
+	var boundaries []boundary
+	for _, e := range entities {
+		openTag, closeTag := entityTags(e)
+		if openTag == "" {
+			continue
+		}
+		start := e.Offset
+		end := e.Offset + e.Length
+		if start < 0 || end > len(units) || start >= end {
+			continue
+		}
+		// order keeps close tags nesting correctly when entities share a
+		// boundary: opens sort after closes, inner ranges close first, and
+		// wider ranges open first.
+		boundaries = append(boundaries, boundary{pos: start, openTag: openTag, order: len(units) - end})
+		boundaries = append(boundaries, boundary{pos: end, closeTag: closeTag, order: -1 - start})
+	}
+	sort.SliceStable(boundaries, func(i, j int) bool {
+		if boundaries[i].pos != boundaries[j].pos {
+			return boundaries[i].pos < boundaries[j].pos
+		}
+		return boundaries[i].order < boundaries[j].order
+	})
+
+	var out []byte
+	last := 0
+	bi := 0
+	for pos := 0; pos <= len(units); pos++ {
+		for bi < len(boundaries) && boundaries[bi].pos == pos {
+			if pos > last {
+				out = append(out, html.EscapeString(string(utf16.Decode(units[last:pos])))...)
+				last = pos
+			}
+			if boundaries[bi].closeTag != "" {
+				out = append(out, boundaries[bi].closeTag...)
+			} else {
+				out = append(out, boundaries[bi].openTag...)
+			}
+			bi++
+		}
+	}
+	if last < len(units) {
+		out = append(out, html.EscapeString(string(utf16.Decode(units[last:])))...)
+	}
+	return string(out)
+}
+
+// entityTags maps a single Telegram entity to its Telegram-HTML open/close
+// tags. Entity types with no HTML representation (e.g. plain mentions,
+// hashtags) return empty strings and are left as plain text.
+func entityTags(e tgbotapi.MessageEntity) (open, close string) {
+	switch e.Type {
+	case "bold":
+		return "<b>", "</b>"
+	case "italic":
+		return "<i>", "</i>"
+	case "underline":
+		return "<u>", "</u>"
+	case "strikethrough":
+		return "<s>", "</s>"
+	case "spoiler":
+		return "<tg-spoiler>", "</tg-spoiler>"
+	case "code":
+		return "<code>", "</code>"
+	case "pre":
+		return "<pre>", "</pre>"
+	case "text_link":
+		return `<a href="` + html.EscapeString(e.URL) + `">`, "</a>"
+	case "text_mention":
+		if e.User != nil {
+			return `<a href="tg://user?id=` + strconv.FormatInt(e.User.ID, 10) + `">`, "</a>"
+		}
+		return "", ""
+	default:
+		return "", ""
+	}
+}
+
+// handleEditedMessage propagates an edit made on one side of a ticket
+// conversation to the relayed copy on the other side. Group chats don't
+// participate in ticket relaying, so they're ignored here same as in
+// handleUpdate's other branches.
+func (b *Bot) handleEditedMessage(message *tgbotapi.Message) {
+	if message.Chat.IsGroup() || message.Chat.IsSuperGroup() {
+		return
+	}
+	if b.ticketService == nil {
+		return
+	}
+
+	if message.ReplyToMessage != nil {
+		b.handleAdminTicketReplyEdited(message)
+		return
+	}
+	b.handleUserTicketMessageEdited(message)
+}
+
+// handleAdminTicketReplyEdited updates the stored transcript and the
+// already-relayed message in the buyer's chat when an admin edits their
+// reply. Only replies we actually relayed (i.e. found in relayedMessages)
+// can be propagated - an edit to an unrelated message is a no-op.
+func (b *Bot) handleAdminTicketReplyEdited(message *tgbotapi.Message) {
+	relay, ok := lookupRelayedMessage(message.MessageID)
+	if !ok {
+		return
+	}
+
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ?", message.From.ID).First(&admin).Error; err != nil {
+		logger.Error("Failed to find admin for edited reply", "telegram_id", message.From.ID, "error", err)
+		return
+	}
+
+	contentHTML := entitiesToHTML(message.Text, message.Entities)
+	if err := b.ticketService.AddRichMessage(relay.ticketID, "admin", message.From.ID, admin.Username, contentHTML, message.MessageID); err != nil {
+		logger.Error("Failed to update edited admin reply in ticket", "error", err, "ticket_id", relay.ticketID)
+	}
+
+	prefix := "ğŸ’¬ å®¢æœå›å¤ / Support Reply\n\n"
+	edit := tgbotapi.NewEditMessageText(relay.targetChatID, relay.targetMessageID, prefix+message.Text)
+	edit.Entities = shiftEntities(message.Entities, len([]rune(prefix)))
+	if _, err := b.api.Send(edit); err != nil {
+		logger.Error("Failed to propagate edited reply to user", "error", err, "user_id", relay.targetChatID)
+	}
+}
+
+// handleUserTicketMessageEdited updates the stored transcript when a buyer
+// edits a message that was part of their active ticket. There is no admin-
+// facing relay to edit here (ticket messages are surfaced to admins via
+// internal/ticket's own notification path), so this only keeps the
+// transcript itself in sync.
+func (b *Bot) handleUserTicketMessageEdited(message *tgbotapi.Message) {
+	ticket, err := b.ticketService.GetTicketByUserMessage(message.From.ID)
+	if err != nil || ticket == nil {
+		return
+	}
+
+	username := message.From.UserName
+	if username == "" {
+		username = fmt.Sprintf("User %d", message.From.ID)
+	}
+
+	contentHTML := entitiesToHTML(message.Text, message.Entities)
+	if err := b.ticketService.AddRichMessage(ticket.ID, "user", message.From.ID, username, contentHTML, message.MessageID); err != nil {
+		logger.Error("Failed to update edited user message in ticket", "error", err, "ticket_id", ticket.ID)
+	}
+}
+
+// shiftEntities offsets every entity by delta UTF-16 code units, for when
+// plain text is prepended to a message before entities from the original are
+// re-applied to the outgoing copy.
+func shiftEntities(entities []tgbotapi.MessageEntity, delta int) []tgbotapi.MessageEntity {
+	if len(entities) == 0 {
+		return nil
+	}
+	shifted := make([]tgbotapi.MessageEntity, len(entities))
+	for i, e := range entities {
+		e.Offset += delta
+		shifted[i] = e
+	}
+	return shifted
+}
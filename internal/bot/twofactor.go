@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/bot/fsm"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+	"shop-bot/internal/twofactor"
+)
+
+// totpSuffixPattern matches a trailing "#code:123456" an admin appends to a
+// reply to pass their TOTP code inline; this suffix is the only way a
+// reply carries the code.
+var totpSuffixPattern = regexp.MustCompile(`(?i)\s*#code:(\d{6})\s*$`)
+
+// extractTOTPCode splits a trailing "#code:123456" off text, returning the
+// remaining body and the 6-digit code (empty if none was present).
+func extractTOTPCode(text string) (body string, code string) {
+	loc := totpSuffixPattern.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return text, ""
+	}
+	return text[:loc[0]], text[loc[2]:loc[3]]
+}
+
+// trimEntitiesToText drops any entity that extends past body's length, for
+// when extractTOTPCode has truncated the trailing "#code:..." suffix off
+// the original message text.
+func trimEntitiesToText(entities []tgbotapi.MessageEntity, body string) []tgbotapi.MessageEntity {
+	limit := len(utf16.Encode([]rune(body)))
+	var kept []tgbotapi.MessageEntity
+	for _, e := range entities {
+		if e.Offset+e.Length <= limit {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// sensitiveTicketKeywords flags a ticket as touching payment info, which
+// requires a fresh TOTP code regardless of the replying admin's role.
+var sensitiveTicketKeywords = []string{"refund", "payment", "退款", "充值", "订单"}
+
+func isSensitiveTicket(ticket store.Ticket) bool {
+	subject := strings.ToLower(ticket.Subject)
+	for _, kw := range sensitiveTicketKeywords {
+		if strings.Contains(subject, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireTwoFactor gates a privileged admin action behind a fresh TOTP
+// code when the ticket is sensitive or the admin is a superadmin, logging
+// every attempt (success or failure) so a compromised Telegram account
+// can't silently drain refunds without leaving a trail.
+func (b *Bot) requireTwoFactor(admin store.AdminUser, ticket *store.Ticket, action, code string) error {
+	sensitive := admin.Role == "superadmin"
+	if ticket != nil && isSensitiveTicket(*ticket) {
+		sensitive = true
+	}
+	if !sensitive {
+		return nil
+	}
+
+	var ticketID *uint
+	if ticket != nil {
+		id := ticket.ID
+		ticketID = &id
+	}
+
+	if code == "" {
+		store.RecordTwoFactorAttempt(b.db, admin.ID, action, ticketID, false)
+		return fmt.Errorf("this action requires a 2FA code - append #code:123456 to your reply")
+	}
+
+	secret, err := store.GetTwoFactorSecret(b.db, admin.ID)
+	if err != nil || !secret.Enabled {
+		store.RecordTwoFactorAttempt(b.db, admin.ID, action, ticketID, false)
+		return fmt.Errorf("you have not enrolled in 2FA yet - run /2fa_enroll")
+	}
+
+	if !twofactor.Validate(secret.Secret, code) {
+		store.RecordTwoFactorAttempt(b.db, admin.ID, action, ticketID, false)
+		return fmt.Errorf("invalid or expired 2FA code")
+	}
+
+	store.RecordTwoFactorAttempt(b.db, admin.ID, action, ticketID, true)
+	return nil
+}
+
+// handleTwoFactorEnroll is "/2fa_enroll": it (re)generates a TOTP secret for
+// the calling admin, sends it as a QR code, and drops them into the
+// awaiting_2fa_confirm state so enrollment only takes effect once they've
+// proven possession of the authenticator with one valid code.
+func (b *Bot) handleTwoFactorEnroll(message *tgbotapi.Message) {
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", message.From.ID, true).First(&admin).Error; err != nil {
+		return
+	}
+
+	secret, otpauthURL, err := twofactor.GenerateSecret(admin.Username)
+	if err != nil {
+		logger.Error("Failed to generate 2FA secret", "error", err, "admin_id", admin.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to generate 2FA secret, try again later."))
+		return
+	}
+	if err := store.SaveTwoFactorSecret(b.db, admin.ID, secret); err != nil {
+		logger.Error("Failed to save 2FA secret", "error", err, "admin_id", admin.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Failed to save 2FA secret, try again later."))
+		return
+	}
+
+	png, err := twofactor.QRCodePNG(otpauthURL, 256)
+	if err != nil {
+		logger.Error("Failed to render 2FA QR code", "error", err, "admin_id", admin.ID)
+	} else {
+		photo := tgbotapi.NewPhoto(message.Chat.ID, tgbotapi.FileBytes{Name: "2fa.png", Bytes: png})
+		photo.Caption = fmt.Sprintf("Scan this with your authenticator app, or enter the secret manually: %s", secret)
+		b.api.Send(photo)
+	}
+
+	if err := b.fsm.Enter(message.From.ID, "awaiting_2fa_confirm", fsm.Context{}); err != nil {
+		logger.Warn("Failed to enter awaiting_2fa_confirm state", "error", err, "admin_id", admin.ID)
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Enter the 6-digit code from your authenticator app to confirm enrollment."))
+}
+
+// handleTwoFactorConfirm finishes /2fa_enroll: the admin's next plain-text
+// message is checked against the secret saved moments ago, and enrollment
+// only becomes Enabled (i.e. actually required by requireTwoFactor) once
+// that check passes.
+func (b *Bot) handleTwoFactorConfirm(message *tgbotapi.Message) {
+	var admin store.AdminUser
+	if err := b.db.Where("telegram_id = ? AND is_active = ?", message.From.ID, true).First(&admin).Error; err != nil {
+		return
+	}
+
+	secret, err := store.GetTwoFactorSecret(b.db, admin.ID)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "No pending enrollment found, run /2fa_enroll again."))
+		return
+	}
+
+	code := strings.TrimSpace(message.Text)
+	if !twofactor.Validate(secret.Secret, code) {
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Invalid code, try again or run /2fa_enroll to restart."))
+		return
+	}
+
+	if err := store.EnableTwoFactorSecret(b.db, admin.ID); err != nil {
+		logger.Error("Failed to enable 2FA", "error", err, "admin_id", admin.ID)
+		b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "Code valid but failed to save, try /2fa_enroll again."))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(message.Chat.ID, "2FA enrollment confirmed. Sensitive actions now require a fresh code."))
+}
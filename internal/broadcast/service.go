@@ -0,0 +1,142 @@
+// Package broadcast sends templated messages to every known user, with
+// per-recipient localization and optional A/B variants.
+package broadcast
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	htmltemplate "html/template"
+	"sort"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"gorm.io/gorm"
+
+	"shop-bot/internal/bot/messages"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/metrics"
+	"shop-bot/internal/store"
+	"shop-bot/internal/tmplfuncs"
+)
+
+// Template holds one broadcast's named A/B variants and their send split.
+// Variants maps a variant name to template source text (rendered through
+// messages.Manager.Format, so it can use the recipient's language and the
+// usual {{.Field}} placeholders). Split maps a variant name to its relative
+// weight; a variant missing from Split gets weight 1 (an even split).
+type Template struct {
+	Variants map[string]string
+	Split    map[string]int
+}
+
+// Service sends broadcasts to the full user base.
+type Service struct {
+	db  *gorm.DB
+	api *tgbotapi.BotAPI
+}
+
+// NewService builds a broadcast Service backed by db and api.
+func NewService(db *gorm.DB, api *tgbotapi.BotAPI) *Service {
+	return &Service{db: db, api: api}
+}
+
+// Send renders tmpl for every user (localized to their stored language,
+// bucketed into one of tmpl's variants) and queues it for delivery through
+// the same durable, rate-limited store.TelegramSendJob queue the admin
+// "test notifier" flow uses (see internal/worker.TelegramSendQueue) rather
+// than calling the Telegram API directly in this loop, so a broadcast to a
+// large user base can't blow past Telegram's flood limits. "sent" here
+// means "queued for delivery", not "confirmed delivered" - the queue
+// worker retries/records actual delivery asynchronously.
+func (s *Service) Send(campaignID uint, tmpl Template, data map[string]interface{}) (sent int, failed int, err error) {
+	variants := sortedVariantNames(tmpl)
+	if len(variants) == 0 {
+		return 0, 0, fmt.Errorf("broadcast template has no variants")
+	}
+
+	users, err := store.GetAllUsers(s.db)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load broadcast recipients: %w", err)
+	}
+
+	for _, user := range users {
+		variant := assignVariant(variants, tmpl.Split, user.ID)
+		lang := messages.GetUserLanguage(user.Language, "")
+		text := messages.GetManager().Format(lang, tmpl.Variants[variant], data)
+
+		if _, queueErr := store.EnqueueTelegramSendJob(s.db, user.TgUserID, text, ""); queueErr != nil {
+			failed++
+			metrics.BroadcastSent.WithLabelValues("failed").Inc()
+			logger.Warn("Failed to queue broadcast message", "error", queueErr, "user_id", user.ID, "variant", variant)
+			continue
+		}
+
+		sent++
+		metrics.BroadcastSent.WithLabelValues("sent").Inc()
+		if recErr := store.RecordBroadcastDelivery(s.db, campaignID, user.ID, variant); recErr != nil {
+			logger.Warn("Failed to record broadcast delivery", "error", recErr, "user_id", user.ID)
+		}
+	}
+
+	return sent, failed, nil
+}
+
+// Stats returns delivery and click-through counts per variant for campaignID.
+func (s *Service) Stats(campaignID uint) ([]store.BroadcastVariantStat, error) {
+	return store.GetBroadcastStats(s.db, campaignID)
+}
+
+// Preview renders tmplSrc against data using the same template.FuncMap the
+// admin router uses, so operators see the exact output before sending -
+// including currency formatting and the usual arithmetic helpers.
+func Preview(tmplSrc, currencySymbol string, data map[string]interface{}) (string, error) {
+	t, err := htmltemplate.New("broadcast_preview").Funcs(tmplfuncs.FuncMap(currencySymbol)).Parse(tmplSrc)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// assignVariant deterministically buckets userID into one of variants by
+// weighted hash, so a given user always lands in the same variant for a
+// given split (repeat sends of the same campaign stay consistent).
+func assignVariant(variants []string, split map[string]int, userID uint) string {
+	weights := make([]int, len(variants))
+	total := 0
+	for i, v := range variants {
+		w := split[v]
+		if w <= 0 {
+			w = 1
+		}
+		weights[i] = w
+		total += w
+	}
+
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d", userID)
+	bucket := int(h.Sum32() % uint32(total))
+
+	cursor := 0
+	for i, w := range weights {
+		cursor += w
+		if bucket < cursor {
+			return variants[i]
+		}
+	}
+	return variants[len(variants)-1]
+}
+
+func sortedVariantNames(tmpl Template) []string {
+	names := make([]string, 0, len(tmpl.Variants))
+	for name := range tmpl.Variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
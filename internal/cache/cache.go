@@ -0,0 +1,69 @@
+// Package cache wraps the Redis client used for ephemeral state (rate
+// limiting, dedupe sets, cached lookups) across the bot.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client is a thin wrapper around *redis.Client so callers can depend on a
+// concrete, zero-value-safe type even when Redis isn't configured.
+type Client struct {
+	rdb *redis.Client
+}
+
+// NewClient parses redisURL and opens a connection pool. An empty URL is
+// rejected so callers fall back to a zero-value Client (cache disabled).
+func NewClient(redisURL string) (*Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{rdb: redis.NewClient(opts)}, nil
+}
+
+// Ping verifies connectivity, used by the /readyz check. A nil/disabled
+// client is reported healthy since the bot runs fine without a cache.
+func (c *Client) Ping(ctx context.Context) error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	return c.rdb.Ping(ctx).Err()
+}
+
+// Close releases the underlying connection pool.
+func (c *Client) Close() error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	return c.rdb.Close()
+}
+
+// Set stores value under key with the given TTL (0 means no expiry).
+func (c *Client) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if c == nil || c.rdb == nil {
+		return nil
+	}
+	return c.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+// Get returns the stored value for key, or redis.Nil if absent.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	if c == nil || c.rdb == nil {
+		return "", redis.Nil
+	}
+	return c.rdb.Get(ctx, key).Result()
+}
+
+// SetNX sets key only if it doesn't already exist, returning whether it was
+// newly set. Used for the webhook update_id dedupe and idempotency keys.
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if c == nil || c.rdb == nil {
+		return true, nil
+	}
+	return c.rdb.SetNX(ctx, key, value, ttl).Result()
+}
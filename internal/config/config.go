@@ -2,14 +2,31 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
-	BotToken    string `envconfig:"BOT_TOKEN" required:"true"`
-	AdminToken  string `envconfig:"ADMIN_TOKEN" required:"true"`
+	// BotToken is the Telegram bot token itself. Leave it empty and set
+	// BotTokenFile or BotTokenCommand instead to avoid putting the token in
+	// an env var any process on the host can read out of /proc - exactly
+	// one of the three may be set; see ResolveBotToken.
+	BotToken   string `envconfig:"BOT_TOKEN" default:""`
+	AdminToken string `envconfig:"ADMIN_TOKEN" required:"true"`
+	// BotTokenFile, if set, is read at startup and on every Reload to
+	// populate BotToken, the same "_FILE" convention Alertmanager and most
+	// Docker/Kubernetes secret-mount setups use for credentials.
+	BotTokenFile string `envconfig:"BOT_TOKEN_FILE" default:""`
+	// BotTokenCommand, if set, is run through the shell at startup and on
+	// every Reload; its trimmed stdout becomes BotToken. Useful for
+	// fetching the token from a secret manager's CLI (vault, aws
+	// secretsmanager, etc.) rather than mounting it as a file.
+	BotTokenCommand string `envconfig:"BOT_TOKEN_COMMAND" default:""`
 	
 	// Database configuration - individual fields
 	DBType     string `envconfig:"DB_TYPE" default:"sqlite"` // sqlite or postgres
@@ -23,17 +40,131 @@ type Config struct {
 	// Legacy DB_DSN for backward compatibility
 	DBDSN       string `envconfig:"DB_DSN" default:""`
 	
+	// Logging configuration. Leave LogFile empty to log to stdout only
+	// (the default before this existed); set it to rotate JSON logs
+	// through lumberjack instead.
+	LogFile        string `envconfig:"LOG_FILE" default:""`
+	LogMaxSizeMB   int    `envconfig:"LOG_MAX_SIZE" default:"100"`
+	LogMaxAgeDays  int    `envconfig:"LOG_MAX_AGE" default:"28"`
+	LogMaxBackups  int    `envconfig:"LOG_MAX_BACKUPS" default:"7"`
+	LogLevel       string `envconfig:"LOG_LEVEL" default:"info"`
+
+	// MasterKeySource configures internal/secrets' envelope encryption of
+	// sensitive system settings (bot/admin tokens, payment keys). It's
+	// either the key material itself, "file://<path>", or
+	// "vault://<kv-v2-path>#<field>". Leave empty to store those settings
+	// as plaintext, same as before this existed.
+	MasterKeySource string `envconfig:"MASTER_KEY" default:""`
+
 	// Payment configuration
 	EpayPID     string `envconfig:"EPAY_PID" default:""`
 	EpayKey     string `envconfig:"EPAY_KEY" default:""`
 	EpayGateway string `envconfig:"EPAY_GATEWAY" default:""`
 	BaseURL     string `envconfig:"BASE_URL" default:"http://localhost:7832"`
+
+	// Crypto payment provider (OKX-compatible exchange API). Leave
+	// OKX_API_KEY/OKX_API_SECRET/OKX_PASSPHRASE empty to disable it; epay
+	// remains available on its own in that case.
+	OKXAPIKey     string `envconfig:"OKX_API_KEY" default:""`
+	OKXAPISecret  string `envconfig:"OKX_API_SECRET" default:""`
+	OKXPassphrase string `envconfig:"OKX_PASSPHRASE" default:""`
+	OKXBaseURL    string `envconfig:"OKX_BASE_URL" default:"https://www.okx.com"`
+	OKXCurrency   string `envconfig:"OKX_CURRENCY" default:"USDT"`
+
+	// Native Alipay provider. Leave ALIPAY_APP_ID empty to disable it;
+	// other gateways remain available on their own in that case.
+	AlipayAppID           string `envconfig:"ALIPAY_APP_ID" default:""`
+	AlipayPrivateKey      string `envconfig:"ALIPAY_PRIVATE_KEY" default:""`
+	AlipayPublicKey       string `envconfig:"ALIPAY_PUBLIC_KEY" default:""`
+	AlipayIsProduction    bool   `envconfig:"ALIPAY_IS_PRODUCTION" default:"true"`
+
+	// WeChat Pay v3 (Native pay) provider. Leave WECHAT_MCH_ID empty to
+	// disable it.
+	WechatAppID         string `envconfig:"WECHAT_APP_ID" default:""`
+	WechatMchID         string `envconfig:"WECHAT_MCH_ID" default:""`
+	WechatSerialNo      string `envconfig:"WECHAT_SERIAL_NO" default:""`
+	WechatPrivateKey    string `envconfig:"WECHAT_PRIVATE_KEY" default:""`
+	WechatAPIv3Key      string `envconfig:"WECHAT_API_V3_KEY" default:""`
+	// WechatPlatformCert is WeChat Pay's platform certificate PEM (fetch it
+	// once via GET /v3/certificates), used to verify that an inbound
+	// notification actually came from WeChat Pay before trusting it.
+	WechatPlatformCert string `envconfig:"WECHAT_PLATFORM_CERT" default:""`
 	
 	// Webhook configuration
 	UseWebhook  bool   `envconfig:"USE_WEBHOOK" default:"false"`
 	WebhookURL  string `envconfig:"WEBHOOK_URL"`
 	WebhookPort int    `envconfig:"WEBHOOK_PORT" default:"9147"`
-	
+	// WebhookSecret is compared against Telegram's X-Telegram-Bot-Api-Secret-Token
+	// header. Leave empty to accept any (or no) header, e.g. in local dev.
+	WebhookSecret string `envconfig:"WEBHOOK_SECRET" default:""`
+	// WebhookWorkers bounds how many updates can be processed concurrently;
+	// extra updates queue instead of spawning unbounded goroutines.
+	WebhookWorkers int `envconfig:"WEBHOOK_WORKERS" default:"16"`
+	// WebhookDedupeTTLSeconds bounds how long a delivered update_id is
+	// remembered so Telegram's at-least-once retries are dropped, not reprocessed.
+	WebhookDedupeTTLSeconds int `envconfig:"WEBHOOK_DEDUPE_TTL_SECONDS" default:"300"`
+	// WebhookMaxConnections caps how many simultaneous HTTPS connections
+	// Telegram will open to deliver updates.
+	WebhookMaxConnections int `envconfig:"WEBHOOK_MAX_CONNECTIONS" default:"40"`
+	// WebhookCertPath, if set, is uploaded to Telegram as the webhook's
+	// self-signed certificate.
+	WebhookCertPath string `envconfig:"WEBHOOK_CERT_PATH" default:""`
+	// WebhookSetupMaxAttempts bounds how many times RegisterWebhook retries
+	// setWebhook before giving up and letting the caller fall back to polling.
+	WebhookSetupMaxAttempts int `envconfig:"WEBHOOK_SETUP_MAX_ATTEMPTS" default:"3"`
+	// WebhookDropPendingOnShutdown controls deleteWebhook's
+	// drop_pending_updates flag when the bot shuts down.
+	WebhookDropPendingOnShutdown bool `envconfig:"WEBHOOK_DROP_PENDING_ON_SHUTDOWN" default:"false"`
+
+	// Rate limiting - general per-user token bucket, covering every command,
+	// callback, and text message.
+	RateLimitBurst        float64 `envconfig:"RATE_LIMIT_BURST" default:"20"`
+	RateLimitRefillPerSec float64 `envconfig:"RATE_LIMIT_REFILL_PER_SEC" default:"5"`
+	// Rate limiting - a stricter bucket for expensive actions (buy/deposit
+	// callbacks), on top of the general one above.
+	RateLimitActionBurst        float64 `envconfig:"RATE_LIMIT_ACTION_BURST" default:"3"`
+	RateLimitActionRefillPerSec float64 `envconfig:"RATE_LIMIT_ACTION_REFILL_PER_SEC" default:"0.5"`
+	// RateLimitWarnWindowSeconds bounds how often a throttled user is sent
+	// the "slow down" message, so a burst of blocked taps doesn't also spam them.
+	RateLimitWarnWindowSeconds int `envconfig:"RATE_LIMIT_WARN_WINDOW_SECONDS" default:"30"`
+
+	// ReservationTTLMinutes bounds how long a code stays reserved for a
+	// pending (not-yet-paid) order before the sweeper releases it back to
+	// the pool and cancels the order.
+	ReservationTTLMinutes int `envconfig:"RESERVATION_TTL_MINUTES" default:"15"`
+	// ReservationSweepIntervalSeconds is how often the sweeper checks for
+	// expired reservations.
+	ReservationSweepIntervalSeconds int `envconfig:"RESERVATION_SWEEP_INTERVAL_SECONDS" default:"60"`
+
+	// AutoPaymentSchedulerIntervalSeconds is how often the scheduler checks
+	// for due recurring subscriptions (auto top-ups and auto-purchases).
+	AutoPaymentSchedulerIntervalSeconds int `envconfig:"AUTO_PAYMENT_SCHEDULER_INTERVAL_SECONDS" default:"60"`
+	// AutoPaymentBatchSize bounds how many due subscriptions are claimed per
+	// scheduler tick, so one slow tick can't lock the whole table.
+	AutoPaymentBatchSize int `envconfig:"AUTO_PAYMENT_BATCH_SIZE" default:"50"`
+
+	// RefundArbitrationDeadlineHours bounds how long a refund request waits
+	// for an admin response before escalating to the arbitration group, and
+	// again before auto-expiring if arbitration itself goes unanswered.
+	RefundArbitrationDeadlineHours int `envconfig:"REFUND_ARBITRATION_DEADLINE_HOURS" default:"72"`
+	// RefundArbitrationGroupChatID is the Telegram chat a refund request is
+	// escalated to past its deadline. Leave 0 to disable escalation
+	// notifications (the request still expires on schedule).
+	RefundArbitrationGroupChatID int64 `envconfig:"REFUND_ARBITRATION_GROUP_CHAT_ID" default:"0"`
+	// RefundSchedulerIntervalSeconds is how often the scheduler checks for
+	// refund requests past their current deadline.
+	RefundSchedulerIntervalSeconds int `envconfig:"REFUND_SCHEDULER_INTERVAL_SECONDS" default:"300"`
+
+	// SMTP configuration for EmailNotifier, used both as a user-selectable
+	// ticket reply channel and as the automatic fallback when delivering to
+	// Telegram fails (e.g. the user blocked the bot). Leave SMTPHost empty
+	// to disable email delivery entirely.
+	SMTPHost     string `envconfig:"SMTP_HOST" default:""`
+	SMTPPort     string `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string `envconfig:"SMTP_USERNAME" default:""`
+	SMTPPassword string `envconfig:"SMTP_PASSWORD" default:""`
+	SMTPFrom     string `envconfig:"SMTP_FROM" default:""`
+
 	// HTTP Server configuration
 	Port        int    `envconfig:"PORT" default:"7832"`
 	
@@ -45,6 +176,143 @@ type Config struct {
 	
 	// Legacy REDIS_URL for backward compatibility
 	RedisURL    string `envconfig:"REDIS_URL"`
+
+	// TelegramLoginEnabled turns on the Telegram Login Widget as an
+	// alternative to username/password on the admin login page.
+	TelegramLoginEnabled bool `envconfig:"TELEGRAM_LOGIN_ENABLED" default:"false"`
+	// TelegramLoginAllowedIDs, if non-empty, restricts Telegram SSO to this
+	// comma-separated list of Telegram user IDs even if more AdminUser rows
+	// have a TelegramID set. Leave empty to allow any mapped admin account.
+	TelegramLoginAllowedIDs string `envconfig:"TELEGRAM_LOGIN_ALLOWED_IDS" default:""`
+	// TelegramLoginTTLSeconds bounds how old a widget callback's auth_date
+	// may be before it's rejected as stale.
+	TelegramLoginTTLSeconds int `envconfig:"TELEGRAM_LOGIN_TTL_SECONDS" default:"86400"`
+
+	// EnableCSRF turns on double-submit-cookie CSRF protection for
+	// /admin's state-changing routes (see internal/middleware.CSRFMiddleware).
+	EnableCSRF bool `envconfig:"ENABLE_CSRF" default:"false"`
+
+	// Matrix notification backend (see internal/notifier). Leave
+	// MATRIX_HOMESERVER_URL empty to disable it; Telegram remains available
+	// on its own in that case.
+	MatrixHomeserverURL string `envconfig:"MATRIX_HOMESERVER_URL" default:""`
+	MatrixAccessToken   string `envconfig:"MATRIX_ACCESS_TOKEN" default:""`
+
+	// ntfy notification backend (see internal/notifier). Leave
+	// NTFY_SERVER_URL empty to disable it. NtfyAuthToken is only needed for
+	// a private/self-hosted server that requires auth to publish.
+	NtfyServerURL string `envconfig:"NTFY_SERVER_URL" default:""`
+	NtfyAuthToken string `envconfig:"NTFY_AUTH_TOKEN" default:""`
+
+	// TrustedProxyCIDRs is a comma-separated list of CIDRs (e.g. your load
+	// balancer's subnet) allowed to set X-Forwarded-For/X-Real-IP.
+	// Requests from anywhere else have those headers ignored, so a client
+	// can't spoof its own IP in the audit trail. Leave empty to never
+	// trust those headers (RemoteAddr is always used instead).
+	TrustedProxyCIDRs string `envconfig:"TRUSTED_PROXY_CIDRS" default:""`
+
+	// AdminTelegramIDs is a comma-separated list of Telegram user IDs to
+	// seed as AdminUser rows on startup (see store.InitializeAdminsFromConfig),
+	// for standing up the first admin(s) without a manual DB insert.
+	AdminTelegramIDs string `envconfig:"ADMIN_TELEGRAM_IDS" default:""`
+
+	// Currency and CurrencySymbol are the defaults store.GetCurrencySettings
+	// falls back to when neither the "currency" nor "currency_symbol"
+	// system setting has been set in the database yet.
+	Currency       string `envconfig:"CURRENCY" default:""`
+	CurrencySymbol string `envconfig:"CURRENCY_SYMBOL" default:""`
+}
+
+// TelegramLoginAllowedIDList parses TelegramLoginAllowedIDs into int64
+// Telegram user IDs, skipping anything that doesn't parse. An empty result
+// means "no restriction", not "deny all".
+func (c *Config) TelegramLoginAllowedIDList() []int64 {
+	var ids []int64
+	for _, part := range strings.Split(c.TelegramLoginAllowedIDs, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// TrustedProxyList parses TrustedProxyCIDRs into *net.IPNet values,
+// skipping anything that doesn't parse. An empty result means no proxy is
+// trusted, not "trust everything".
+func (c *Config) TrustedProxyList() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(c.TrustedProxyCIDRs, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if _, n, err := net.ParseCIDR(part); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// GetAdminTelegramIDs parses AdminTelegramIDs into int64 Telegram user IDs,
+// skipping anything that doesn't parse. An empty result means no admins
+// are seeded from config (existing AdminUser rows are unaffected).
+func (c *Config) GetAdminTelegramIDs() []int64 {
+	var ids []int64
+	for _, part := range strings.Split(c.AdminTelegramIDs, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ResolveBotToken fills in BotToken from BotTokenFile or BotTokenCommand when
+// either is set, rejecting the case where more than one source is
+// configured so it's never ambiguous which one wins. It's called from Load
+// at startup and again from Reload on every SIGHUP, so a secret mounted via
+// a rotating file or fetched through a secret-manager CLI stays current
+// without restarting the process.
+func (c *Config) ResolveBotToken() error {
+	sources := 0
+	if c.BotToken != "" {
+		sources++
+	}
+	if c.BotTokenFile != "" {
+		sources++
+	}
+	if c.BotTokenCommand != "" {
+		sources++
+	}
+	if sources > 1 {
+		return fmt.Errorf("config: only one of BOT_TOKEN, BOT_TOKEN_FILE, BOT_TOKEN_COMMAND may be set")
+	}
+
+	if c.BotTokenFile != "" {
+		data, err := os.ReadFile(c.BotTokenFile)
+		if err != nil {
+			return fmt.Errorf("config: read bot_token_file: %w", err)
+		}
+		c.BotToken = strings.TrimSpace(string(data))
+	} else if c.BotTokenCommand != "" {
+		out, err := exec.Command("sh", "-c", c.BotTokenCommand).Output()
+		if err != nil {
+			return fmt.Errorf("config: run bot_token_command: %w", err)
+		}
+		c.BotToken = strings.TrimSpace(string(out))
+	}
+
+	if c.BotToken == "" {
+		return fmt.Errorf("config: no Telegram bot token configured (set BOT_TOKEN, BOT_TOKEN_FILE, or BOT_TOKEN_COMMAND)")
+	}
+	return nil
 }
 
 // GetDBDSN constructs the database DSN from individual fields or returns the legacy DSN
@@ -91,5 +359,8 @@ func Load() (*Config, error) {
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.ResolveBotToken(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
\ No newline at end of file
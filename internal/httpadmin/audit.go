@@ -0,0 +1,124 @@
+package httpadmin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/middleware"
+	"shop-bot/internal/store"
+)
+
+// isAuditableMethod reports whether the audit trail middleware records a
+// request with this HTTP method; GET/HEAD/OPTIONS never mutate anything,
+// so they're skipped.
+func isAuditableMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// AuditTrail returns middleware that writes one store.AuditLog row per
+// mutating /admin request, correlated to the rest of that request's logs
+// via middleware.RequestContext's request ID. Mount it on adminGroup,
+// after authMiddleware, so AdminUserID is already resolved by the time it
+// runs.
+func (s *Server) AuditTrail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAuditableMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		duration := time.Since(start)
+
+		rc := middleware.FromGinContext(c)
+
+		var adminUserID *uint
+		if v, ok := c.Get("user_id"); ok {
+			switch id := v.(type) {
+			case uint:
+				adminUserID = &id
+			case string:
+				if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+					u := uint(n)
+					adminUserID = &u
+				}
+			}
+		}
+
+		entry := &store.AuditLog{
+			RequestID:   rc.RequestID,
+			AdminUserID: adminUserID,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			Status:      c.Writer.Status(),
+			IP:          rc.ClientIP,
+			UserAgent:   c.Request.UserAgent(),
+			DurationMS:  duration.Milliseconds(),
+		}
+		if entity, id, ok := auditEntityFromPath(c.Request.URL.Path); ok {
+			entry.ChangedEntity = entity
+			entry.ChangedID = id
+		}
+
+		if err := store.CreateAuditLog(s.db, entry); err != nil {
+			logger.Error("Failed to write audit log", "error", err, "request_id", rc.RequestID)
+		}
+	}
+}
+
+// auditEntityFromPath does a best-effort split of "/admin/<entity>/<id>"
+// into its entity name and ID, for routes shaped that way (most of them).
+func auditEntityFromPath(path string) (entity string, id string, ok bool) {
+	const prefix = "/admin/"
+	if len(path) <= len(prefix) || path[:len(prefix)] != prefix {
+		return "", "", false
+	}
+	rest := path[len(prefix):]
+	segments := splitPath(rest)
+	if len(segments) == 0 {
+		return "", "", false
+	}
+	entity = segments[0]
+	if len(segments) > 1 {
+		id = segments[1]
+	}
+	return entity, id, true
+}
+
+func splitPath(s string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == '/' {
+			if i > start {
+				segments = append(segments, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return segments
+}
+
+// handleAuditLogList is the GET /admin/audit browser: a paginated, newest
+// first view of every mutating admin request.
+func (s *Server) handleAuditLogList(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	logs, err := store.ListAuditLogs(s.db, limit, offset)
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audit_logs": logs})
+}
@@ -0,0 +1,142 @@
+package httpadmin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"shop-bot/internal/broadcast"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// handleBroadcastList lists every campaign, draft or sent.
+func (s *Server) handleBroadcastList(c *gin.Context) {
+	campaigns, err := store.GetBroadcastCampaigns(s.db)
+	if err != nil {
+		logger.Error("Failed to list broadcast campaigns", "error", err)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// handleBroadcastCreate saves a new draft campaign with its A/B variants
+// and send split, without sending anything yet.
+func (s *Server) handleBroadcastCreate(c *gin.Context) {
+	var req struct {
+		Name     string            `json:"name" binding:"required"`
+		Variants map[string]string `json:"variants" binding:"required"`
+		Split    map[string]int    `json:"split"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if len(req.Variants) == 0 {
+		JSONError(c, NewBadRequestError("At least one variant is required", nil))
+		return
+	}
+
+	campaign, err := store.CreateBroadcastCampaign(s.db, req.Name, req.Variants, req.Split)
+	if err != nil {
+		logger.Error("Failed to create broadcast campaign", "error", err)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign})
+}
+
+// handleBroadcastDetail returns a campaign plus its per-variant delivery/CTR stats.
+func (s *Server) handleBroadcastDetail(c *gin.Context) {
+	id, err := parseUintParam(c, "id")
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid campaign id", err))
+		return
+	}
+
+	campaign, err := store.GetBroadcastCampaign(s.db, id)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Campaign not found", err))
+		return
+	}
+
+	stats, err := s.broadcast.Load().Stats(id)
+	if err != nil {
+		logger.Error("Failed to load broadcast stats", "error", err, "campaign_id", id)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign, "stats": stats})
+}
+
+// handleBroadcastSend renders and delivers a campaign's templates to every
+// user, each localized to their stored language and bucketed into an A/B variant.
+func (s *Server) handleBroadcastSend(c *gin.Context) {
+	var req struct {
+		ID   uint                   `json:"id" binding:"required"`
+		Data map[string]interface{} `json:"data"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	campaign, err := store.GetBroadcastCampaign(s.db, req.ID)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Campaign not found", err))
+		return
+	}
+
+	variants, err := campaign.Variants()
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	split, err := campaign.Split()
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	sent, failed, err := s.broadcast.Load().Send(campaign.ID, broadcast.Template{Variants: variants, Split: split}, req.Data)
+	if err != nil {
+		logger.Error("Failed to send broadcast campaign", "error", err, "campaign_id", campaign.ID)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	if err := store.MarkBroadcastCampaignSent(s.db, campaign.ID); err != nil {
+		logger.Warn("Failed to mark campaign sent", "error", err, "campaign_id", campaign.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sent": sent, "failed": failed})
+}
+
+// handleBroadcastPreview renders a template against sample data using the
+// same template.FuncMap as the main admin router, so an operator sees the
+// exact output before a campaign goes out.
+func (s *Server) handleBroadcastPreview(c *gin.Context) {
+	var req struct {
+		Template string                 `json:"template" binding:"required"`
+		Data     map[string]interface{} `json:"data"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	_, currencySymbol := store.GetCurrencySettings(s.db, s.config.Load())
+	rendered, err := broadcast.Preview(req.Template, currencySymbol, req.Data)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Failed to render template", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rendered": rendered})
+}
@@ -0,0 +1,66 @@
+package httpadmin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+)
+
+// AdminError is a handler error that already knows the HTTP status it should
+// be reported as, so handlers can return a single error value instead of
+// hand-writing a c.JSON(status, gin.H{"error": ...}) at every call site.
+type AdminError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *AdminError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AdminError) Unwrap() error { return e.Err }
+
+func NewBadRequestError(message string, err error) *AdminError {
+	return &AdminError{Status: http.StatusBadRequest, Message: message, Err: err}
+}
+
+func NewUnauthorizedError(message string) *AdminError {
+	return &AdminError{Status: http.StatusUnauthorized, Message: message}
+}
+
+func NewTooManyRequestsError(message string) *AdminError {
+	return &AdminError{Status: http.StatusTooManyRequests, Message: message}
+}
+
+func NewForbiddenError(message string) *AdminError {
+	return &AdminError{Status: http.StatusForbidden, Message: message}
+}
+
+func NewExternalServiceError(service string, err error) *AdminError {
+	return &AdminError{Status: http.StatusBadGateway, Message: "request to " + service + " failed", Err: err}
+}
+
+func NewInternalError(err error) *AdminError {
+	return &AdminError{Status: http.StatusInternalServerError, Message: "internal error", Err: err}
+}
+
+// JSONError writes err as a JSON error response, using its AdminError status
+// if it has one and logging unexpected (non-AdminError) errors as 500s.
+func JSONError(c *gin.Context, err error) {
+	if adminErr, ok := err.(*AdminError); ok {
+		if adminErr.Status >= http.StatusInternalServerError {
+			logger.Error("Admin API error", "error", adminErr.Error(), "path", c.Request.URL.Path)
+		}
+		c.JSON(adminErr.Status, gin.H{"error": adminErr.Message})
+		return
+	}
+
+	logger.Error("Unhandled admin API error", "error", err.Error(), "path", c.Request.URL.Path)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+}
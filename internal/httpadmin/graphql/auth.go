@@ -0,0 +1,50 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"shop-bot/internal/store"
+)
+
+// contextKey namespaces values this package stashes on the request
+// context, so it doesn't collide with keys other packages might set.
+type contextKey string
+
+const roleContextKey contextKey = "role"
+
+// WithRole attaches the authenticated admin's role to ctx, so resolvers
+// that mutate data (e.g. SendBroadcast) can enforce the same per-role
+// permissions as the REST handlers do via httpadmin.RequirePermission.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleContextKey, role)
+}
+
+// RoleFromContext returns the role WithRole attached to ctx, or "" if none.
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleContextKey).(string)
+	return role
+}
+
+// requirePermission mirrors httpadmin.RequirePermission's check for
+// resolvers that mutate data, since middleware only guards the route as a
+// whole and GraphQL multiplexes every mutation behind one route.
+func requirePermission(db *gorm.DB, roleName, permKey string) error {
+	perm, ok := store.PermissionByKey(permKey)
+	if !ok {
+		panic("graphql: unknown permission key " + permKey)
+	}
+	if roleName == "" {
+		return errors.New("graphql: authentication required")
+	}
+	role, err := store.GetRoleByName(db, roleName)
+	if err != nil {
+		return errors.New("graphql: role not recognized")
+	}
+	if !role.Has(perm) {
+		return errors.New("graphql: insufficient permissions for this action")
+	}
+	return nil
+}
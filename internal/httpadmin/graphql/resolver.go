@@ -0,0 +1,175 @@
+// Package graphql implements a schema-first GraphQL endpoint over the
+// admin panel's data, as an alternative to the REST handlers in
+// httpadmin for clients that want to shape their own queries instead of
+// adding a new ad-hoc filter/pagination handler per screen.
+//
+// The product/order/user/ticket/recharge-card/FAQ side of the schema is
+// declared but not resolved: the orders, products and ticket queries and
+// the replyTicket and updateTicketStatus mutations return
+// ErrNotImplemented. The broadcasts and adminUsers queries and the
+// sendBroadcast mutation read and write through the store.
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"shop-bot/internal/broadcast"
+	"shop-bot/internal/store"
+)
+
+// ErrNotImplemented is returned by the resolvers that have no logic behind
+// them: Orders, Products, Ticket, ReplyTicket and UpdateTicketStatus.
+var ErrNotImplemented = errors.New("graphql: not implemented")
+
+// Resolver is the schema's root resolver. db and broadcastService are
+// funcs rather than plain fields so a resolver built once at startup keeps
+// seeing Server's current db/broadcast service across a config Reload.
+type Resolver struct {
+	db              func() *gorm.DB
+	broadcastService func() *broadcast.Service
+}
+
+// NewResolver builds a root Resolver. db and broadcastService are called
+// fresh on every field resolution, so callers can pass accessors backed by
+// an atomic.Pointer (see httpadmin.Server) without the resolver itself
+// holding a stale reference.
+func NewResolver(db func() *gorm.DB, broadcastService func() *broadcast.Service) *Resolver {
+	return &Resolver{db: db, broadcastService: broadcastService}
+}
+
+// --- Root queries ---
+
+// Broadcasts lists every broadcast campaign, draft or sent.
+func (r *Resolver) Broadcasts(ctx context.Context) ([]*BroadcastResolver, error) {
+	campaigns, err := store.GetBroadcastCampaigns(r.db())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*BroadcastResolver, len(campaigns))
+	for i := range campaigns {
+		out[i] = &BroadcastResolver{c: &campaigns[i]}
+	}
+	return out, nil
+}
+
+// AdminUsers lists every admin dashboard account.
+func (r *Resolver) AdminUsers(ctx context.Context) ([]*AdminUserResolver, error) {
+	admins, err := store.ListAdminUsers(r.db())
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*AdminUserResolver, len(admins))
+	for i := range admins {
+		out[i] = &AdminUserResolver{a: &admins[i]}
+	}
+	return out, nil
+}
+
+// ordersArgs mirrors the filters the REST handleOrderList supports.
+type ordersArgs struct {
+	Status *string
+	UserID *string
+	From   *string
+	To     *string
+	Limit  *int32
+	Cursor *string
+}
+
+// Orders is not implemented: it always returns ErrNotImplemented and
+// applies none of the filters in args.
+func (r *Resolver) Orders(ctx context.Context, args ordersArgs) ([]*OrderResolver, error) {
+	return nil, ErrNotImplemented
+}
+
+// productsArgs mirrors handleProductList's includeDeleted filter.
+type productsArgs struct {
+	IncludeDeleted *bool
+}
+
+// Products is not implemented: it always returns ErrNotImplemented.
+func (r *Resolver) Products(ctx context.Context, args productsArgs) ([]*ProductResolver, error) {
+	return nil, ErrNotImplemented
+}
+
+type ticketArgs struct {
+	ID string
+}
+
+// Ticket is not implemented: it always returns ErrNotImplemented.
+func (r *Resolver) Ticket(ctx context.Context, args ticketArgs) (*TicketResolver, error) {
+	return nil, ErrNotImplemented
+}
+
+// --- Mutations ---
+
+type sendBroadcastArgs struct {
+	ID   string
+	Data *string
+}
+
+// SendBroadcast renders and sends a draft campaign, same as
+// httpadmin.handleBroadcastSend - including that handler's
+// "broadcast.send" permission requirement, since a GraphQL client
+// shouldn't be able to reach a mutation the REST route would 403 on.
+func (r *Resolver) SendBroadcast(ctx context.Context, args sendBroadcastArgs) (*BroadcastResultResolver, error) {
+	if err := requirePermission(r.db(), RoleFromContext(ctx), "broadcast.send"); err != nil {
+		return nil, err
+	}
+
+	id, err := parseUintID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign, err := store.GetBroadcastCampaign(r.db(), id)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := campaign.Variants()
+	if err != nil {
+		return nil, err
+	}
+	split, err := campaign.Split()
+	if err != nil {
+		return nil, err
+	}
+
+	svc := r.broadcastService()
+	if svc == nil {
+		return nil, errors.New("graphql: broadcast service not configured")
+	}
+	sent, failed, err := svc.Send(campaign.ID, broadcast.Template{Variants: variants, Split: split}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.MarkBroadcastCampaignSent(r.db(), campaign.ID); err != nil {
+		return nil, err
+	}
+
+	return &BroadcastResultResolver{sent: int32(sent), failed: int32(failed)}, nil
+}
+
+type replyTicketArgs struct {
+	TicketID string
+	Body     string
+}
+
+// ReplyTicket is not implemented: it always returns ErrNotImplemented and
+// stores or sends no reply.
+func (r *Resolver) ReplyTicket(ctx context.Context, args replyTicketArgs) (*TicketReplyResolver, error) {
+	return nil, ErrNotImplemented
+}
+
+type updateTicketStatusArgs struct {
+	TicketID string
+	Status   string
+}
+
+// UpdateTicketStatus is not implemented: it always returns
+// ErrNotImplemented and leaves the ticket's status unchanged.
+func (r *Resolver) UpdateTicketStatus(ctx context.Context, args updateTicketStatusArgs) (*TicketResolver, error) {
+	return nil, ErrNotImplemented
+}
@@ -0,0 +1,102 @@
+package graphql
+
+import (
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// schemaSource declares every type the REST admin handlers already expose
+// ad-hoc (Product, Order, User, Ticket, Broadcast, RechargeCard, FAQ,
+// AdminUser...), mirroring their GORM relations where those relations
+// exist in this codebase. See resolver.go for which queries/mutations are
+// actually wired versus ErrNotImplemented.
+const schemaSource = `
+	schema {
+		query: Query
+		mutation: Mutation
+	}
+
+	type Query {
+		broadcasts: [Broadcast!]!
+		adminUsers: [AdminUser!]!
+		orders(status: String, userId: String, from: String, to: String, limit: Int, cursor: String): [Order!]!
+		products(includeDeleted: Boolean): [Product!]!
+		ticket(id: String!): Ticket
+	}
+
+	type Mutation {
+		sendBroadcast(id: String!, data: String): BroadcastResult!
+		replyTicket(ticketId: String!, body: String!): TicketReply
+		updateTicketStatus(ticketId: String!, status: String!): Ticket
+	}
+
+	type Product {
+		id: ID!
+		name: String!
+		codes: [ProductCode!]!
+	}
+
+	type ProductCode {
+		id: ID!
+		isSold: Boolean!
+	}
+
+	type Order {
+		id: ID!
+		status: String!
+		user: User!
+		product: Product!
+	}
+
+	type User {
+		id: ID!
+		telegramId: String!
+	}
+
+	type Ticket {
+		id: ID!
+		status: String!
+		replies: [TicketReply!]!
+	}
+
+	type TicketReply {
+		id: ID!
+		body: String!
+	}
+
+	type Broadcast {
+		id: ID!
+		name: String!
+		status: String!
+	}
+
+	type BroadcastResult {
+		sent: Int!
+		failed: Int!
+	}
+
+	type RechargeCard {
+		id: ID!
+		code: String!
+	}
+
+	type FAQ {
+		id: ID!
+		question: String!
+		answer: String!
+	}
+
+	type AdminUser {
+		id: ID!
+		username: String!
+		role: String!
+		isActive: Boolean!
+	}
+`
+
+// NewSchema parses schemaSource against resolver. Call once at server
+// startup and reuse the returned *graphql.Schema for every request -
+// resolver's db/broadcastService accessors are what stay dynamic across a
+// config Reload, not the schema itself.
+func NewSchema(resolver *Resolver) (*graphql.Schema, error) {
+	return graphql.ParseSchema(schemaSource, resolver)
+}
@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"shop-bot/internal/store"
+)
+
+func parseUintID(id string) (uint, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(n), nil
+}
+
+// ProductResolver, ProductCodeResolver, OrderResolver, UserResolver,
+// TicketResolver, TicketReplyResolver, RechargeCardResolver and
+// FAQResolver are declared as the Go return types of the queries and
+// mutations that return ErrNotImplemented (e.g. Orders()) and have no
+// field methods of their own.
+type ProductResolver struct{}
+type ProductCodeResolver struct{}
+type OrderResolver struct{}
+type UserResolver struct{}
+type TicketResolver struct{}
+type TicketReplyResolver struct{}
+type RechargeCardResolver struct{}
+type FAQResolver struct{}
+
+// BroadcastResolver wraps a store.BroadcastCampaign.
+type BroadcastResolver struct {
+	c *store.BroadcastCampaign
+}
+
+func (b *BroadcastResolver) ID() graphql.ID { return graphql.ID(strconv.FormatUint(uint64(b.c.ID), 10)) }
+func (b *BroadcastResolver) Name() string   { return b.c.Name }
+func (b *BroadcastResolver) Status() string { return b.c.Status }
+
+// BroadcastResultResolver is SendBroadcast's return value.
+type BroadcastResultResolver struct {
+	sent   int32
+	failed int32
+}
+
+func (r *BroadcastResultResolver) Sent() int32   { return r.sent }
+func (r *BroadcastResultResolver) Failed() int32 { return r.failed }
+
+// AdminUserResolver wraps a store.AdminUser.
+type AdminUserResolver struct {
+	a *store.AdminUser
+}
+
+func (a *AdminUserResolver) ID() graphql.ID   { return graphql.ID(strconv.FormatUint(uint64(a.a.ID), 10)) }
+func (a *AdminUserResolver) Username() string { return a.a.Username }
+func (a *AdminUserResolver) Role() string     { return a.a.Role }
+func (a *AdminUserResolver) IsActive() bool   { return a.a.IsActive }
@@ -0,0 +1,41 @@
+package httpadmin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	gql "shop-bot/internal/httpadmin/graphql"
+)
+
+// graphQLRequest is the standard GraphQL-over-HTTP request body.
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleGraphQL executes a query/mutation against s.graphqlSchema. It sits
+// behind the same authMiddleware as every other /admin route; mutations
+// that need a higher bar than "authenticated" (e.g. SendBroadcast) enforce
+// it themselves via the role this attaches to the request context with
+// gql.WithRole.
+func (s *Server) handleGraphQL(c *gin.Context) {
+	if s.graphqlSchema == nil {
+		JSONError(c, NewInternalError(errGraphQLSchemaUnavailable))
+		return
+	}
+
+	var req graphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid GraphQL request body", err))
+		return
+	}
+
+	ctx := gql.WithRole(c.Request.Context(), c.GetString("role"))
+	response := s.graphqlSchema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	c.JSON(http.StatusOK, response)
+}
+
+var errGraphQLSchemaUnavailable = fmt.Errorf("graphql schema failed to initialize")
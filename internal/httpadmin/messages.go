@@ -0,0 +1,86 @@
+package httpadmin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"shop-bot/internal/bot/messages"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// handleMessagesList returns every known message key for every language,
+// merging the embedded catalog with the admin-edited overrides so the UI
+// shows what is actually being served.
+func (s *Server) handleMessagesList(c *gin.Context) {
+	mgr := messages.GetManager()
+
+	result := make(map[string]map[string]string)
+	for _, lang := range mgr.GetAvailableLanguages() {
+		result[lang.Code] = make(map[string]string)
+		for _, key := range mgr.Keys(lang.Code) {
+			result[lang.Code][key] = mgr.Get(lang.Code, key)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": result})
+}
+
+// handleMessageUpdate upserts a single (language, key) override and reloads the catalog.
+func (s *Server) handleMessageUpdate(c *gin.Context) {
+	var req struct {
+		Language string `json:"language" binding:"required"`
+		Key      string `json:"key" binding:"required"`
+		Value    string `json:"value"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	if err := store.UpsertBotMessage(s.db, req.Language, req.Key, req.Value); err != nil {
+		logger.Error("Failed to save message override", "error", err, "language", req.Language, "key", req.Key)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	if err := messages.GetManager().Reload(s.db); err != nil {
+		logger.Error("Failed to reload message catalog", "error", err)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// handleMessagePreview renders a message template against caller-supplied sample
+// data using the same html/template pipeline Format uses, without saving anything.
+func (s *Server) handleMessagePreview(c *gin.Context) {
+	var req struct {
+		Language string                 `json:"language" binding:"required"`
+		Key      string                 `json:"key" binding:"required"`
+		Data     map[string]interface{} `json:"data"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	rendered := messages.GetManager().Format(req.Language, req.Key, req.Data)
+	c.JSON(http.StatusOK, gin.H{"rendered": rendered})
+}
+
+// handleMessagesReload forces the catalog to re-read the embedded files, disk
+// overrides, and bot_messages table, without waiting for the next edit.
+func (s *Server) handleMessagesReload(c *gin.Context) {
+	if err := messages.GetManager().Reload(s.db); err != nil {
+		logger.Error("Failed to reload message catalog", "error", err)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,25 @@
+package httpadmin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"shop-bot/internal/store/migrations"
+)
+
+// handleMigrationsStatus reports how far the database schema has been
+// brought up via internal/store/migrations, so operators can confirm a
+// deploy applied everything it expected to before relying on it.
+func (s *Server) handleMigrationsStatus(c *gin.Context) {
+	current, target, pending, err := migrations.Status(s.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"current_version": current,
+		"target_version":  target,
+		"pending":         pending,
+	})
+}
@@ -0,0 +1,173 @@
+package httpadmin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/bot/messages"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// notifyOrderRefunded tells order's buyer it was refunded, editing the
+// message from order's last status notification (see bot.sendOrEditOrderMessage
+// for the bot-side equivalent of this same stored-message-ID pattern) rather
+// than sending a new one, so admin-side refunds keep the same evolving
+// notification the buyer already sees for bot-driven transitions.
+func (s *Server) notifyOrderRefunded(order *store.Order) {
+	if s.bot == nil {
+		return
+	}
+	var user store.User
+	if err := s.db.First(&user, order.UserID).Error; err != nil {
+		logger.Warn("Failed to load user for refund notification", "error", err, "order_id", order.ID)
+		return
+	}
+
+	lang := messages.GetUserLanguage(user.Language, "")
+	text := messages.GetManager().Format(lang, "order_refunded", map[string]interface{}{"OrderID": order.ID})
+
+	if messageID, err := store.GetTelegramMessage(s.db, order.ID, user.TgUserID); err == nil {
+		if _, err := s.bot.Send(tgbotapi.NewEditMessageText(user.TgUserID, messageID, text)); err == nil {
+			return
+		}
+	}
+	resp, err := s.bot.Send(tgbotapi.NewMessage(user.TgUserID, text))
+	if err != nil {
+		logger.Warn("Failed to send refund notification", "error", err, "order_id", order.ID)
+		return
+	}
+	if err := store.UpsertTelegramMessage(s.db, order.ID, user.TgUserID, resp.MessageID); err != nil {
+		logger.Warn("Failed to record refund notification message ID", "error", err, "order_id", order.ID)
+	}
+}
+
+// refundViaGateway asks every configured payment provider to refund order
+// in turn and returns the first one that succeeds. The order doesn't record
+// which gateway settled it, so this is the best available way to find the
+// right one without adding a new column to store.Order.
+func (s *Server) refundViaGateway(ctx context.Context, order *store.Order, amountCents int) (string, error) {
+	if len(s.paymentProviders) == 0 {
+		return "", nil
+	}
+	var lastErr error
+	for _, provider := range s.paymentProviders {
+		ref, err := provider.Refund(ctx, order.EpayOutTradeNo, amountCents)
+		if err == nil {
+			return ref, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// handleRefundOrder refunds an order's payment via its gateway and releases
+// its codes back to the pool, all inside store.RefundOrder's transaction.
+func (s *Server) handleRefundOrder(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	var order store.Order
+	if err := s.db.First(&order, uint(orderID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	gatewayRef, err := s.refundViaGateway(c.Request.Context(), &order, order.PaymentAmount)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Gateway refund failed: " + err.Error()})
+		return
+	}
+
+	operatorID, _ := currentAdminID(c)
+	if err := store.RefundOrder(s.db, uint(orderID), operatorID, req.Reason, gatewayRef); err != nil {
+		logger.Error("Failed to refund order", "order_id", orderID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.notifyOrderRefunded(&order)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order refunded"})
+}
+
+// handleFreezeOrder marks an order frozen, e.g. while fraud is investigated.
+func (s *Server) handleFreezeOrder(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	operatorID, _ := currentAdminID(c)
+	if err := store.FreezeOrder(s.db, uint(orderID), operatorID, req.Reason); err != nil {
+		logger.Error("Failed to freeze order", "order_id", orderID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order frozen"})
+}
+
+// handleUnfreezeOrder restores a frozen order to restore_state.
+func (s *Server) handleUnfreezeOrder(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+	var req struct {
+		RestoreState string `json:"restore_state"`
+		Reason       string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.RestoreState == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "restore_state is required"})
+		return
+	}
+
+	operatorID, _ := currentAdminID(c)
+	if err := store.UnfreezeOrder(s.db, uint(orderID), operatorID, req.RestoreState, req.Reason); err != nil {
+		logger.Error("Failed to unfreeze order", "order_id", orderID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order unfrozen"})
+}
+
+// handleReissueCode voids an order's current code and claims a fresh one.
+func (s *Server) handleReissueCode(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	operatorID, _ := currentAdminID(c)
+	newCode, err := store.ReissueCode(s.db, uint(orderID), operatorID, req.Reason)
+	if err != nil {
+		logger.Error("Failed to reissue code", "order_id", orderID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Code reissued", "code": newCode})
+}
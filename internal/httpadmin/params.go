@@ -0,0 +1,17 @@
+package httpadmin
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseUintParam parses the named URL param as a uint, for handlers keyed
+// by a numeric ID (e.g. "/broadcast/:id").
+func parseUintParam(c *gin.Context, name string) (uint, error) {
+	v, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(v), nil
+}
@@ -0,0 +1,117 @@
+package httpadmin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	payment "shop-bot/internal/payment"
+	"shop-bot/internal/store"
+)
+
+// handlePaymentNotify is the generic counterpart to handleEpayNotify for
+// every provider added after epay (alipay, wechat, ...). The :gateway
+// segment is matched against each provider's Name() so the right one
+// verifies the notification; the order itself is still looked up by its
+// EpayOutTradeNo, since that field is the shared out_trade_no regardless of
+// which gateway settled it. Settlement itself goes through
+// store.SettleOrderTx, so two duplicate notifications for the same order
+// (gateways routinely retry until they get a 200) can't each claim a code
+// or credit a balance.
+func (s *Server) handlePaymentNotify(c *gin.Context) {
+	gateway := c.Param("gateway")
+
+	var provider payment.Provider
+	for _, p := range s.paymentProviders {
+		if p.Name() == gateway {
+			provider = p
+			break
+		}
+	}
+	if provider == nil {
+		c.String(http.StatusNotFound, "unknown gateway")
+		return
+	}
+
+	// Buffer the raw body before anything else reads it, so providers that
+	// need to verify a signature over the exact bytes sent (WeChat Pay v3's
+	// platform signature, computed over timestamp+nonce+body) aren't stuck
+	// with a body already re-serialized by json.Marshal.
+	bodyBytes, _ := c.GetRawData()
+	c.Request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+
+	raw := make(map[string]string)
+	if err := c.Request.ParseForm(); err == nil {
+		for k := range c.Request.Form {
+			raw[k] = c.Request.PostForm.Get(k)
+		}
+	}
+	var body map[string]interface{}
+	if err := c.ShouldBindJSON(&body); err == nil {
+		for k, v := range body {
+			if s, ok := v.(string); ok {
+				raw[k] = s
+			}
+		}
+	}
+	raw["_body"] = string(bodyBytes)
+	raw["_header_Wechatpay-Signature"] = c.GetHeader("Wechatpay-Signature")
+	raw["_header_Wechatpay-Serial"] = c.GetHeader("Wechatpay-Serial")
+	raw["_header_Wechatpay-Timestamp"] = c.GetHeader("Wechatpay-Timestamp")
+	raw["_header_Wechatpay-Nonce"] = c.GetHeader("Wechatpay-Nonce")
+
+	result, paid := provider.VerifyNotification(raw)
+	if !paid || result.OutTradeNo == "" {
+		c.String(http.StatusBadRequest, "fail")
+		return
+	}
+
+	order, code, err := store.SettleOrderTx(c.Request.Context(), s.db, result.OutTradeNo, result.AmountCents, result.GatewayTxNo)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAlreadySettled):
+			// A retry of a notification we already settled - tell the
+			// gateway it succeeded so it stops retrying.
+			c.String(http.StatusOK, "success")
+			return
+		case errors.Is(err, store.ErrAmountMismatch):
+			logger.Error("Payment notify amount mismatch", "gateway", gateway, "out_trade_no", result.OutTradeNo, "amount_cents", result.AmountCents)
+			c.String(http.StatusBadRequest, "fail")
+			return
+		default:
+			logger.Error("Failed to settle order from payment notify", "gateway", gateway, "out_trade_no", result.OutTradeNo, "error", err)
+			c.String(http.StatusInternalServerError, "fail")
+			return
+		}
+	}
+
+	logger.Info("Order settled via payment notify", "gateway", gateway, "order_id", order.ID, "out_trade_no", result.OutTradeNo)
+
+	if order.ProductID != nil {
+		s.deliverCode(order, code)
+	}
+
+	c.String(http.StatusOK, "success")
+}
+
+// deliverCode sends a successfully claimed code to the buyer. Errors are
+// logged, not returned, since the gateway has already been told the
+// notification succeeded and retrying it won't change the outcome.
+func (s *Server) deliverCode(order *store.Order, code string) {
+	var user store.User
+	if err := s.db.First(&user, order.UserID).Error; err != nil {
+		logger.Warn("Delivery: user not found for paid order", "order_id", order.ID, "error", err)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(user.TgUserID, fmt.Sprintf("Your order #%d has been paid. Code: %s", order.ID, code))
+	if _, err := s.bot.Send(msg); err != nil {
+		logger.Error("Failed to deliver code to buyer", "order_id", order.ID, "error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package httpadmin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// RequirePermission returns middleware that rejects the request with 403
+// unless the authenticated admin's role grants permKey (e.g. "orders.read",
+// or a "tickets.*" wildcard covering a whole category - see
+// store.PermissionByKey). It must be mounted after authMiddleware, which is
+// what populates the "role" context value this reads. permKey must be one
+// EnsureDefaultRoles/store.PermissionByKey recognizes; an unknown key is a
+// programming error and panics at route-setup time rather than failing
+// open at request time.
+func (s *Server) RequirePermission(permKey string) gin.HandlerFunc {
+	perm, ok := store.PermissionByKey(permKey)
+	if !ok {
+		panic(fmt.Sprintf("httpadmin: unknown permission key %q", permKey))
+	}
+
+	return func(c *gin.Context) {
+		roleVal, exists := c.Get("role")
+		roleName, _ := roleVal.(string)
+		if !exists || roleName == "" {
+			JSONError(c, NewUnauthorizedError("Authentication required"))
+			c.Abort()
+			return
+		}
+
+		role, err := store.GetRoleByName(s.db, roleName)
+		if err != nil {
+			JSONError(c, NewForbiddenError(fmt.Sprintf("Role %q is not recognized", roleName)))
+			c.Abort()
+			return
+		}
+
+		if !role.Has(perm) {
+			JSONError(c, NewForbiddenError("Insufficient permissions for this action"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// handleAdminUserList returns every admin account (password hashes
+// excluded), for the admin-management screen.
+func (s *Server) handleAdminUserList(c *gin.Context) {
+	admins, err := store.ListAdminUsers(s.db)
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	out := make([]gin.H, len(admins))
+	for i, a := range admins {
+		out[i] = gin.H{
+			"id":                    a.ID,
+			"username":              a.Username,
+			"role":                  a.Role,
+			"telegram_id":           a.TelegramID,
+			"receive_notifications": a.ReceiveNotifications,
+			"is_active":             a.IsActive,
+			"last_login_at":         a.LastLoginAt,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"admins": out})
+}
+
+// handleAdminUserCreate creates a new admin dashboard account.
+func (s *Server) handleAdminUserCreate(c *gin.Context) {
+	var req struct {
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		Role       string `json:"role"`
+		TelegramID *int64 `json:"telegram_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = "admin"
+	}
+	if _, err := store.GetRoleByName(s.db, req.Role); err != nil {
+		JSONError(c, NewBadRequestError(fmt.Sprintf("Unknown role %q", req.Role), err))
+		return
+	}
+
+	passwordService := s.passwordService.Load()
+	if passwordService == nil {
+		JSONError(c, NewInternalError(fmt.Errorf("password policy service not configured")))
+		return
+	}
+	hash, err := passwordService.HashPassword(req.Password)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Password does not meet policy requirements", err))
+		return
+	}
+
+	admin, err := store.CreateAdminUser(s.db, req.Username, hash, req.Role, req.TelegramID)
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	logger.Audit("Admin account created", "admin_id", admin.ID, "username", admin.Username, "role", admin.Role)
+	c.JSON(http.StatusOK, gin.H{"id": admin.ID, "username": admin.Username, "role": admin.Role})
+}
+
+// handleAdminUserUpdate changes an existing admin account's role and/or
+// active flag. Password rotation isn't exposed here - that's the admin's
+// own login flow, not another admin editing their account.
+func (s *Server) handleAdminUserUpdate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid admin id", err))
+		return
+	}
+
+	var req struct {
+		Role     *string `json:"role"`
+		IsActive *bool   `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	if req.Role != nil {
+		if _, err := store.GetRoleByName(s.db, *req.Role); err != nil {
+			JSONError(c, NewBadRequestError(fmt.Sprintf("Unknown role %q", *req.Role), err))
+			return
+		}
+		if err := store.UpdateAdminUserRole(s.db, uint(id), *req.Role); err != nil {
+			JSONError(c, NewInternalError(err))
+			return
+		}
+	}
+
+	if req.IsActive != nil {
+		if err := store.SetAdminUserActive(s.db, uint(id), *req.IsActive); err != nil {
+			JSONError(c, NewInternalError(err))
+			return
+		}
+	}
+
+	logger.Audit("Admin account updated", "admin_id", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Admin account updated"})
+}
@@ -0,0 +1,137 @@
+package httpadmin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"shop-bot/internal/auth"
+	logger "shop-bot/internal/log"
+	payment "shop-bot/internal/payment/epay"
+	"shop-bot/internal/security"
+)
+
+// Reload re-reads configuration through s.configManager and atomically
+// swaps every subservice built from it, without touching sessionManager's
+// state - only jwtService, rateLimiter, passwordService, dataSecurity,
+// securityLogger and epay are rebuilt, so logged-in admins stay logged in
+// across a reload. epay is only replaced when its PID/Key/Gateway actually
+// changed, so an unrelated settings edit doesn't tear down a healthy
+// payment client mid-request.
+func (s *Server) Reload(ctx context.Context) error {
+	if s.configManager == nil {
+		return fmt.Errorf("reload: no config manager configured")
+	}
+
+	if err := s.configManager.LoadFromDatabase(); err != nil {
+		return fmt.Errorf("reload: load config: %w", err)
+	}
+	newCfg := s.configManager.GetConfig()
+	if newCfg == nil {
+		return fmt.Errorf("reload: config manager returned nil config")
+	}
+	if err := newCfg.ResolveBotToken(); err != nil {
+		return fmt.Errorf("reload: resolve bot token: %w", err)
+	}
+	oldCfg := s.config.Load()
+	s.config.Store(newCfg)
+
+	jwtConfig := &auth.JWTConfig{
+		SecretKey:        newCfg.JWTSecret,
+		TokenExpiry:      time.Duration(newCfg.JWTExpiry) * time.Hour,
+		RefreshExpiry:    time.Duration(newCfg.JWTRefreshExpiry) * 24 * time.Hour,
+		Issuer:           "shop-bot-admin",
+		LegacyToken:      s.adminToken,
+		EnableLegacyAuth: newCfg.EnableLegacyAuth,
+	}
+	s.jwtService.Store(auth.NewJWTService(jwtConfig))
+
+	rateLimiterConfig := &auth.RateLimiterConfig{
+		MaxAttempts:     newCfg.LoginMaxAttempts,
+		LockoutDuration: time.Duration(newCfg.LoginLockoutMinutes) * time.Minute,
+		WindowDuration:  5 * time.Minute,
+		CleanupInterval: 10 * time.Minute,
+	}
+	s.rateLimiter.Store(auth.NewRateLimiter(rateLimiterConfig))
+
+	if newCfg.EnablePasswordPolicy {
+		s.passwordService.Store(auth.NewPasswordService(&auth.PasswordConfig{
+			MinLength:      newCfg.PasswordMinLength,
+			RequireUpper:   newCfg.PasswordRequireUpper,
+			RequireLower:   newCfg.PasswordRequireLower,
+			RequireDigit:   newCfg.PasswordRequireDigit,
+			RequireSpecial: newCfg.PasswordRequireSpecial,
+			BcryptCost:     12,
+		}))
+	} else {
+		s.passwordService.Store(nil)
+	}
+
+	if ds, err := security.NewDataSecurity(newCfg.DataEncryptionKey); err == nil {
+		s.dataSecurity.Store(ds)
+	} else {
+		logger.Error("Reload: failed to rebuild data security", "error", err)
+	}
+
+	if newCfg.EnableSecurityLogging {
+		s.securityLogger.Store(security.NewSecurityLogger(true, newCfg.MaskSensitiveData))
+	} else {
+		s.securityLogger.Store(nil)
+	}
+
+	// sessionManager is deliberately left alone: rebuilding it would drop
+	// every currently-authenticated admin's session.
+
+	epayChanged := oldCfg == nil ||
+		oldCfg.EpayPID != newCfg.EpayPID ||
+		oldCfg.EpayKey != newCfg.EpayKey ||
+		oldCfg.EpayGateway != newCfg.EpayGateway
+	if epayChanged {
+		if newCfg.EpayPID != "" && newCfg.EpayKey != "" && newCfg.EpayGateway != "" {
+			s.epay.Store(payment.NewClient(newCfg.EpayPID, newCfg.EpayKey, newCfg.EpayGateway))
+		} else {
+			s.epay.Store(nil)
+		}
+	}
+
+	logger.Audit("Admin server configuration reloaded")
+	return nil
+}
+
+// handleConfigReload is the permission-gated HTTP counterpart to the SIGHUP
+// handler installed by WatchReloadSignal, for operators who'd rather hit an
+// endpoint than send a signal.
+func (s *Server) handleConfigReload(c *gin.Context) {
+	if err := s.Reload(c.Request.Context()); err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Configuration reloaded"})
+}
+
+// WatchReloadSignal installs a SIGHUP handler that calls Reload, mirroring
+// the usual "kill -HUP" convention for reloading a running server's config
+// without restarting it. It runs until ctx is cancelled.
+func (s *Server) WatchReloadSignal(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				if err := s.Reload(ctx); err != nil {
+					logger.Error("Config reload via SIGHUP failed", "error", err)
+				}
+			}
+		}
+	}()
+}
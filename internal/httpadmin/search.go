@@ -0,0 +1,46 @@
+package httpadmin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/search"
+	"shop-bot/internal/store"
+)
+
+// handleCatalogSearch fuzzy-matches the query string against active product
+// names, reusing the same scorer the bot's /find command and inline queries
+// use, so admins can locate a product without knowing its exact name.
+func (s *Server) handleCatalogSearch(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		JSONError(c, NewBadRequestError("q is required", nil))
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	products, err := store.GetActiveProducts(s.db)
+	if err != nil {
+		logger.Error("Failed to load products for catalog search", "error", err)
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	items := make([]search.Item, 0, len(products))
+	for _, p := range products {
+		items = append(items, search.Item{ID: p.ID, Kind: "product", Text: p.Name})
+	}
+
+	idx := search.NewIndex()
+	idx.Build(items)
+	results := idx.Search(query, limit)
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
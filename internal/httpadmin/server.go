@@ -1,49 +1,79 @@
 package httpadmin
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	graphqlgo "github.com/graph-gophers/graphql-go"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gorm.io/gorm"
 
 	"shop-bot/internal/auth"
+	"shop-bot/internal/auth/tan"
 	"shop-bot/internal/broadcast"
 	"shop-bot/internal/config"
+	gql "shop-bot/internal/httpadmin/graphql"
 	logger "shop-bot/internal/log"
 	"shop-bot/internal/middleware"
 	"shop-bot/internal/notification"
+	"shop-bot/internal/notifier"
+	paymentgw "shop-bot/internal/payment"
 	payment "shop-bot/internal/payment/epay"
+	"shop-bot/internal/secrets"
 	"shop-bot/internal/security"
 	"shop-bot/internal/store"
 	"shop-bot/internal/ticket"
+	"shop-bot/internal/worker"
 )
 
+// Server holds the subservices built from config.Config. Everything that
+// Reload can swap out without a restart (see reload.go) is an
+// atomic.Pointer rather than a plain field, so an in-flight request that
+// already loaded its pointer keeps using that instance even if Reload
+// replaces it mid-request; sessionManager is the one exception Reload
+// deliberately never re-Stores, so live sessions survive a config change.
 type Server struct {
 	adminToken   string
 	db           *gorm.DB
 	bot          *tgbotapi.BotAPI
-	epay         *payment.Client
-	config       *config.Config
+	// paymentProviders holds every non-epay payment.Provider, keyed by
+	// Name() in handlePaymentNotify's dispatch.
+	paymentProviders []paymentgw.Provider
 	configManager *config.Manager
-	broadcast    *broadcast.Service
-	notification *notification.Service
-	ticketService *ticket.Service
-	jwtService   *auth.JWTService
+	// graphqlSchema serves POST /admin/graphql. It's built once at startup
+	// (parsing the schema on every request would be wasteful) - its
+	// resolver reads db/broadcast through closures over s, so it still
+	// sees whatever Reload swaps in without being rebuilt itself.
+	graphqlSchema *graphqlgo.Schema
+	// telegramQueue is the durable, rate-limited send queue handleTestNotifier's
+	// Telegram branch enqueues into instead of calling bot.Send directly - see
+	// internal/worker.TelegramSendQueue and StartTelegramQueue.
+	telegramQueue *worker.TelegramSendQueue
+
+	config          atomic.Pointer[config.Config]
+	epay            atomic.Pointer[payment.Client]
+	broadcast       atomic.Pointer[broadcast.Service]
+	notification    atomic.Pointer[notification.Service]
+	ticketService   atomic.Pointer[ticket.Service]
+	jwtService      atomic.Pointer[auth.JWTService]
 
 	// Security services
-	passwordService  *auth.PasswordService
-	rateLimiter      *auth.RateLimiter
-	sessionManager   *auth.SessionManager
-	dataSecurity     *security.DataSecurity
-	securityLogger   *security.SecurityLogger
+	passwordService  atomic.Pointer[auth.PasswordService]
+	rateLimiter      atomic.Pointer[auth.RateLimiter]
+	sessionManager   atomic.Pointer[auth.SessionManager]
+	dataSecurity     atomic.Pointer[security.DataSecurity]
+	securityLogger   atomic.Pointer[security.SecurityLogger]
 }
 
 func NewServer(adminToken string, db *gorm.DB) *Server {
@@ -57,6 +87,26 @@ func NewServer(adminToken string, db *gorm.DB) *Server {
 		}
 	}
 	
+	if err := logger.Init(logger.Config{
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxAgeDays: cfg.LogMaxAgeDays,
+		MaxBackups: cfg.LogMaxBackups,
+		Level:      cfg.LogLevel,
+	}); err != nil {
+		logger.Error("Failed to init logger, continuing with stdout fallback", "error", err)
+	}
+
+	// Wire up encryption-at-rest for sensitive system settings, same as
+	// bot.go's New(). No MASTER_KEY means settings stay plaintext.
+	if cfg.MasterKeySource != "" {
+		if secretsManager, err := secrets.NewManager(cfg.MasterKeySource); err != nil {
+			logger.Error("Failed to initialize secrets manager, settings will be stored in plaintext", "error", err)
+		} else {
+			store.ConfigureSecrets(secretsManager)
+		}
+	}
+
 	// Initialize bot API for sending messages
 	var bot *tgbotapi.BotAPI
 	if cfg.BotToken != "" {
@@ -71,6 +121,46 @@ func NewServer(adminToken string, db *gorm.DB) *Server {
 	if cfg.EpayPID != "" && cfg.EpayKey != "" && cfg.EpayGateway != "" {
 		epayClient = payment.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway)
 	}
+
+	// Every non-epay provider, keyed by Name() for handlePaymentNotify's
+	// dispatch. Epay keeps its own dedicated route/client above since it
+	// predates the Provider abstraction.
+	var paymentProviders []paymentgw.Provider
+	if cfg.AlipayAppID != "" && cfg.AlipayPrivateKey != "" && cfg.AlipayPublicKey != "" {
+		if alipayProvider, err := paymentgw.NewAlipayProvider(paymentgw.AlipayConfig{
+			AppID:           cfg.AlipayAppID,
+			PrivateKey:      cfg.AlipayPrivateKey,
+			AlipayPublicKey: cfg.AlipayPublicKey,
+			IsProduction:    cfg.AlipayIsProduction,
+		}); err != nil {
+			logger.Error("Failed to initialize Alipay provider", "error", err)
+		} else {
+			paymentProviders = append(paymentProviders, alipayProvider)
+		}
+	}
+	if cfg.WechatMchID != "" && cfg.WechatPrivateKey != "" && cfg.WechatAPIv3Key != "" {
+		if wechatProvider, err := paymentgw.NewWechatProvider(paymentgw.WechatConfig{
+			AppID:           cfg.WechatAppID,
+			MchID:           cfg.WechatMchID,
+			SerialNo:        cfg.WechatSerialNo,
+			PrivateKeyPEM:   cfg.WechatPrivateKey,
+			APIv3Key:        cfg.WechatAPIv3Key,
+			PlatformCertPEM: cfg.WechatPlatformCert,
+		}); err != nil {
+			logger.Error("Failed to initialize WeChat Pay provider", "error", err)
+		} else {
+			paymentProviders = append(paymentProviders, wechatProvider)
+		}
+	}
+	if cfg.OKXAPIKey != "" && cfg.OKXAPISecret != "" && cfg.OKXPassphrase != "" {
+		paymentProviders = append(paymentProviders, paymentgw.NewCryptoProvider(paymentgw.CryptoConfig{
+			APIKey:     cfg.OKXAPIKey,
+			APISecret:  cfg.OKXAPISecret,
+			Passphrase: cfg.OKXPassphrase,
+			BaseURL:    cfg.OKXBaseURL,
+			Currency:   cfg.OKXCurrency,
+		}))
+	}
 	
 	// Initialize broadcast service
 	var broadcastService *broadcast.Service
@@ -156,23 +246,35 @@ func NewServer(adminToken string, db *gorm.DB) *Server {
 			securityLogger = security.NewSecurityLogger(true, cfg.MaskSensitiveData)
 		}
 	}
-	
-	return &Server{
-		adminToken:      adminToken,
-		db:              db,
-		bot:             bot,
-		epay:            epayClient,
-		config:          cfg,
-		broadcast:       broadcastService,
-		notification:    notificationService,
-		ticketService:   ticketService,
-		jwtService:      jwtService,
-		passwordService: passwordService,
-		rateLimiter:     rateLimiter,
-		sessionManager:  sessionManager,
-		dataSecurity:    dataSecurity,
-		securityLogger:  securityLogger,
+
+	if db != nil {
+		if err := store.EnsureDefaultRoles(db); err != nil {
+			logger.Error("Failed to ensure default admin roles", "error", err)
+		}
+	}
+
+	s := &Server{
+		adminToken:       adminToken,
+		db:               db,
+		bot:              bot,
+		paymentProviders: paymentProviders,
+	}
+	if bot != nil && db != nil {
+		s.telegramQueue = worker.NewTelegramSendQueue(db, bot)
 	}
+	s.config.Store(cfg)
+	s.epay.Store(epayClient)
+	s.broadcast.Store(broadcastService)
+	s.notification.Store(notificationService)
+	s.ticketService.Store(ticketService)
+	s.jwtService.Store(jwtService)
+	s.passwordService.Store(passwordService)
+	s.rateLimiter.Store(rateLimiter)
+	s.sessionManager.Store(sessionManager)
+	s.dataSecurity.Store(dataSecurity)
+	s.securityLogger.Store(securityLogger)
+	s.graphqlSchema = s.buildGraphQLSchema()
+	return s
 }
 
 // NewServerWithApp creates a new server with application reference
@@ -197,11 +299,11 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 	// Try to get Config field
 	if cfgField := appValue.FieldByName("Config"); cfgField.IsValid() {
 		if cfg, ok := cfgField.Interface().(*config.Config); ok {
-			server.config = cfg
+			server.config.Store(cfg)
 
 			// Initialize payment client with proper validation
 			if cfg.EpayPID != "" && cfg.EpayKey != "" && cfg.EpayGateway != "" {
-				server.epay = payment.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway)
+				server.epay.Store(payment.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway))
 				logger.Info("Payment client initialized on server startup",
 					"epay_pid", cfg.EpayPID,
 					"epay_gateway", cfg.EpayGateway)
@@ -220,7 +322,7 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 			server.configManager = cfgManager
 		}
 	}
-	
+
 	// Try to get Bot field and extract API
 	if botField := appValue.FieldByName("Bot"); botField.IsValid() && !botField.IsNil() {
 		if method := botField.MethodByName("GetAPI"); method.IsValid() {
@@ -231,39 +333,38 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 			}
 		}
 	}
-	
+
 	// Try to get Broadcast field
 	if broadcastField := appValue.FieldByName("Broadcast"); broadcastField.IsValid() {
 		if bc, ok := broadcastField.Interface().(*broadcast.Service); ok {
-			server.broadcast = bc
+			server.broadcast.Store(bc)
 		}
 	}
-	
+
+	cfg := server.config.Load()
+
 	// Initialize notification service if we have bot and config
-	if server.bot != nil && server.config != nil {
-		server.notification = notification.NewService(server.bot, server.config, server.db)
+	if server.bot != nil && cfg != nil {
+		server.notification.Store(notification.NewService(server.bot, cfg, server.db))
 	}
-	
+
 	// Initialize ticket service
 	if server.bot != nil && server.db != nil {
-		server.ticketService = ticket.NewService(server.db, server.bot)
+		server.ticketService.Store(ticket.NewService(server.db, server.bot))
 	}
-	
+
 	// Initialize JWT service
-	if server.config != nil {
+	if cfg != nil {
 		jwtConfig := &auth.JWTConfig{
-			SecretKey:        server.config.JWTSecret,
-			TokenExpiry:      time.Duration(server.config.JWTExpiry) * time.Hour,
-			RefreshExpiry:    time.Duration(server.config.JWTRefreshExpiry) * 24 * time.Hour,
+			SecretKey:        cfg.JWTSecret,
+			TokenExpiry:      time.Duration(cfg.JWTExpiry) * time.Hour,
+			RefreshExpiry:    time.Duration(cfg.JWTRefreshExpiry) * 24 * time.Hour,
 			Issuer:          "shop-bot-admin",
 			LegacyToken:     server.adminToken,
-			EnableLegacyAuth: server.config.EnableLegacyAuth,
+			EnableLegacyAuth: cfg.EnableLegacyAuth,
 		}
-		server.jwtService = auth.NewJWTService(jwtConfig)
-		
-		// Initialize security services
-		cfg := server.config
-		
+		server.jwtService.Store(auth.NewJWTService(jwtConfig))
+
 		// Password service
 		if cfg.EnablePasswordPolicy {
 			passwordConfig := &auth.PasswordConfig{
@@ -274,9 +375,9 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 				RequireSpecial: cfg.PasswordRequireSpecial,
 				BcryptCost:     12,
 			}
-			server.passwordService = auth.NewPasswordService(passwordConfig)
+			server.passwordService.Store(auth.NewPasswordService(passwordConfig))
 		}
-		
+
 		// Rate limiter for login attempts
 		rateLimiterConfig := &auth.RateLimiterConfig{
 			MaxAttempts:     cfg.LoginMaxAttempts,
@@ -284,8 +385,8 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 			WindowDuration:  5 * time.Minute,
 			CleanupInterval: 10 * time.Minute,
 		}
-		server.rateLimiter = auth.NewRateLimiter(rateLimiterConfig)
-		
+		server.rateLimiter.Store(auth.NewRateLimiter(rateLimiterConfig))
+
 		// Session manager
 		sessionConfig := &auth.SessionConfig{
 			MaxConcurrent:        cfg.SessionMaxConcurrent,
@@ -294,24 +395,52 @@ func NewServerWithApp(adminToken string, app interface{}) *Server {
 			EnableIPCheck:        cfg.EnableIPValidation,
 			EnableUserAgentCheck: cfg.EnableUserAgentCheck,
 		}
-		server.sessionManager = auth.NewSessionManager(sessionConfig)
-		
+		server.sessionManager.Store(auth.NewSessionManager(sessionConfig))
+
 		// Data security
 		if ds, err := security.NewDataSecurity(cfg.DataEncryptionKey); err == nil {
-			server.dataSecurity = ds
+			server.dataSecurity.Store(ds)
 		} else {
 			logger.Error("Failed to initialize data security", "error", err)
 		}
-		
+
 		// Security logger
 		if cfg.EnableSecurityLogging {
-			server.securityLogger = security.NewSecurityLogger(true, cfg.MaskSensitiveData)
+			server.securityLogger.Store(security.NewSecurityLogger(true, cfg.MaskSensitiveData))
 		}
 	}
-	
+
+	if server.db != nil {
+		if err := store.EnsureDefaultRoles(server.db); err != nil {
+			logger.Error("Failed to ensure default admin roles", "error", err)
+		}
+	}
+
+	if server.bot != nil && server.db != nil {
+		server.telegramQueue = worker.NewTelegramSendQueue(server.db, server.bot)
+	}
+
+	server.graphqlSchema = server.buildGraphQLSchema()
+
 	return server
 }
 
+// buildGraphQLSchema parses the admin GraphQL schema against a resolver
+// whose db/broadcast accessors read through s, so they keep seeing
+// whatever Reload swaps in later.
+func (s *Server) buildGraphQLSchema() *graphqlgo.Schema {
+	resolver := gql.NewResolver(
+		func() *gorm.DB { return s.db },
+		func() *broadcast.Service { return s.broadcast.Load() },
+	)
+	schema, err := gql.NewSchema(resolver)
+	if err != nil {
+		logger.Error("Failed to parse admin GraphQL schema", "error", err)
+		return nil
+	}
+	return schema
+}
+
 // toInt64 converts interface{} to int64
 func toInt64(v interface{}) (int64, error) {
 	switch val := v.(type) {
@@ -342,7 +471,7 @@ func (s *Server) Router() *gin.Engine {
 	r := gin.Default()
 	
 	// Get currency settings
-	_, currencySymbol := store.GetCurrencySettings(s.db, s.config)
+	_, currencySymbol := store.GetCurrencySettings(s.db, s.config.Load())
 	
 	// Add template functions BEFORE loading templates
 	r.SetFuncMap(template.FuncMap{
@@ -399,6 +528,11 @@ func (s *Server) Router() *gin.Engine {
 	r.LoadHTMLGlob("templates/*")
 
 	// Add middleware
+	var trustedProxies []*net.IPNet
+	if cfg := s.config.Load(); cfg != nil {
+		trustedProxies = cfg.TrustedProxyList()
+	}
+	r.Use(middleware.NewRequestContextMiddleware(trustedProxies))
 	r.Use(s.requestLogger())
 	r.Use(RecoveryMiddleware())  // Add panic recovery before error handler
 	r.Use(ErrorHandlerMiddleware())
@@ -406,39 +540,43 @@ func (s *Server) Router() *gin.Engine {
 	// Set up all routes
 	s.SetupRoutes(r)
 
+	// Let "kill -HUP <pid>" pick up settings changes made through
+	// /admin/settings without a restart; see reload.go.
+	s.WatchReloadSignal(context.Background())
+
 	return r
 }
 
 // SetupRoutes sets up routes on an existing router
 func (s *Server) SetupRoutes(r *gin.Engine) {
 	// Apply global security middleware if configured
-	if s.config != nil {
+	if cfg := s.config.Load(); cfg != nil {
 		// Rate limiting
-		if s.config.EnableRateLimit {
+		if cfg.EnableRateLimit {
 			r.Use(middleware.RateLimitMiddleware(
-				s.config.RateLimitRequests,
-				time.Duration(s.config.RateLimitWindowMinutes)*time.Minute,
-				s.config.RateLimitMessage,
+				cfg.RateLimitRequests,
+				time.Duration(cfg.RateLimitWindowMinutes)*time.Minute,
+				cfg.RateLimitMessage,
 			))
 		}
-		
+
 		// Security headers
-		if s.config.EnableSecurityHeaders {
+		if cfg.EnableSecurityHeaders {
 			securityConfig := &middleware.SecurityConfig{
 				EnableSecurityHeaders: true,
-				HSTS:                 s.config.EnableHSTS,
-				HSTSMaxAge:          s.config.HSTSMaxAge,
+				HSTS:                 cfg.EnableHSTS,
+				HSTSMaxAge:          cfg.HSTSMaxAge,
 				ContentTypeNosniff:  true,
 				XFrameOptions:       "SAMEORIGIN",
 				XSSProtection:       true,
 			}
 			r.Use(middleware.SecurityHeadersMiddleware(securityConfig))
 		}
-		
-		// CSRF protection for forms
-		if s.config.EnableCSRF {
-			// Apply CSRF middleware selectively (not on all routes)
-			// We'll add it to specific routes that need it
+
+		// CSRF protection for forms. Webhook/machine routes are exempt
+		// (see csrfExempt) since they can't carry a browser cookie.
+		if cfg.EnableCSRF {
+			r.Use(middleware.CSRFMiddleware(s.csrfExempt))
 		}
 	}
 	
@@ -475,89 +613,132 @@ func (s *Server) SetupRoutes(r *gin.Engine) {
 	
 	// API routes
 	r.POST("/api/login", s.handleLogin)
+	r.GET("/api/login/telegram/callback", s.handleTelegramLogin)
 	r.POST("/api/logout", s.handleLogout)
 	r.POST("/api/refresh", s.handleRefreshToken)
+	r.GET("/api/csrf", s.handleCSRFToken)
+	r.GET("/api/admin/telegram/queue", s.authMiddleware(), s.handleTelegramQueueStatus)
+	r.GET("/api/admin/telegram/queue/:job_id", s.authMiddleware(), s.handleTelegramQueueJob)
 
 	// Payment webhook routes
 	r.POST("/payment/epay/notify", s.handleEpayNotify)
+	// Generic notify route for every other provider (alipay, wechat, crypto),
+	// dispatched by the :gateway segment matching a payment.Provider's Name().
+	r.POST("/payment/notify/:gateway", s.handlePaymentNotify)
 	r.GET("/payment/return", s.handlePaymentReturn)
 	
 	// Test bot endpoint (protected)
-	r.POST("/admin/test-bot/:user_id", s.authMiddleware(), s.handleTestBot)
+	r.POST("/admin/test-notifier/:user_id", s.authMiddleware(), s.handleTestNotifier)
 
 	// Admin routes (protected)
-	adminGroup := r.Group("/admin", s.authMiddleware())
+	adminGroup := r.Group("/admin", s.authMiddleware(), s.AuditTrail())
 	{
 		// Product management
 		adminGroup.GET("/products", s.handleProductList)
 		adminGroup.GET("/products/test", func(c *gin.Context) {
 			c.HTML(http.StatusOK, "product_test.html", nil)
 		})
-		adminGroup.POST("/products", s.handleProductCreate)
-		adminGroup.PUT("/products/:id", s.handleProductUpdate)
-		adminGroup.DELETE("/products/:id", s.handleProductDelete)
-		adminGroup.PUT("/products/:id/restore", s.handleProductRestore)
-		adminGroup.DELETE("/products/:id/permanent", s.handleProductPermanentDelete)
+		adminGroup.POST("/products", s.RequirePermission("products.write"), s.handleProductCreate)
+		adminGroup.PUT("/products/:id", s.RequirePermission("products.write"), s.handleProductUpdate)
+		adminGroup.DELETE("/products/:id", s.RequirePermission("products.write"), s.handleProductDelete)
+		adminGroup.PUT("/products/:id/restore", s.RequirePermission("products.write"), s.handleProductRestore)
+		adminGroup.DELETE("/products/:id/permanent", s.RequirePermission("products.write"), s.handleProductPermanentDelete)
 		adminGroup.GET("/products/:id/codes", s.handleProductCodes)
-		adminGroup.POST("/products/:id/codes/upload", s.handleCodesUpload)
-		adminGroup.DELETE("/codes/:id", s.handleCodeDelete)
+		adminGroup.POST("/products/:id/codes/upload", s.RequirePermission("products.write"), s.handleCodesUpload)
+		adminGroup.DELETE("/codes/:id", s.RequirePermission("products.write"), s.handleCodeDelete)
 		adminGroup.GET("/codes/template", s.handleCodeTemplate)
 
 		// Order management
 		adminGroup.GET("/orders", s.handleOrderList)
-		
+		adminGroup.POST("/orders/:id/refund", s.RequirePermission("orders.write"), s.handleRefundOrder)
+		adminGroup.POST("/orders/:id/freeze", s.RequirePermission("orders.write"), s.handleFreezeOrder)
+		adminGroup.POST("/orders/:id/unfreeze", s.RequirePermission("orders.write"), s.handleUnfreezeOrder)
+		adminGroup.POST("/orders/:id/reissue-code", s.RequirePermission("orders.write"), s.handleReissueCode)
+
 		// User management
-		adminGroup.GET("/users", s.handleUserList)
-		adminGroup.GET("/users/:id", s.handleUserDetail)
+		adminGroup.GET("/users", s.RequirePermission("users.manage"), s.handleUserList)
+		adminGroup.GET("/users/:id", s.RequirePermission("users.manage"), s.handleUserDetail)
 
 		// Recharge card management
 		adminGroup.GET("/recharge-cards", s.handleRechargeCardList)
-		adminGroup.POST("/recharge-cards/generate", s.handleRechargeCardGenerate)
-		adminGroup.DELETE("/recharge-cards/:id", s.handleRechargeCardDelete)
+		adminGroup.POST("/recharge-cards/generate", s.RequirePermission("products.write"), s.handleRechargeCardGenerate)
+		adminGroup.DELETE("/recharge-cards/:id", s.RequirePermission("products.write"), s.handleRechargeCardDelete)
 		adminGroup.GET("/recharge-cards/:id/usage", s.handleRechargeCardUsage)
 
 		// Template management
 		adminGroup.GET("/templates", s.handleTemplateList)
-		adminGroup.POST("/templates/:id", s.handleTemplateUpdate)
+		adminGroup.POST("/templates/:id", s.RequirePermission("settings.write"), s.handleTemplateUpdate)
 
 		// System settings
-		adminGroup.GET("/settings", s.handleSettingsList)
-		adminGroup.POST("/settings", s.handleSettingsUpdate)
-		
+		adminGroup.GET("/settings", s.RequirePermission("settings.write"), s.handleSettingsList)
+		adminGroup.POST("/settings", s.RequirePermission("settings.write"), s.handleSettingsUpdate)
+
+		// GraphQL - an alternative to the REST list/filter handlers above
+		// for clients that want to shape their own queries.
+		adminGroup.POST("/graphql", s.handleGraphQL)
+
+		// Audit trail browser
+		adminGroup.GET("/audit", s.RequirePermission("settings.write"), s.handleAuditLogList)
+
+		// Telegram bot /link enrollment
+		adminGroup.POST("/telegram/link", s.RequirePermission("settings.write"), s.handleTelegramLink)
+
 		// FAQ management
 		adminGroup.GET("/faq", s.handleFAQList)
-		adminGroup.POST("/faq", s.handleFAQCreate)
-		adminGroup.PUT("/faq/:id", s.handleFAQUpdate)
-		adminGroup.DELETE("/faq/:id", s.handleFAQDelete)
-		adminGroup.PUT("/faq/:id/sort", s.handleFAQSort)
-		adminGroup.POST("/faq/init", s.handleFAQInit)
+		adminGroup.POST("/faq", s.RequirePermission("settings.write"), s.handleFAQCreate)
+		adminGroup.PUT("/faq/:id", s.RequirePermission("settings.write"), s.handleFAQUpdate)
+		adminGroup.DELETE("/faq/:id", s.RequirePermission("settings.write"), s.handleFAQDelete)
+		adminGroup.PUT("/faq/:id/sort", s.RequirePermission("settings.write"), s.handleFAQSort)
+		adminGroup.POST("/faq/init", s.RequirePermission("settings.write"), s.handleFAQInit)
 		
 		// Broadcast management
 		adminGroup.GET("/broadcast", s.handleBroadcastList)
-		adminGroup.POST("/broadcast", s.handleBroadcastCreate)
-		adminGroup.POST("/broadcast/send", s.handleBroadcastSend)  // Add this route for AJAX requests
+		adminGroup.POST("/broadcast", s.RequirePermission("broadcast.send"), s.handleBroadcastCreate)
+		adminGroup.POST("/broadcast/send", s.RequirePermission("broadcast.send"), s.handleBroadcastSend)  // Add this route for AJAX requests
 		adminGroup.GET("/broadcast/:id", s.handleBroadcastDetail)
+		adminGroup.POST("/broadcast/preview", s.RequirePermission("broadcast.send"), s.handleBroadcastPreview)
 		
 		// Ticket management
-		adminGroup.GET("/tickets", s.handleTicketList)
-		adminGroup.GET("/tickets/:id", s.handleTicketDetail)
-		adminGroup.POST("/tickets/:id/reply", s.handleTicketReply)
-		adminGroup.PUT("/tickets/:id/status", s.handleTicketStatusUpdate)
-		adminGroup.PUT("/tickets/:id/assign", s.handleTicketAssign)
-		adminGroup.GET("/ticket-templates", s.handleTicketTemplates)
+		adminGroup.GET("/tickets", s.RequirePermission("tickets.*"), s.handleTicketList)
+		adminGroup.GET("/tickets/:id", s.RequirePermission("tickets.*"), s.handleTicketDetail)
+		adminGroup.POST("/tickets/:id/reply", s.RequirePermission("tickets.*"), s.handleTicketReply)
+		adminGroup.PUT("/tickets/:id/status", s.RequirePermission("tickets.*"), s.handleTicketStatusUpdate)
+		adminGroup.PUT("/tickets/:id/assign", s.RequirePermission("tickets.*"), s.handleTicketAssign)
+		adminGroup.GET("/ticket-templates", s.RequirePermission("tickets.*"), s.handleTicketTemplates)
+
+		// Message catalog management
+		adminGroup.GET("/messages", s.handleMessagesList)
+		adminGroup.POST("/messages", s.RequirePermission("settings.write"), s.handleMessageUpdate)
+		adminGroup.POST("/messages/preview", s.RequirePermission("settings.write"), s.handleMessagePreview)
+		adminGroup.POST("/messages/reload", s.RequirePermission("settings.write"), s.handleMessagesReload)
+
+	// Catalog search (fuzzy product lookup, same scorer as the bot's /find)
+	adminGroup.GET("/catalog/search", s.handleCatalogSearch)
 
 		// Admin profile
 		adminGroup.GET("/profile/telegram", s.handleGetAdminTelegram)
 		adminGroup.POST("/profile/telegram", s.handleSetAdminTelegram)
-		adminGroup.POST("/ticket-templates", s.handleTicketTemplateCreate)
-		adminGroup.PUT("/ticket-templates/:id", s.handleTicketTemplateUpdate)
-		adminGroup.DELETE("/ticket-templates/:id", s.handleTicketTemplateDelete)
-		
+		adminGroup.POST("/ticket-templates", s.RequirePermission("tickets.*"), s.handleTicketTemplateCreate)
+		adminGroup.PUT("/ticket-templates/:id", s.RequirePermission("tickets.*"), s.handleTicketTemplateUpdate)
+		adminGroup.DELETE("/ticket-templates/:id", s.RequirePermission("tickets.*"), s.handleTicketTemplateDelete)
+
 		// Order maintenance APIs
-		adminGroup.POST("/api/settings", s.handleSaveSettings)
-		adminGroup.POST("/api/settings/core", s.handleSaveCoreSettings)
-		adminGroup.POST("/api/settings/payment", s.handleSavePaymentSettings)
-		adminGroup.POST("/api/orders/cleanup", s.handleCleanupOrders)
+		adminGroup.POST("/api/settings", s.RequirePermission("settings.write"), s.handleSaveSettings)
+		// These mutate bot/admin tokens, payment credentials, admin
+		// accounts, or bulk-delete orders, so each is gated behind the
+		// challenge/solve flow in tan.go on top of the usual auth check.
+		adminGroup.POST("/api/settings/core", s.requireTAN(TanOpCoreSettings, s.handleSaveCoreSettings))
+		adminGroup.POST("/api/settings/payment", s.requireTAN(TanOpPaymentSettings, s.handleSavePaymentSettings))
+		adminGroup.POST("/api/orders/cleanup", s.requireTAN(TanOpOrderCleanup, s.handleCleanupOrders))
+		adminGroup.DELETE("/admins/:id", s.requireTAN(TanOpAdminDelete, s.handleAdminUserDelete))
+		adminGroup.GET("/admins", s.RequirePermission("admins.manage"), s.handleAdminUserList)
+		adminGroup.POST("/admins", s.RequirePermission("admins.manage"), s.handleAdminUserCreate)
+		adminGroup.PUT("/admins/:id", s.RequirePermission("admins.manage"), s.handleAdminUserUpdate)
+		adminGroup.GET("/admins/:id/sessions", s.RequirePermission("admins.manage"), s.handleListUserSessions)
+		adminGroup.DELETE("/admins/:id/sessions/:family_id", s.RequirePermission("admins.manage"), s.handleRevokeUserSession)
+	adminGroup.POST("/api/settings/rotate-master-key", s.requireTAN(TanOpMasterKeyRotate, s.handleRotateMasterKey))
+		adminGroup.POST("/api/config/reload", s.RequirePermission("settings.write"), s.handleConfigReload)
+		adminGroup.POST("/api/migrations/status", s.handleMigrationsStatus)
 
 		// Dashboard
 		adminGroup.GET("/", s.handleAdminDashboard)
@@ -571,10 +752,10 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		userAgent := c.Request.UserAgent()
 
 		// Check session first if session manager is available
-		if s.sessionManager != nil {
+		if s.sessionManager.Load() != nil {
 			sessionID, err := c.Cookie("session_id")
 			if err == nil && sessionID != "" {
-				session, err := s.sessionManager.ValidateSession(sessionID, clientIP, userAgent)
+				session, err := s.sessionManager.Load().ValidateSession(sessionID, clientIP, userAgent)
 				if err == nil {
 					// Valid session found
 					c.Set("session_id", sessionID)
@@ -609,8 +790,8 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// 3. Validate token
 		if token != "" {
 			// First try JWT validation
-			if s.jwtService != nil {
-				claims, err := s.jwtService.ValidateToken(token)
+			if s.jwtService.Load() != nil {
+				claims, err := s.jwtService.Load().ValidateToken(token)
 				if err == nil {
 					// Store claims in context for later use
 					c.Set("user_claims", claims)
@@ -619,8 +800,8 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 					c.Set("role", claims.Role)
 
 					// Log data access if security logger is available
-					if s.securityLogger != nil {
-						s.securityLogger.LogDataAccess(
+					if s.securityLogger.Load() != nil {
+						s.securityLogger.Load().LogDataAccess(
 							claims.UserID,
 							claims.Username,
 							c.Request.URL.Path,
@@ -650,8 +831,8 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 
 		// Authentication failed
 		// Log unauthorized access
-		if s.securityLogger != nil {
-			s.securityLogger.LogAccessDenied(
+		if s.securityLogger.Load() != nil {
+			s.securityLogger.Load().LogAccessDenied(
 				"",
 				"",
 				c.Request.URL.Path,
@@ -677,125 +858,186 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 
 // handleLoginPage serves the login page
 func (s *Server) handleLoginPage(c *gin.Context) {
-	c.HTML(http.StatusOK, "login.html", nil)
+	// TelegramLoginEnabled/TelegramLoginBotUsername/TelegramLoginCallbackURL
+	// let login.html render the Telegram Login Widget script
+	// (data-auth-url pointed at /api/login/telegram/callback) next to the
+	// existing username/password form when Telegram SSO is turned on.
+	data := gin.H{"TelegramLoginEnabled": false}
+	if cfg := s.config.Load(); cfg != nil && cfg.TelegramLoginEnabled && cfg.BotToken != "" {
+		botUsername := ""
+		if s.bot != nil {
+			botUsername = s.bot.Self.UserName
+		}
+		data["TelegramLoginEnabled"] = botUsername != ""
+		data["TelegramLoginBotUsername"] = botUsername
+		data["TelegramLoginCallbackURL"] = "/api/login/telegram/callback"
+	}
+	c.HTML(http.StatusOK, "login.html", data)
 }
 
-// handleLogin processes login request
+// handleLogin authenticates against the AdminUser table - the real account
+// system - and falls back to the single shared adminToken only when
+// s.config.EnableLegacyAuth is set, for deployments mid-migration off it.
+// Real accounts carry their own numeric ID into the JWT/session instead of
+// the literal string "admin" every legacy login used to share, which is
+// what lets SessionManager's MaxConcurrent setting actually bound
+// concurrent sessions per admin instead of lumping every legacy login into
+// one shared bucket.
 func (s *Server) handleLogin(c *gin.Context) {
 	var req struct {
-		Token string `json:"token"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Token    string `json:"token"`
 	}
-	
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		JSONError(c, NewBadRequestError("Invalid request format", err))
 		return
 	}
-	
+
 	// Get client IP and User-Agent
 	clientIP := c.ClientIP()
 	userAgent := c.Request.UserAgent()
-	
+
 	// Check rate limiting if enabled
-	if s.rateLimiter != nil {
-		allowed, remaining := s.rateLimiter.CheckAttempt(clientIP)
+	if s.rateLimiter.Load() != nil {
+		allowed, remaining := s.rateLimiter.Load().CheckAttempt(clientIP)
 		if !allowed {
 			// Log security event
-			if s.securityLogger != nil {
-				s.securityLogger.LogRateLimited(clientIP, userAgent, "/api/login")
+			if s.securityLogger.Load() != nil {
+				s.securityLogger.Load().LogRateLimited(clientIP, userAgent, "/api/login")
 			}
 			JSONError(c, NewTooManyRequestsError(auth.FormatLockoutMessage(remaining)))
 			return
 		}
 	}
-	
-	// Verify token against admin token
-	if req.Token != s.adminToken {
-		// Record failed attempt
-		if s.rateLimiter != nil {
-			s.rateLimiter.RecordAttempt(clientIP, false)
+
+	var userID uint
+	var username, role string
+
+	switch {
+	case req.Username != "" && req.Password != "":
+		admin, err := store.GetAdminUserByUsername(s.db, req.Username)
+		if err != nil || !admin.IsActive || s.passwordService.Load() == nil ||
+			!s.passwordService.Load().VerifyPassword(admin.Password, req.Password) {
+			if s.rateLimiter.Load() != nil {
+				s.rateLimiter.Load().RecordAttempt(clientIP, false)
+			}
+			if s.securityLogger.Load() != nil {
+				s.securityLogger.Load().LogLoginFailed(req.Username, clientIP, userAgent, "invalid_credentials")
+			}
+			JSONError(c, NewUnauthorizedError("Invalid credentials"))
+			return
 		}
-		
-		// Log failed login
-		if s.securityLogger != nil {
-			s.securityLogger.LogLoginFailed("admin", clientIP, userAgent, "invalid_token")
+		userID, username, role = admin.ID, admin.Username, admin.Role
+		if err := store.RecordAdminLogin(s.db, admin.ID); err != nil {
+			logger.Error("Failed to record admin login", "admin_id", admin.ID, "error", err)
+		}
+
+	case s.config.Load() != nil && s.config.Load().EnableLegacyAuth && req.Token != "" && req.Token == s.adminToken:
+		userID, username, role = 0, "admin", "admin"
+
+	default:
+		if s.rateLimiter.Load() != nil {
+			s.rateLimiter.Load().RecordAttempt(clientIP, false)
+		}
+		if s.securityLogger.Load() != nil {
+			s.securityLogger.Load().LogLoginFailed(req.Username, clientIP, userAgent, "invalid_credentials")
 		}
-		
 		JSONError(c, NewUnauthorizedError("Invalid credentials"))
 		return
 	}
-	
+
 	// Record successful attempt
-	if s.rateLimiter != nil {
-		s.rateLimiter.RecordAttempt(clientIP, true)
+	if s.rateLimiter.Load() != nil {
+		s.rateLimiter.Load().RecordAttempt(clientIP, true)
 	}
-	
+
+	s.issueAdminSession(c, userID, username, role, clientIP, userAgent)
+}
+
+// issueAdminSession mints the session/JWT pair for an already-authenticated
+// admin (userID/username/role) and writes the login response, shared by
+// every login path (password, legacy token, Telegram SSO) so each one only
+// has to decide who the admin is, not how to log them in.
+func (s *Server) issueAdminSession(c *gin.Context, userID uint, username, role, clientIP, userAgent string) {
 	// Create session if session manager is available
 	var sessionID string
-	if s.sessionManager != nil {
-		session, err := s.sessionManager.CreateSession("admin", "admin", "admin", clientIP, userAgent)
+	if s.sessionManager.Load() != nil {
+		session, err := s.sessionManager.Load().CreateSession(userID, username, role, clientIP, userAgent)
 		if err != nil {
 			logger.Error("Failed to create session", "error", err)
 		} else {
 			sessionID = session.ID
 		}
 	}
-	
+
 	// Generate JWT token if JWT service is available
 	var responseToken string
 	var refreshToken string
-	
-	if s.jwtService != nil {
+
+	if s.jwtService.Load() != nil {
 		// Generate JWT tokens
-		token, err := s.jwtService.GenerateToken("admin", "admin", "admin")
+		token, err := s.jwtService.Load().GenerateToken(userID, username, role)
 		if err != nil {
 			logger.Error("Failed to generate JWT token", "error", err)
 			// Fall back to legacy token
 			responseToken = s.adminToken
 		} else {
 			responseToken = token
-			
-			// Generate refresh token
-			refresh, err := s.jwtService.GenerateRefreshToken("admin")
-			if err != nil {
+
+			// Generate a refresh token and start a new rotation family for
+			// it (see store.RefreshToken) - logging in always begins a
+			// fresh family, distinct from the one handleRefreshToken
+			// extends on each rotation.
+			jti, jtiErr := tan.GenerateNonce()
+			if jtiErr != nil {
+				logger.Error("Failed to generate refresh token jti", "error", jtiErr)
+			} else if refresh, err := s.jwtService.Load().GenerateRefreshToken(userID, jti); err != nil {
 				logger.Error("Failed to generate refresh token", "error", err)
 			} else {
 				refreshToken = refresh
+				cfg := s.config.Load()
+				expiry := time.Duration(cfg.JWTRefreshExpiry) * 24 * time.Hour
+				if err := store.CreateRefreshToken(s.db, jti, jti, "", userID, deviceFingerprint(c), clientIP, time.Now().Add(expiry)); err != nil {
+					logger.Error("Failed to record refresh token", "error", err)
+				}
 			}
 		}
 	} else {
 		// Use legacy token
 		responseToken = s.adminToken
 	}
-	
+
 	// Log successful login
-	if s.securityLogger != nil {
-		s.securityLogger.LogLogin("admin", "admin", clientIP, userAgent)
+	if s.securityLogger.Load() != nil {
+		s.securityLogger.Load().LogLogin(fmt.Sprint(userID), username, clientIP, userAgent)
 	}
-	
+
 	// Set cookie with the token
 	c.SetCookie("admin_token", responseToken, 86400*7, "/", "", false, true) // 7 days
-	
+
 	// Set session cookie if available
 	if sessionID != "" {
 		c.SetCookie("session_id", sessionID, 86400, "/", "", false, true) // 1 day
 	}
-	
+
 	// Return tokens in response
 	response := gin.H{
 		"success": true,
 		"token":   responseToken,
 	}
-	
+
 	if refreshToken != "" {
 		response["refresh_token"] = refreshToken
 		// Also set refresh token as httpOnly cookie
 		c.SetCookie("refresh_token", refreshToken, 86400*7, "/", "", false, true)
 	}
-	
+
 	if sessionID != "" {
 		response["session_id"] = sessionID
 	}
-	
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -807,96 +1049,198 @@ func (s *Server) handleLogout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
-// handleRefreshToken refreshes the access token using a refresh token
+// handleRefreshToken validates the presented refresh token, rotates it (the
+// presented token is revoked and a new one takes its place in the same
+// rotation family - see store.RefreshToken), and issues a new access token.
+// A refresh token presented a second time after it's already been rotated
+// past can only mean it was stolen and the legitimate client already moved
+// on, so that revokes the entire family and forces the user to log in
+// again, instead of just rejecting the one request.
 func (s *Server) handleRefreshToken(c *gin.Context) {
-	// Check if JWT service is available
-	if s.jwtService == nil {
+	if s.jwtService.Load() == nil {
 		JSONError(c, NewInternalError(fmt.Errorf("JWT service not available")))
 		return
 	}
-	
+
 	var refreshToken string
-	
-	// Try to get refresh token from request body
 	var req struct {
 		RefreshToken string `json:"refresh_token"`
 	}
 	if err := c.ShouldBindJSON(&req); err == nil && req.RefreshToken != "" {
 		refreshToken = req.RefreshToken
 	}
-	
-	// Try to get from cookie if not in body
 	if refreshToken == "" {
 		if cookie, err := c.Cookie("refresh_token"); err == nil {
 			refreshToken = cookie
 		}
 	}
-	
 	if refreshToken == "" {
 		JSONError(c, NewBadRequestError("Refresh token required", nil))
 		return
 	}
-	
-	// Generate new access token
-	newToken, err := s.jwtService.RefreshToken(refreshToken)
+
+	userID, jti, err := s.jwtService.Load().ParseRefreshClaims(refreshToken)
 	if err != nil {
 		JSONError(c, NewUnauthorizedError("Invalid refresh token"))
 		return
 	}
-	
-	// Set new token in cookie
+
+	// The reuse check (is this jti still active?) and the rotation (revoke
+	// it, insert its replacement) have to happen as one atomic unit - see
+	// store.RevokeRefreshTokenIfActive's doc comment for why a plain
+	// read-then-write here would let two concurrent requests both win.
+	var familyID string
+	var newToken, newRefreshToken string
+	txErr := s.db.Transaction(func(tx *gorm.DB) error {
+		stored, err := store.GetRefreshTokenByJTI(tx, jti)
+		if err != nil {
+			return errRefreshInvalid
+		}
+		familyID = stored.FamilyID
+
+		active, err := store.RevokeRefreshTokenIfActive(tx, jti)
+		if err != nil {
+			return err
+		}
+		if !active {
+			// Already revoked by an earlier rotation (or the losing side
+			// of a concurrent replay) - presenting it again means it was
+			// stolen after the legitimate client moved on.
+			if revokeErr := store.RevokeRefreshFamily(tx, familyID); revokeErr != nil {
+				return revokeErr
+			}
+			return errRefreshReuse
+		}
+
+		token, err := s.jwtService.Load().RefreshToken(refreshToken)
+		if err != nil {
+			return err
+		}
+		jti2, err := tan.GenerateNonce()
+		if err != nil {
+			return err
+		}
+		refresh, err := s.jwtService.Load().GenerateRefreshToken(userID, jti2)
+		if err != nil {
+			return err
+		}
+
+		cfg := s.config.Load()
+		expiry := time.Duration(cfg.JWTRefreshExpiry) * 24 * time.Hour
+		if err := store.CreateRefreshToken(tx, jti2, familyID, jti, userID, deviceFingerprint(c), c.ClientIP(), time.Now().Add(expiry)); err != nil {
+			return err
+		}
+
+		newToken, newRefreshToken = token, refresh
+		return nil
+	})
+
+	if txErr == errRefreshReuse {
+		logger.Audit("Refresh token reuse detected, family revoked", "admin_id", userID, "jti", jti, "family_id", familyID, "client_ip", c.ClientIP())
+		if s.securityLogger.Load() != nil {
+			s.securityLogger.Load().LogLoginFailed(fmt.Sprint(userID), c.ClientIP(), c.Request.UserAgent(), "refresh_token_reuse")
+		}
+		JSONError(c, NewUnauthorizedError("Refresh token has already been used, please log in again"))
+		return
+	}
+	if txErr == errRefreshInvalid {
+		JSONError(c, NewUnauthorizedError("Invalid refresh token"))
+		return
+	}
+	if txErr != nil {
+		JSONError(c, NewInternalError(txErr))
+		return
+	}
+
 	c.SetCookie("admin_token", newToken, 86400*7, "/", "", false, true)
-	
+	c.SetCookie("refresh_token", newRefreshToken, 86400*7, "/", "", false, true)
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"token":   newToken,
+		"success":       true,
+		"token":         newToken,
+		"refresh_token": newRefreshToken,
 	})
 }
 
-// handleTestBot tests sending a message to a user
-func (s *Server) handleTestBot(c *gin.Context) {
+// errRefreshReuse signals handleRefreshToken's transaction detected a
+// revoked (already-rotated) refresh token being presented again.
+var errRefreshReuse = errors.New("refresh token reuse detected")
+
+// errRefreshInvalid signals the presented jti has no matching row at all.
+var errRefreshInvalid = errors.New("refresh token not found")
+
+// handleTestNotifier tests sending a message to a user through whichever
+// channel they've selected (store.User.NotifyChannel), instead of always
+// going through Telegram - the direct s.bot.Send this replaced only ever
+// worked for Telegram users. userRef is resolved per-channel: the user's
+// TgUserID for Telegram, or NotifyChannelRef (a Matrix room ID / ntfy
+// topic) for the other backends.
+func (s *Server) handleTestNotifier(c *gin.Context) {
 	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
 	if err != nil {
 		JSONError(c, NewBadRequestError("Invalid user ID format", err))
 		return
 	}
-	
-	if s.bot == nil {
-		JSONError(c, NewInternalError(fmt.Errorf("bot service not initialized")))
+
+	var user store.User
+	if err := s.db.First(&user, uint(userID)).Error; err != nil {
+		JSONError(c, &AdminError{Status: http.StatusNotFound, Message: "User not found"})
 		return
 	}
-	
-	// Log bot info
-	logger.Info("Test bot", "bot_username", s.bot.Self.UserName, "bot_id", s.bot.Self.ID, "target_user", userID)
-	
-	// Send test message
-	testMsg := "ðŸ”” æµ‹è¯•æ¶ˆæ¯ / Test Message\n\nè¿™æ˜¯ä¸€æ¡æµ‹è¯•æ¶ˆæ¯ï¼Œç”¨äºŽéªŒè¯æœºå™¨äººè¿žæŽ¥ã€‚\nThis is a test message to verify bot connection."
-	msg := tgbotapi.NewMessage(userID, testMsg)
-	msg.ParseMode = "Markdown"
-	
-	resp, err := s.bot.Send(msg)
-	if err != nil {
-		logger.Error("Failed to send test message", "error", err, "user_id", userID, "error_type", fmt.Sprintf("%T", err))
-		if apiErr, ok := err.(*tgbotapi.Error); ok {
-			// Telegram API specific error
-			JSONError(c, AppError{
-				Code:       ErrCodeExternalService,
-				Message:    "Failed to send message via Telegram",
-				Details:    fmt.Sprintf("Telegram error: %s (code: %d)", apiErr.Message, apiErr.Code),
-				HTTPStatus: http.StatusBadRequest,
-				Err:        err,
-			})
+
+	channel := notifier.Channel(user.NotifyChannel)
+	userRef := user.NotifyChannelRef
+	if channel == "" || channel == notifier.ChannelTelegram {
+		channel = notifier.ChannelTelegram
+		userRef = strconv.FormatInt(user.TgUserID, 10)
+	}
+
+	testMsg := "🔔 测试消息 / Test Message\n\n这是一条测试消息，用于验证通知渠道连接。\nThis is a test message to verify the notification channel connection."
+
+	// Telegram goes through the durable, rate-limited queue instead of
+	// sending synchronously, so a flood of test sends (or a broadcast)
+	// can't trip Telegram's flood limits; poll /api/admin/telegram/queue
+	// for overall depth, or GET the job back later for this one's outcome.
+	if channel == notifier.ChannelTelegram {
+		if s.telegramQueue == nil {
+			JSONError(c, NewInternalError(fmt.Errorf("telegram send queue not configured")))
+			return
+		}
+		chatID, err := strconv.ParseInt(userRef, 10, 64)
+		if err != nil {
+			JSONError(c, NewBadRequestError("Invalid Telegram chat id", err))
+			return
+		}
+		jobID, err := s.telegramQueue.Enqueue(chatID, testMsg, "Markdown")
+		if err != nil {
+			JSONError(c, NewInternalError(err))
 			return
 		}
-		JSONError(c, NewExternalServiceError("Telegram Bot API", err))
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"channel": channel,
+			"job_id":  jobID,
+		})
 		return
 	}
-	
+
+	n, err := notifier.For(channel, s.config.Load(), s.bot)
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	ref, err := n.Send(c.Request.Context(), userRef, notifier.Message{Text: testMsg, ParseMode: "Markdown"})
+	if err != nil {
+		logger.Error("Failed to send test notification", "error", err, "user_id", userID, "channel", channel)
+		JSONError(c, NewExternalServiceError(string(channel), err))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message_id": resp.MessageID,
-		"chat_id": resp.Chat.ID,
-		"bot_username": s.bot.Self.UserName,
+		"success":    true,
+		"channel":    channel,
+		"message_id": ref,
 	})
 }
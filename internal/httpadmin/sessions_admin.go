@@ -0,0 +1,67 @@
+package httpadmin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"shop-bot/internal/store"
+)
+
+// deviceFingerprint derives a stable-per-device identifier from headers that
+// don't themselves identify a person, so store.RefreshToken rows can be
+// grouped by device without storing raw User-Agent strings.
+func deviceFingerprint(c *gin.Context) string {
+	h := sha256.Sum256([]byte(c.Request.UserAgent() + "|" + c.GetHeader("Accept-Language")))
+	return hex.EncodeToString(h[:])
+}
+
+// handleListUserSessions lists an admin's active (non-revoked) refresh
+// token families, one per logged-in device.
+func (s *Server) handleListUserSessions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid user id", err))
+		return
+	}
+
+	tokens, err := store.ListActiveRefreshTokensForUser(s.db, uint(userID))
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	sessions := make([]gin.H, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, gin.H{
+			"family_id":          t.FamilyID,
+			"device_fingerprint": t.DeviceFingerprint,
+			"ip":                 t.IP,
+			"created_at":         t.CreatedAt,
+			"expires_at":         t.ExpiresAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// handleRevokeUserSession revokes every token in a session's rotation
+// family, forcing that one device to log in again without affecting the
+// admin's other sessions.
+func (s *Server) handleRevokeUserSession(c *gin.Context) {
+	familyID := c.Param("family_id")
+	if familyID == "" {
+		JSONError(c, NewBadRequestError("Missing family id", nil))
+		return
+	}
+
+	if err := store.RevokeRefreshFamily(s.db, familyID); err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -9,6 +9,7 @@ import (
 	"shop-bot/internal/store"
 	logger "shop-bot/internal/log"
 	payment "shop-bot/internal/payment/epay"
+	"shop-bot/internal/secrets"
 )
 
 // handleSettings shows the settings page
@@ -31,19 +32,30 @@ func (s *Server) handleSettings(c *gin.Context) {
 		orderStats = make(map[string]int64)
 	}
 
+	cfg := s.config.Load()
+
 	// Get core settings from config
 	coreSettings := gin.H{
 		"admin_token": strings.Repeat("*", 20), // Mask the token
 		"bot_token": strings.Repeat("*", 20), // Mask the token
-		"admin_telegram_ids": s.config.AdminTelegramIDs,
+		"admin_telegram_ids": cfg.AdminTelegramIDs,
 	}
 
 	// Get payment settings from config
 	paymentSettings := gin.H{
-		"epay_pid": s.config.EpayPID,
+		"epay_pid": cfg.EpayPID,
 		"epay_key": strings.Repeat("*", 20), // Mask the key
-		"epay_gateway": s.config.EpayGateway,
-		"base_url": s.config.BaseURL,
+		"epay_gateway": cfg.EpayGateway,
+		"base_url": cfg.BaseURL,
+		"alipay_app_id": cfg.AlipayAppID,
+		"alipay_private_key": strings.Repeat("*", 20),
+		"alipay_public_key": strings.Repeat("*", 20),
+		"alipay_is_production": cfg.AlipayIsProduction,
+		"wechat_app_id": cfg.WechatAppID,
+		"wechat_mch_id": cfg.WechatMchID,
+		"wechat_serial_no": cfg.WechatSerialNo,
+		"wechat_private_key": strings.Repeat("*", 20),
+		"wechat_api_v3_key": strings.Repeat("*", 20),
 	}
 
 	// Get currency list
@@ -137,7 +149,9 @@ func (s *Server) handleSaveSettings(c *gin.Context) {
 			return
 		}
 	}
-	
+
+	auditSettingsChange(c, "handleSaveSettings", req)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Settings saved successfully"})
 }
 
@@ -271,9 +285,39 @@ func (s *Server) handleSaveCoreSettings(c *gin.Context) {
 		}
 	}
 
+	auditSettingsChange(c, "handleSaveCoreSettings", maskSensitiveSettings(updates))
+
 	c.JSON(http.StatusOK, gin.H{"message": "核心设置已保存"})
 }
 
+// maskSensitiveSettings returns a copy of updates with every key in
+// sensitiveSettingKeys replaced by a fixed-length mask, for logging a
+// settings diff without leaking the secret itself into the log stream.
+func maskSensitiveSettings(updates map[string]string) map[string]string {
+	masked := make(map[string]string, len(updates))
+	for key, value := range updates {
+		if sensitiveSettingKeysForAudit[key] {
+			masked[key] = strings.Repeat("*", 8)
+		} else {
+			masked[key] = value
+		}
+	}
+	return masked
+}
+
+// sensitiveSettingKeysForAudit mirrors store's sensitiveSettingKeys; kept
+// as its own copy since store doesn't export the set and httpadmin
+// shouldn't reach into an unexported package var to get it.
+var sensitiveSettingKeysForAudit = map[string]bool{
+	"admin_token":         true,
+	"bot_token":           true,
+	"epay_key":            true,
+	"alipay_private_key":  true,
+	"alipay_public_key":   true,
+	"wechat_private_key":  true,
+	"wechat_api_v3_key":   true,
+}
+
 // handleSavePaymentSettings saves payment gateway settings
 func (s *Server) handleSavePaymentSettings(c *gin.Context) {
 	var req struct {
@@ -281,6 +325,17 @@ func (s *Server) handleSavePaymentSettings(c *gin.Context) {
 		EpayKey     string `json:"epay_key"`
 		EpayGateway string `json:"epay_gateway"`
 		BaseURL     string `json:"base_url"`
+
+		AlipayAppID        string `json:"alipay_app_id"`
+		AlipayPrivateKey   string `json:"alipay_private_key"`
+		AlipayPublicKey    string `json:"alipay_public_key"`
+		AlipayIsProduction bool   `json:"alipay_is_production"`
+
+		WechatAppID      string `json:"wechat_app_id"`
+		WechatMchID      string `json:"wechat_mch_id"`
+		WechatSerialNo   string `json:"wechat_serial_no"`
+		WechatPrivateKey string `json:"wechat_private_key"`
+		WechatAPIv3Key   string `json:"wechat_api_v3_key"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -300,6 +355,25 @@ func (s *Server) handleSavePaymentSettings(c *gin.Context) {
 		updates["epay_key"] = req.EpayKey
 	}
 
+	updates["alipay_app_id"] = req.AlipayAppID
+	updates["alipay_is_production"] = strconv.FormatBool(req.AlipayIsProduction)
+	if req.AlipayPrivateKey != "" && !strings.Contains(req.AlipayPrivateKey, "*") {
+		updates["alipay_private_key"] = req.AlipayPrivateKey
+	}
+	if req.AlipayPublicKey != "" && !strings.Contains(req.AlipayPublicKey, "*") {
+		updates["alipay_public_key"] = req.AlipayPublicKey
+	}
+
+	updates["wechat_app_id"] = req.WechatAppID
+	updates["wechat_mch_id"] = req.WechatMchID
+	updates["wechat_serial_no"] = req.WechatSerialNo
+	if req.WechatPrivateKey != "" && !strings.Contains(req.WechatPrivateKey, "*") {
+		updates["wechat_private_key"] = req.WechatPrivateKey
+	}
+	if req.WechatAPIv3Key != "" && !strings.Contains(req.WechatAPIv3Key, "*") {
+		updates["wechat_api_v3_key"] = req.WechatAPIv3Key
+	}
+
 	// Update and reload configuration if config manager is available
 	if s.configManager != nil {
 		if err := s.configManager.UpdateAndReload(updates); err != nil {
@@ -310,23 +384,25 @@ func (s *Server) handleSavePaymentSettings(c *gin.Context) {
 		// Always try to update payment client when payment settings change
 		// This ensures configuration changes take effect immediately
 		if len(updates) > 0 {
+			cfg := s.config.Load()
+
 			// Log current configuration for debugging
 			logger.Info("Payment configuration after update",
-				"epay_pid", s.config.EpayPID,
-				"epay_key_set", s.config.EpayKey != "",
-				"epay_gateway", s.config.EpayGateway)
+				"epay_pid", cfg.EpayPID,
+				"epay_key_set", cfg.EpayKey != "",
+				"epay_gateway", cfg.EpayGateway)
 
 			// Update payment client if we have the minimum required configuration
-			if s.config.EpayPID != "" && s.config.EpayKey != "" && s.config.EpayGateway != "" {
-				s.epay = payment.NewClient(s.config.EpayPID, s.config.EpayKey, s.config.EpayGateway)
+			if cfg.EpayPID != "" && cfg.EpayKey != "" && cfg.EpayGateway != "" {
+				s.epay.Store(payment.NewClient(cfg.EpayPID, cfg.EpayKey, cfg.EpayGateway))
 				logger.Info("Payment client updated with new configuration")
 			} else {
 				// Set to nil if configuration is incomplete to avoid using stale client
-				s.epay = nil
+				s.epay.Store(nil)
 				logger.Info("Payment client set to nil due to incomplete configuration",
-					"epay_pid_empty", s.config.EpayPID == "",
-					"epay_key_empty", s.config.EpayKey == "",
-					"epay_gateway_empty", s.config.EpayGateway == "")
+					"epay_pid_empty", cfg.EpayPID == "",
+					"epay_key_empty", cfg.EpayKey == "",
+					"epay_gateway_empty", cfg.EpayGateway == "")
 			}
 		}
 	} else {
@@ -347,5 +423,67 @@ func (s *Server) handleSavePaymentSettings(c *gin.Context) {
 		}
 	}
 
+	auditSettingsChange(c, "handleSavePaymentSettings", maskSensitiveSettings(updates))
+
 	c.JSON(http.StatusOK, gin.H{"message": "支付设置已保存"})
+}
+
+// handleRotateMasterKey re-wraps every encrypted system setting's DEK under
+// a newly provided master key. Gated behind requireTAN (TanOpMasterKeyRotate)
+// since it's as sensitive as rotating the tokens it protects. Rotation only
+// touches the (small) wrapped DEKs, not the underlying ciphertext, so it
+// runs without downtime even with a large settings table.
+func (s *Server) handleRotateMasterKey(c *gin.Context) {
+	var req struct {
+		NewMasterKey string `json:"new_master_key"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.NewMasterKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new_master_key is required"})
+		return
+	}
+
+	oldManager, err := secrets.NewManager(s.config.Load().MasterKeySource)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "current master key is not configured: " + err.Error()})
+		return
+	}
+	newManager, err := secrets.NewManager(req.NewMasterKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid new master key: " + err.Error()})
+		return
+	}
+
+	if err := store.RotateMasterKey(s.db, oldManager, newManager); err != nil {
+		logger.Error("Master key rotation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rotation failed: " + err.Error()})
+		return
+	}
+
+	if s.configManager != nil {
+		if err := s.configManager.UpdateAndReload(map[string]string{"master_key": req.NewMasterKey}); err != nil {
+			logger.Error("Failed to persist rotated master key to config", "error", err)
+		}
+	}
+	s.config.Load().MasterKeySource = req.NewMasterKey
+
+	c.JSON(http.StatusOK, gin.H{"message": "Master key rotated"})
+}
+
+// handleAdminUserDelete removes an admin user. Gated behind requireTAN
+// (TanOpAdminDelete) same as the settings endpoints above, since it's just
+// as capable of locking out or handing over the shop as rotating tokens.
+func (s *Server) handleAdminUserDelete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin id"})
+		return
+	}
+
+	if err := s.db.Delete(&store.AdminUser{}, uint(id)).Error; err != nil {
+		logger.Error("Failed to delete admin user", "admin_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete admin user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Admin user deleted"})
 }
\ No newline at end of file
@@ -0,0 +1,200 @@
+package httpadmin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/auth/tan"
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+	"shop-bot/internal/twofactor"
+)
+
+const (
+	tanChallengeTTL = 5 * time.Minute
+	tanMaxAttempts  = 5
+)
+
+// Protected operation kinds, one per mutating endpoint wrapped in
+// requireTAN. Kept as a pluggable set of string constants so a new endpoint
+// can opt in without this file needing to know about it.
+const (
+	TanOpCoreSettings    = "core_settings"
+	TanOpPaymentSettings = "payment_settings"
+	TanOpAdminDelete     = "admin_delete"
+	TanOpOrderCleanup    = "order_cleanup"
+	TanOpMasterKeyRotate = "master_key_rotate"
+)
+
+func hashPayload(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// currentAdminID reads the admin ID authMiddleware stashed in the gin
+// context, tolerating whichever numeric type it was stored as depending on
+// whether the request authenticated via session cookie or token.
+func currentAdminID(c *gin.Context) (uint, bool) {
+	v, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	switch id := v.(type) {
+	case uint:
+		return id, true
+	case int:
+		return uint(id), true
+	case int64:
+		return uint(id), true
+	case float64:
+		return uint(id), true
+	default:
+		return 0, false
+	}
+}
+
+// requireTAN wraps a mutating handler behind a challenge/solve flow. The
+// first call (no X-Tan-Nonce header) persists a single-use challenge bound
+// to this exact request body, sends a numeric code to the admin via the
+// Telegram bot, and returns 202 without running handler. The client re-
+// posts the identical body with X-Tan-Nonce and X-Tan-Code set (the code
+// either the one delivered via Telegram, or a current TOTP code if the
+// admin enrolled a shared secret); only then does handler run.
+func (s *Server) requireTAN(op string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, ok := currentAdminID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		payloadHash := hashPayload(body)
+
+		nonce := c.GetHeader("X-Tan-Nonce")
+		if nonce == "" {
+			challenge, err := s.issueTanChallenge(op, adminID, payloadHash)
+			if err != nil {
+				logger.Error("Failed to issue TAN challenge", "error", err, "op", op, "admin_id", adminID)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start verification"})
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusAccepted, gin.H{
+				"tan_required": true,
+				"nonce":        challenge.Nonce,
+				"expires_in":   int(tanChallengeTTL.Seconds()),
+			})
+			c.Abort()
+			return
+		}
+
+		challenge, err := store.GetTanChallengeByNonce(s.db, nonce)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown or expired verification challenge"})
+			c.Abort()
+			return
+		}
+
+		code := c.GetHeader("X-Tan-Code")
+		if err := s.verifyTanChallenge(challenge, op, adminID, payloadHash, code); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		handler(c)
+	}
+}
+
+// issueTanChallenge persists a new single-use challenge and sends its code
+// to the admin over Telegram. Admins with no Telegram ID on file, or when
+// the Telegram send fails, fall back to TOTP-only: no code is stored, and
+// the client must solve with a code from their enrolled authenticator.
+func (s *Server) issueTanChallenge(op string, adminID uint, payloadHash string) (*store.TanChallenge, error) {
+	nonce, err := tan.GenerateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	var code string
+	var admin store.AdminUser
+	if err := s.db.First(&admin, adminID).Error; err == nil && admin.TelegramID != nil && s.bot != nil {
+		generated, err := tan.GenerateNumericCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate code: %w", err)
+		}
+		msg := tgbotapi.NewMessage(*admin.TelegramID, fmt.Sprintf(
+			"Verification code for %s: %s (expires in %d minutes)", op, generated, int(tanChallengeTTL.Minutes())))
+		if _, sendErr := s.bot.Send(msg); sendErr != nil {
+			logger.Warn("Failed to deliver TAN code via Telegram, falling back to TOTP-only", "error", sendErr, "admin_id", adminID)
+		} else {
+			code = generated
+		}
+	}
+
+	challenge := &store.TanChallenge{
+		Op:          op,
+		AdminID:     adminID,
+		PayloadHash: payloadHash,
+		Code:        code,
+		Nonce:       nonce,
+		MaxAttempts: tanMaxAttempts,
+		ExpiresAt:   time.Now().Add(tanChallengeTTL),
+	}
+	if err := store.CreateTanChallenge(s.db, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// verifyTanChallenge checks a challenge matches op/admin/payload, hasn't
+// expired, been used, or exhausted its attempts, and that code is either
+// the one delivered via Telegram or a current TOTP code for the admin's
+// enrolled secret.
+func (s *Server) verifyTanChallenge(challenge *store.TanChallenge, op string, adminID uint, payloadHash, code string) error {
+	if challenge.Solved {
+		return fmt.Errorf("challenge already used")
+	}
+	if challenge.Op != op || challenge.AdminID != adminID {
+		return fmt.Errorf("challenge does not match this request")
+	}
+	if challenge.PayloadHash != payloadHash {
+		return fmt.Errorf("request body changed since verification was requested")
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return fmt.Errorf("challenge expired, request a new code")
+	}
+	if challenge.Attempts >= challenge.MaxAttempts {
+		return fmt.Errorf("too many attempts, request a new code")
+	}
+
+	valid := code != "" && challenge.Code != "" && code == challenge.Code
+	if !valid {
+		if secret, err := store.GetTwoFactorSecret(s.db, adminID); err == nil && secret.Enabled {
+			valid = twofactor.Validate(secret.Secret, code)
+		}
+	}
+
+	if !valid {
+		store.IncrementTanChallengeAttempts(s.db, challenge.ID)
+		return fmt.Errorf("incorrect verification code")
+	}
+
+	return store.MarkTanChallengeSolved(s.db, challenge.ID)
+}
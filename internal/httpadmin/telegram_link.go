@@ -0,0 +1,48 @@
+package httpadmin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// handleTelegramLink completes the bot's /link enrollment flow: the
+// logged-in admin pastes the token their Telegram DM gave them, and this
+// binds that chat's Telegram ID to their account, the same pairing
+// GetAdminUserByTelegramID (used by the Telegram Login Widget) and the
+// bot's admin command gate look up later.
+func (s *Server) handleTelegramLink(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		JSONError(c, NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	adminID, ok := currentAdminID(c)
+	if !ok {
+		JSONError(c, NewUnauthorizedError("Authentication required"))
+		return
+	}
+
+	tokenEntry, err := store.GetTelegramLinkToken(s.db, req.Token)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid or expired link token", err))
+		return
+	}
+
+	if err := store.SetAdminUserTelegramID(s.db, adminID, tokenEntry.TelegramID); err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	if err := store.MarkTelegramLinkTokenUsed(s.db, tokenEntry.ID); err != nil {
+		logger.Warn("Failed to mark Telegram link token used", "error", err, "token_id", tokenEntry.ID)
+	}
+
+	logger.Audit("Admin account linked to Telegram", "admin_id", adminID, "telegram_id", tokenEntry.TelegramID)
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram account linked", "telegram_id": tokenEntry.TelegramID})
+}
@@ -0,0 +1,119 @@
+package httpadmin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/store"
+)
+
+// handleTelegramLogin verifies a Telegram Login Widget callback
+// (https://core.telegram.org/widgets/login#checking-authorization) and, if
+// the callback's telegram_id maps to an active AdminUser, logs that admin
+// in the same way handleLogin does. It's mounted as the widget's
+// data-auth-url, so Telegram delivers the callback as a GET with the
+// fields as query parameters.
+func (s *Server) handleTelegramLogin(c *gin.Context) {
+	cfg := s.config.Load()
+	if cfg == nil || !cfg.TelegramLoginEnabled {
+		JSONError(c, NewUnauthorizedError("Telegram login is not enabled"))
+		return
+	}
+	if cfg.BotToken == "" {
+		JSONError(c, NewUnauthorizedError("Telegram login is not configured"))
+		return
+	}
+
+	clientIP := c.ClientIP()
+	userAgent := c.Request.UserAgent()
+
+	query := c.Request.URL.Query()
+	hash := query.Get("hash")
+	if hash == "" {
+		JSONError(c, NewBadRequestError("Missing hash", nil))
+		return
+	}
+
+	fields := make([]string, 0, len(query))
+	for key, values := range query {
+		if key == "hash" || len(values) == 0 {
+			continue
+		}
+		fields = append(fields, key+"="+values[0])
+	}
+	sort.Strings(fields)
+	dataCheckString := strings.Join(fields, "\n")
+
+	secret := sha256.Sum256([]byte(cfg.BotToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(dataCheckString))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(hash))) {
+		if sl := s.securityLogger.Load(); sl != nil {
+			sl.LogLoginFailed("telegram", clientIP, userAgent, "bad_signature")
+		}
+		JSONError(c, NewUnauthorizedError("Invalid Telegram login signature"))
+		return
+	}
+
+	authDate, err := strconv.ParseInt(query.Get("auth_date"), 10, 64)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid auth_date", err))
+		return
+	}
+	ttl := cfg.TelegramLoginTTLSeconds
+	if ttl <= 0 {
+		ttl = 86400
+	}
+	if time.Since(time.Unix(authDate, 0)) > time.Duration(ttl)*time.Second {
+		JSONError(c, NewUnauthorizedError("Telegram login has expired, please try again"))
+		return
+	}
+
+	telegramID, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid Telegram id", err))
+		return
+	}
+
+	if allowed := cfg.TelegramLoginAllowedIDList(); len(allowed) > 0 {
+		ok := false
+		for _, id := range allowed {
+			if id == telegramID {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			if sl := s.securityLogger.Load(); sl != nil {
+				sl.LogLoginFailed("telegram", clientIP, userAgent, "telegram_id_not_allowed")
+			}
+			JSONError(c, NewUnauthorizedError("This Telegram account is not authorized"))
+			return
+		}
+	}
+
+	admin, err := store.GetAdminUserByTelegramID(s.db, telegramID)
+	if err != nil || !admin.IsActive {
+		if sl := s.securityLogger.Load(); sl != nil {
+			sl.LogLoginFailed("telegram", clientIP, userAgent, "no_admin_mapped")
+		}
+		JSONError(c, NewUnauthorizedError("This Telegram account is not linked to an admin"))
+		return
+	}
+
+	if err := store.RecordAdminLogin(s.db, admin.ID); err != nil {
+		logger.Error("Failed to record admin login", "admin_id", admin.ID, "error", err)
+	}
+
+	s.issueAdminSession(c, admin.ID, admin.Username, admin.Role, clientIP, userAgent)
+}
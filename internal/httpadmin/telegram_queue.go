@@ -0,0 +1,77 @@
+package httpadmin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"shop-bot/internal/store"
+)
+
+// StartTelegramQueue runs the durable Telegram send queue's worker loop
+// until ctx is cancelled, the same "caller starts it in its own goroutine"
+// shape as WatchReloadSignal. A nil telegramQueue (no bot configured) is a
+// no-op.
+func (s *Server) StartTelegramQueue(ctx context.Context) {
+	if s.telegramQueue == nil {
+		return
+	}
+	s.telegramQueue.Start(ctx)
+}
+
+// handleTelegramQueueStatus reports the send queue's current depth and
+// failure count, so an admin watching a broadcast go out can tell it's
+// working through the backlog rather than stuck.
+func (s *Server) handleTelegramQueueStatus(c *gin.Context) {
+	if s.telegramQueue == nil {
+		JSONError(c, &AdminError{Status: http.StatusServiceUnavailable, Message: "Telegram send queue not configured"})
+		return
+	}
+
+	pending, err := store.CountTelegramSendJobsByStatus(s.db, "pending")
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+	failed, err := store.CountTelegramSendJobsByStatus(s.db, "failed")
+	if err != nil {
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending": pending,
+		"failed":  failed,
+	})
+}
+
+// handleTelegramQueueJob returns one job's current status, for an admin
+// polling the job_id handleTestNotifier handed back.
+func (s *Server) handleTelegramQueueJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		JSONError(c, NewBadRequestError("Invalid job id", err))
+		return
+	}
+
+	job, err := store.GetTelegramSendJob(s.db, uint(jobID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			JSONError(c, &AdminError{Status: http.StatusNotFound, Message: "Job not found"})
+			return
+		}
+		JSONError(c, NewInternalError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         job.ID,
+		"status":     job.Status,
+		"attempts":   job.Attempts,
+		"last_error": job.LastError,
+		"message_id": job.MessageID,
+	})
+}
@@ -0,0 +1,152 @@
+// Package log provides structured, leveled logging shared by every
+// package in the bot. Each call emits one JSON line with a level, message,
+// timestamp, and the supplied key/value fields. By default (before Init is
+// called) it writes directly to stdout with no external dependencies, so
+// packages that log during early startup/tests never see a nil logger.
+// Calling Init switches to a zap logger backed by lumberjack for rotating
+// file output, once config is available.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type level string
+
+const (
+	levelDebug level = "debug"
+	levelInfo  level = "info"
+	levelWarn  level = "warn"
+	levelError level = "error"
+	// levelAudit marks settings-mutation records (who changed what) so
+	// they can be filtered out of the regular operational log stream.
+	levelAudit level = "audit"
+)
+
+// Config configures Init's rotating file output. An empty File means log
+// to stdout only, same as before this existed.
+type Config struct {
+	File       string
+	MaxSizeMB  int
+	MaxAgeDays int
+	MaxBackups int
+	Level      string
+}
+
+// zapLogger is nil until Init is called, in which case log() falls back to
+// the original plain-JSON-to-stdout writer below.
+var zapLogger *zap.Logger
+
+// Init switches logging to a zap logger writing rotating JSON lines via
+// lumberjack. Safe to call once at startup after config is loaded; logging
+// before that point (or in a deployment that never calls Init) uses the
+// zero-dependency stdout fallback.
+func Init(cfg Config) error {
+	zapLevel, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		zapLevel = zapcore.InfoLevel
+	}
+
+	var writer zapcore.WriteSyncer
+	if cfg.File != "" {
+		writer = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxAge:     cfg.MaxAgeDays,
+			MaxBackups: cfg.MaxBackups,
+		})
+	} else {
+		writer = zapcore.AddSync(os.Stdout)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.MessageKey = "msg"
+	encoderConfig.LevelKey = "level"
+	encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), writer, zapLevel)
+	zapLogger = zap.New(core)
+	return nil
+}
+
+func log(lvl level, msg string, kv ...interface{}) {
+	if zapLogger != nil {
+		fields := make([]zap.Field, 0, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			key, ok := kv[i].(string)
+			if !ok {
+				key = fmt.Sprintf("%v", kv[i])
+			}
+			fields = append(fields, zap.Any(key, kv[i+1]))
+		}
+
+		switch lvl {
+		case levelDebug:
+			zapLogger.Debug(msg, fields...)
+		case levelWarn:
+			zapLogger.Warn(msg, fields...)
+		case levelError:
+			zapLogger.Error(msg, fields...)
+		default:
+			// Info and audit both log at info severity; audit records are
+			// told apart by their "level":"audit" field, set explicitly
+			// below since zap's own level enum has no audit equivalent.
+			if lvl == levelAudit {
+				fields = append(fields, zap.String("level", string(levelAudit)))
+			}
+			zapLogger.Info(msg, fields...)
+		}
+		return
+	}
+
+	fallbackLog(lvl, msg, kv...)
+}
+
+func fallbackLog(lvl level, msg string, kv ...interface{}) {
+	entry := map[string]interface{}{
+		"time":  time.Now().UTC().Format(time.RFC3339Nano),
+		"level": string(lvl),
+		"msg":   msg,
+	}
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprintf("%v", kv[i])
+		}
+		entry[key] = kv[i+1]
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, `{"level":"error","msg":"failed to marshal log entry","error":%q}`+"\n", err)
+		return
+	}
+
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+// Debug logs a debug-level structured event.
+func Debug(msg string, kv ...interface{}) { log(levelDebug, msg, kv...) }
+
+// Info logs an info-level structured event.
+func Info(msg string, kv ...interface{}) { log(levelInfo, msg, kv...) }
+
+// Warn logs a warn-level structured event.
+func Warn(msg string, kv ...interface{}) { log(levelWarn, msg, kv...) }
+
+// Error logs an error-level structured event.
+func Error(msg string, kv ...interface{}) { log(levelError, msg, kv...) }
+
+// Audit logs a settings-mutation record - who changed what, with sensitive
+// values already masked by the caller - so operators can reconstruct an
+// admin's actions from the log stream alone.
+func Audit(msg string, kv ...interface{}) { log(levelAudit, msg, kv...) }
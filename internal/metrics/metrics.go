@@ -0,0 +1,95 @@
+// Package metrics holds the Prometheus collectors shared by the bot,
+// broadcast, and worker packages so operators get one dashboard across
+// update handling, broadcast delivery, and background jobs.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// BotMessagesReceived counts incoming Telegram updates by kind
+	// ("command", "text", "callback", "group", "inline_query",
+	// "chat_member", "edited_message").
+	BotMessagesReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shop_bot_messages_received_total",
+			Help: "Number of Telegram updates received, by kind.",
+		},
+		[]string{"kind"},
+	)
+
+	// BotUpdatesHandled counts updates that finished processing, by handler
+	// name and outcome ("ok" or "error").
+	BotUpdatesHandled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shop_bot_updates_handled_total",
+			Help: "Number of Telegram updates fully handled, by handler and outcome.",
+		},
+		[]string{"handler", "outcome"},
+	)
+
+	// BotHandlerDuration observes how long each update handler took.
+	BotHandlerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "shop_bot_handler_duration_seconds",
+			Help:    "Update handler latency in seconds, by handler name.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"handler"},
+	)
+
+	// OrdersCreated counts orders created across the buy and deposit flows.
+	OrdersCreated = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "shop_bot_orders_created_total",
+			Help: "Number of orders created.",
+		},
+	)
+
+	// WebhookUpdatesReceived counts updates delivered via the Telegram webhook.
+	WebhookUpdatesReceived = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "shop_bot_webhook_updates_received_total",
+			Help: "Number of updates received over the webhook transport.",
+		},
+	)
+
+	// RetryWorkerAttempts counts retry-worker passes, by outcome ("ok"/"failed").
+	RetryWorkerAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shop_bot_retry_worker_attempts_total",
+			Help: "Number of retry-worker attempts, by outcome.",
+		},
+		[]string{"outcome"},
+	)
+
+	// BroadcastSent counts broadcast send outcomes, by outcome ("sent"/"failed").
+	BroadcastSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shop_bot_broadcast_sent_total",
+			Help: "Number of broadcast messages sent, by outcome.",
+		},
+		[]string{"outcome"},
+	)
+
+	// BotRateLimited counts how often a user was throttled, by the action they hit.
+	BotRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shop_bot_rate_limited_total",
+			Help: "Number of requests rejected by the rate limiter, by action.",
+		},
+		[]string{"action"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		BotMessagesReceived,
+		BotUpdatesHandled,
+		BotHandlerDuration,
+		OrdersCreated,
+		WebhookUpdatesReceived,
+		RetryWorkerAttempts,
+		BroadcastSent,
+		BotRateLimited,
+	)
+}
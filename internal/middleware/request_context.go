@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is both the inbound header RequestContext echoes a
+// caller-supplied request ID from, and the outbound header it's always
+// sent back on, so a request can be correlated across a load balancer,
+// this service's logs, and the caller's own logs.
+const RequestIDHeader = "X-Request-ID"
+
+// ginRequestContextKey is what RequestContext stores its *RequestContext
+// under in gin.Context; requestContextKey is the equivalent for
+// context.Context, for code that only has the stdlib request context.
+type ginRequestContextKey struct{}
+type requestContextKey struct{}
+
+// RequestContext carries everything a single request's log lines and
+// audit trail row should be tagged with. RequestID/StartTime/ClientIP/Route
+// are set before the rest of the chain runs; UserID/Role are copied from
+// the gin context's "user_id"/"role" keys only after the whole chain has
+// returned, so middleware and handlers further down the chain (AuditTrail
+// included) see them empty and read "user_id" from the gin context instead.
+type RequestContext struct {
+	RequestID string
+	StartTime time.Time
+	ClientIP  string
+	Route     string
+	UserID    string
+	Role      string
+}
+
+// FromGinContext returns the RequestContext RequestContext() attached to
+// c, or a zero-value one (with a freshly generated RequestID) if the
+// middleware wasn't installed - callers should never nil-check this.
+func FromGinContext(c *gin.Context) *RequestContext {
+	if v, ok := c.Get(ginRequestContextKeyName); ok {
+		if rc, ok := v.(*RequestContext); ok {
+			return rc
+		}
+	}
+	return &RequestContext{RequestID: GenerateRequestID(), StartTime: time.Now()}
+}
+
+// ginRequestContextKeyName is a string key (rather than the unexported
+// ginRequestContextKey{} struct) because gin.Context.Get is keyed by
+// string, not by arbitrary comparable value like context.Context is.
+const ginRequestContextKeyName = "request_ctx"
+
+// RequestIDFromContext returns the request ID stashed in ctx by
+// RequestContext, or "" if ctx wasn't derived from a request it handled.
+func RequestIDFromContext(ctx context.Context) string {
+	rc, _ := ctx.Value(requestContextKey{}).(*RequestContext)
+	if rc == nil {
+		return ""
+	}
+	return rc.RequestID
+}
+
+// NewRequestContextMiddleware returns middleware that attaches a
+// *RequestContext to both the gin.Context (key "request_ctx") and the
+// request's context.Context, generating a UUIDv7 request ID (or reusing an
+// inbound X-Request-ID), resolving the real client IP with
+// X-Forwarded-For/X-Real-IP honored only when the immediate peer is in
+// trustedProxies, and echoing the request ID back via the X-Request-ID
+// response header. Install it before requestLogger so every subsequent
+// middleware and handler shares the same RequestContext value; UserID/Role
+// are filled in by this middleware itself once c.Next() returns.
+func NewRequestContextMiddleware(trustedProxies []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = GenerateRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		rc := &RequestContext{
+			RequestID: requestID,
+			StartTime: time.Now(),
+			ClientIP:  ResolveClientIP(c.Request, trustedProxies),
+			Route:     c.FullPath(),
+		}
+
+		c.Set(ginRequestContextKeyName, rc)
+		ctx := context.WithValue(c.Request.Context(), requestContextKey{}, rc)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// authMiddleware (or whatever resolved auth) runs after us in the
+		// chain but before the final handler, so by the time c.Next()
+		// returns these are populated if the request was authenticated.
+		if v, ok := c.Get("user_id"); ok {
+			rc.UserID = fmt.Sprintf("%v", v)
+		}
+		if v, ok := c.Get("role"); ok {
+			rc.Role = fmt.Sprintf("%v", v)
+		}
+	}
+}
+
+// ResolveClientIP returns the request's real client IP: req.RemoteAddr
+// unless it's one of trustedProxies, in which case the right-most
+// untrusted address in X-Forwarded-For (or X-Real-IP, if that's all
+// there is) is used instead. This avoids trusting a spoofed
+// X-Forwarded-For from a client that isn't actually behind our proxy.
+func ResolveClientIP(req *http.Request, trustedProxies []*net.IPNet) string {
+	remoteIP := remoteAddrIP(req.RemoteAddr)
+	if remoteIP == nil || !ipInNets(remoteIP, trustedProxies) {
+		if remoteIP != nil {
+			return remoteIP.String()
+		}
+		return req.RemoteAddr
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		for i := len(parts) - 1; i >= 0; i-- {
+			candidate := net.ParseIP(strings.TrimSpace(parts[i]))
+			if candidate == nil {
+				continue
+			}
+			if !ipInNets(candidate, trustedProxies) {
+				return candidate.String()
+			}
+		}
+	}
+
+	if xri := req.Header.Get("X-Real-IP"); xri != "" {
+		if candidate := net.ParseIP(strings.TrimSpace(xri)); candidate != nil {
+			return candidate.String()
+		}
+	}
+
+	return remoteIP.String()
+}
+
+func remoteAddrIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateRequestID returns a UUIDv7 string: a 48-bit millisecond Unix
+// timestamp followed by random bits, with the version/variant nibbles set
+// per RFC 9562. Being time-ordered makes request IDs sort (and therefore
+// index) the same way the audit trail they tag does.
+func GenerateRequestID() string {
+	var buf [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint64(buf[0:8], ms<<16)
+	if _, err := rand.Read(buf[6:]); err != nil {
+		// rand.Read failing is effectively unrecoverable, but a degraded
+		// (still unique-enough-to-correlate-a-request) ID beats a panic.
+	}
+	// Write the 48-bit timestamp into buf[0:6]; rand.Read above filled only
+	// buf[6:], so this repeats what PutUint64 already stored there.
+	buf[0] = byte(ms >> 40)
+	buf[1] = byte(ms >> 32)
+	buf[2] = byte(ms >> 24)
+	buf[3] = byte(ms >> 16)
+	buf[4] = byte(ms >> 8)
+	buf[5] = byte(ms)
+	buf[6] = (buf[6] & 0x0f) | 0x70 // version 7
+	buf[8] = (buf[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	s := hex.EncodeToString(buf[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
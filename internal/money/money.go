@@ -0,0 +1,53 @@
+// Package money is the shop's canonical representation of currency amounts.
+// All arithmetic (balance deduction, refunds, deposits) is done on
+// decimal.Decimal so rounding errors can't accumulate the way they can with
+// float64 (0.1+0.2 != 0.3); amounts only become an int at the storage
+// boundary (cents, matching the existing *_cents DB columns) and a float64
+// at the payment gateway boundary.
+package money
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// FromCents converts an integer cent amount (the storage representation) to
+// a Decimal in the major currency unit, e.g. FromCents(1050) == 10.50.
+func FromCents(cents int) decimal.Decimal {
+	return decimal.New(int64(cents), -2)
+}
+
+// ToCents rounds d to the nearest cent and returns it as an int, e.g.
+// ToCents(10.505) == 1051.
+func ToCents(d decimal.Decimal) int {
+	return int(d.Round(2).Shift(2).IntPart())
+}
+
+// ParseAmount parses a user-supplied amount string (e.g. "30" or "29.99"),
+// rejecting more than two fractional digits so a typo like "10.999" can't
+// silently round away a third of a cent.
+func ParseAmount(s string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if amount.Exponent() < -2 {
+		return decimal.Decimal{}, fmt.Errorf("amount %q has more than two fractional digits", s)
+	}
+	return amount, nil
+}
+
+// ToGatewayFloat converts d to the float64 the epay gateway's API expects.
+// Callers must check exact and log when it's false, since that means the
+// conversion lost precision before the amount left our system.
+func ToGatewayFloat(d decimal.Decimal) (value float64, exact bool) {
+	return d.Float64()
+}
+
+// Format renders d with exactly two fractional digits for display, e.g.
+// "10.50", regardless of how many digits d itself carries.
+func Format(d decimal.Decimal) string {
+	return d.StringFixed(2)
+}
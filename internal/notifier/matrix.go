@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MatrixNotifier sends via a Matrix homeserver's Client-Server API, using a
+// single bot account's access token. userRef is the room ID the user's DM
+// with that bot account lives in (Matrix has no concept of messaging a user
+// directly outside of a room).
+type MatrixNotifier struct {
+	homeserverURL string
+	accessToken   string
+	httpClient    *http.Client
+}
+
+// NewMatrixNotifier builds a notifier against homeserverURL (e.g.
+// "https://matrix.org") authenticated as accessToken's account.
+func NewMatrixNotifier(homeserverURL, accessToken string) *MatrixNotifier {
+	return &MatrixNotifier{
+		homeserverURL: homeserverURL,
+		accessToken:   accessToken,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type matrixEventResponse struct {
+	EventID string `json:"event_id"`
+}
+
+func (n *MatrixNotifier) Send(ctx context.Context, userRef string, msg Message) (MessageRef, error) {
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	body := map[string]string{
+		"msgtype": "m.text",
+		"body":    msg.Text,
+	}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(userRef), txnID)
+
+	var resp matrixEventResponse
+	if err := n.do(ctx, http.MethodPut, path, body, &resp); err != nil {
+		return "", err
+	}
+	return MessageRef(resp.EventID), nil
+}
+
+func (n *MatrixNotifier) Edit(ctx context.Context, userRef string, ref MessageRef, msg Message) error {
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	body := map[string]interface{}{
+		"msgtype": "m.text",
+		"body":    "* " + msg.Text,
+		"m.new_content": map[string]string{
+			"msgtype": "m.text",
+			"body":    msg.Text,
+		},
+		"m.relates_to": map[string]string{
+			"rel_type": "m.replace",
+			"event_id": string(ref),
+		},
+	}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/%s", url.PathEscape(userRef), txnID)
+	return n.do(ctx, http.MethodPut, path, body, nil)
+}
+
+func (n *MatrixNotifier) Delete(ctx context.Context, userRef string, ref MessageRef) error {
+	txnID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/redact/%s/%s", url.PathEscape(userRef), url.PathEscape(string(ref)), txnID)
+	return n.do(ctx, http.MethodPut, path, map[string]string{}, nil)
+}
+
+func (n *MatrixNotifier) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("notifier: marshal matrix request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, n.homeserverURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("notifier: build matrix request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.accessToken)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifier: matrix request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notifier: matrix returned %d: %s", resp.StatusCode, respBody)
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("notifier: decode matrix response: %w", err)
+		}
+	}
+	return nil
+}
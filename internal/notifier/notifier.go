@@ -0,0 +1,54 @@
+// Package notifier abstracts "send a message to a user" behind one
+// interface so the HTTP layer (and eventually order-state notifications,
+// see bot.sendOrEditOrderMessage) doesn't have to hard-code Telegram. Users
+// pick a preferred channel in their profile (store.User.NotifyChannel);
+// For resolves the backend for it.
+package notifier
+
+import "context"
+
+// Channel identifies a notification backend. It's stored on store.User as
+// the user's preferred channel.
+type Channel string
+
+const (
+	ChannelTelegram Channel = "telegram"
+	ChannelMatrix   Channel = "matrix"
+	ChannelNtfy     Channel = "ntfy"
+)
+
+// Message is a transport-agnostic notification. ParseMode follows
+// Telegram's convention ("Markdown", "HTML", or "" for plain text);
+// backends that don't support it fall back to stripping or ignoring it.
+type Message struct {
+	Text      string
+	ParseMode string
+}
+
+// MessageRef identifies a previously sent message so it can later be
+// edited or deleted, e.g. a Telegram message ID or a Matrix event ID.
+// It's opaque to callers - store and pass back whatever a backend returned.
+type MessageRef string
+
+// Notifier sends, edits, and deletes a notification for one user on one
+// transport. userRef is transport-specific (a Telegram chat ID, a Matrix
+// user ID, an ntfy topic) - callers get it from store.User's channel-specific
+// identifier field for whichever Channel they resolved.
+type Notifier interface {
+	Send(ctx context.Context, userRef string, msg Message) (MessageRef, error)
+	Edit(ctx context.Context, userRef string, ref MessageRef, msg Message) error
+	Delete(ctx context.Context, userRef string, ref MessageRef) error
+}
+
+// ErrEditNotSupported is returned by backends whose transport has no
+// concept of editing a delivered message (e.g. plain ntfy). Callers should
+// fall back to Delete+Send, same as bot.sendOrEditOrderMessage falls back
+// to a fresh send when an edit fails.
+var ErrEditNotSupported = notSupportedError("notifier: edit not supported by this backend")
+
+// ErrDeleteNotSupported is the Delete equivalent of ErrEditNotSupported.
+var ErrDeleteNotSupported = notSupportedError("notifier: delete not supported by this backend")
+
+type notSupportedError string
+
+func (e notSupportedError) Error() string { return string(e) }
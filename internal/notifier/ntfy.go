@@ -0,0 +1,66 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NtfyNotifier publishes to an ntfy (https://ntfy.sh, or self-hosted)
+// topic. ntfy has no API for editing or deleting a delivered push, so Edit
+// and Delete always return ErrEditNotSupported/ErrDeleteNotSupported -
+// callers should fall back to sending a new message, same as
+// bot.sendOrEditOrderMessage falls back when a Telegram edit fails.
+type NtfyNotifier struct {
+	serverURL  string
+	authToken  string
+	httpClient *http.Client
+}
+
+// NewNtfyNotifier builds a notifier against serverURL (e.g.
+// "https://ntfy.sh"). authToken may be empty for an anonymous/public
+// server.
+func NewNtfyNotifier(serverURL, authToken string) *NtfyNotifier {
+	return &NtfyNotifier{
+		serverURL:  strings.TrimRight(serverURL, "/"),
+		authToken:  authToken,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send publishes msg to userRef, the ntfy topic the user subscribed their
+// device to.
+func (n *NtfyNotifier) Send(ctx context.Context, userRef string, msg Message) (MessageRef, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.serverURL+"/"+userRef, strings.NewReader(msg.Text))
+	if err != nil {
+		return "", fmt.Errorf("notifier: build ntfy request: %w", err)
+	}
+	if n.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+n.authToken)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("notifier: ntfy request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("notifier: ntfy returned %d: %s", resp.StatusCode, body)
+	}
+	// ntfy assigns the message an ID, but there's no endpoint to act on it
+	// later (see Edit/Delete), so there's nothing worth keeping as a ref.
+	return "", nil
+}
+
+func (n *NtfyNotifier) Edit(ctx context.Context, userRef string, ref MessageRef, msg Message) error {
+	return ErrEditNotSupported
+}
+
+func (n *NtfyNotifier) Delete(ctx context.Context, userRef string, ref MessageRef) error {
+	return ErrDeleteNotSupported
+}
@@ -0,0 +1,36 @@
+package notifier
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"shop-bot/internal/config"
+)
+
+// For resolves the Notifier backend for channel, wiring it up from cfg and
+// the already-running Telegram bot client (there's only ever one of those;
+// Matrix and ntfy are stateless HTTP clients built fresh here). Returns an
+// error if channel needs configuration that isn't set, e.g. ChannelMatrix
+// without MatrixHomeserverURL.
+func For(channel Channel, cfg *config.Config, bot *tgbotapi.BotAPI) (Notifier, error) {
+	switch channel {
+	case ChannelTelegram, "":
+		if bot == nil {
+			return nil, fmt.Errorf("notifier: telegram bot not configured")
+		}
+		return NewTelegramNotifier(bot), nil
+	case ChannelMatrix:
+		if cfg.MatrixHomeserverURL == "" || cfg.MatrixAccessToken == "" {
+			return nil, fmt.Errorf("notifier: matrix channel not configured")
+		}
+		return NewMatrixNotifier(cfg.MatrixHomeserverURL, cfg.MatrixAccessToken), nil
+	case ChannelNtfy:
+		if cfg.NtfyServerURL == "" {
+			return nil, fmt.Errorf("notifier: ntfy channel not configured")
+		}
+		return NewNtfyNotifier(cfg.NtfyServerURL, cfg.NtfyAuthToken), nil
+	default:
+		return nil, fmt.Errorf("notifier: unknown channel %q", channel)
+	}
+}
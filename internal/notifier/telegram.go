@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramNotifier sends through an already-configured bot API client -
+// it doesn't own the client's lifecycle, the caller (httpadmin.Server,
+// bot.Bot) does.
+type TelegramNotifier struct {
+	api *tgbotapi.BotAPI
+}
+
+// NewTelegramNotifier wraps api for use as a Notifier.
+func NewTelegramNotifier(api *tgbotapi.BotAPI) *TelegramNotifier {
+	return &TelegramNotifier{api: api}
+}
+
+func (n *TelegramNotifier) Send(ctx context.Context, userRef string, msg Message) (MessageRef, error) {
+	chatID, err := strconv.ParseInt(userRef, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("notifier: invalid telegram chat id %q: %w", userRef, err)
+	}
+	tgMsg := tgbotapi.NewMessage(chatID, msg.Text)
+	tgMsg.ParseMode = msg.ParseMode
+	resp, err := n.api.Send(tgMsg)
+	if err != nil {
+		return "", err
+	}
+	return MessageRef(strconv.Itoa(resp.MessageID)), nil
+}
+
+func (n *TelegramNotifier) Edit(ctx context.Context, userRef string, ref MessageRef, msg Message) error {
+	chatID, err := strconv.ParseInt(userRef, 10, 64)
+	if err != nil {
+		return fmt.Errorf("notifier: invalid telegram chat id %q: %w", userRef, err)
+	}
+	messageID, err := strconv.Atoi(string(ref))
+	if err != nil {
+		return fmt.Errorf("notifier: invalid telegram message ref %q: %w", ref, err)
+	}
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, msg.Text)
+	edit.ParseMode = msg.ParseMode
+	_, err = n.api.Send(edit)
+	return err
+}
+
+func (n *TelegramNotifier) Delete(ctx context.Context, userRef string, ref MessageRef) error {
+	chatID, err := strconv.ParseInt(userRef, 10, 64)
+	if err != nil {
+		return fmt.Errorf("notifier: invalid telegram chat id %q: %w", userRef, err)
+	}
+	messageID, err := strconv.Atoi(string(ref))
+	if err != nil {
+		return fmt.Errorf("notifier: invalid telegram message ref %q: %w", ref, err)
+	}
+	_, err = n.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+	return err
+}
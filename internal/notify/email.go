@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+)
+
+// EmailConfig holds the outbound SMTP settings EmailNotifier sends through.
+type EmailConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// EmailNotifier delivers messages as plain-text emails over SMTP. It's the
+// automatic fallback when a Telegram send fails (e.g. the user blocked the
+// bot), so a ticket reply still reaches a churned-off-Telegram customer.
+type EmailNotifier struct {
+	cfg EmailConfig
+}
+
+func NewEmailNotifier(cfg EmailConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg}
+}
+
+func (n *EmailNotifier) Channel() string { return "email" }
+
+func (n *EmailNotifier) Send(ctx context.Context, user UserRef, msg Message) (MessageRef, error) {
+	if user.Target == "" {
+		return MessageRef{}, fmt.Errorf("email notifier: no address for user")
+	}
+
+	body := fmt.Sprintf("To: %s\r\nFrom: %s\r\nSubject: Support ticket reply\r\n\r\n%s\r\n",
+		user.Target, n.cfg.From, msg.Text)
+
+	addr := n.cfg.Host + ":" + n.cfg.Port
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	if err := smtp.SendMail(addr, auth, n.cfg.From, []string{user.Target}, []byte(body)); err != nil {
+		return MessageRef{}, fmt.Errorf("email send: %w", err)
+	}
+	return MessageRef{Channel: n.Channel(), ID: user.Target}, nil
+}
+
+// Edit is a no-op: sent email can't be edited in place, so a later edit to
+// the original message is simply not propagated over this channel.
+func (n *EmailNotifier) Edit(ctx context.Context, ref MessageRef, msg Message) error {
+	return nil
+}
+
+// Delete is a no-op for the same reason as Edit.
+func (n *EmailNotifier) Delete(ctx context.Context, ref MessageRef) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+// Package notify delivers rendered ticket messages to a user over whichever
+// channel they're reachable on - Telegram, email, Slack, or a generic
+// webhook - behind one Notifier interface, so a reply fans out (or falls
+// back) without the caller caring which transport actually carries it.
+package notify
+
+import "context"
+
+// UserRef identifies who a notification is for. Which fields matter depends
+// on the Notifier: TelegramNotifier only reads TelegramID, EmailNotifier
+// only reads Target (an address), etc.
+type UserRef struct {
+	TelegramID int64
+	Target     string
+}
+
+// Message is a rendered notification, channel-agnostic. Notifiers that can't
+// render HTML (email, Slack) fall back to Text.
+type Message struct {
+	Text string
+	HTML string
+}
+
+// MessageRef is what Send returns, opaque outside the Notifier that issued
+// it except for being passed back to Edit/Delete.
+type MessageRef struct {
+	Channel string
+	ID      string
+}
+
+// Notifier delivers Messages to a UserRef over one channel.
+type Notifier interface {
+	// Channel identifies this notifier for registry lookups and for tagging
+	// the MessageRefs it returns (e.g. "telegram", "email", "slack", "webhook").
+	Channel() string
+	Send(ctx context.Context, user UserRef, msg Message) (MessageRef, error)
+	Edit(ctx context.Context, ref MessageRef, msg Message) error
+	Delete(ctx context.Context, ref MessageRef) error
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramNotifier delivers messages as direct Telegram DMs. It's always
+// registered, since every ticket owner is reachable by Telegram ID by
+// definition - the other notifiers are opt-in, per-user fallbacks.
+type TelegramNotifier struct {
+	api *tgbotapi.BotAPI
+}
+
+func NewTelegramNotifier(api *tgbotapi.BotAPI) *TelegramNotifier {
+	return &TelegramNotifier{api: api}
+}
+
+func (n *TelegramNotifier) Channel() string { return "telegram" }
+
+// telegramRefID packs the chat and message ID into one opaque string, since
+// MessageRef carries only a single ID field but editing/deleting a Telegram
+// message needs both.
+func telegramRefID(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d", chatID, messageID)
+}
+
+func parseTelegramRefID(id string) (chatID int64, messageID int, err error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid telegram message ref %q", id)
+	}
+	chatID, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid telegram message ref %q: %w", id, err)
+	}
+	messageID, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid telegram message ref %q: %w", id, err)
+	}
+	return chatID, messageID, nil
+}
+
+func (n *TelegramNotifier) Send(ctx context.Context, user UserRef, msg Message) (MessageRef, error) {
+	sent, err := n.api.Send(tgbotapi.NewMessage(user.TelegramID, msg.Text))
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("telegram send: %w", err)
+	}
+	return MessageRef{Channel: n.Channel(), ID: telegramRefID(sent.Chat.ID, sent.MessageID)}, nil
+}
+
+func (n *TelegramNotifier) Edit(ctx context.Context, ref MessageRef, msg Message) error {
+	chatID, messageID, err := parseTelegramRefID(ref.ID)
+	if err != nil {
+		return err
+	}
+	_, err = n.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, msg.Text))
+	if err != nil {
+		return fmt.Errorf("telegram edit: %w", err)
+	}
+	return nil
+}
+
+func (n *TelegramNotifier) Delete(ctx context.Context, ref MessageRef) error {
+	chatID, messageID, err := parseTelegramRefID(ref.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := n.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID)); err != nil {
+		return fmt.Errorf("telegram delete: %w", err)
+	}
+	return nil
+}
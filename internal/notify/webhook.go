@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// httpNotifyTimeout bounds how long a webhook/Slack POST can take before
+// it's treated as a failed delivery, so one slow or hung endpoint can't
+// stall a ticket reply fan-out.
+const httpNotifyTimeout = 10 * time.Second
+
+// WebhookNotifier POSTs a JSON payload to a per-user webhook URL (stored as
+// UserRef.Target). It has no concept of editing/deleting a prior delivery,
+// so those are no-ops.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{client: &http.Client{Timeout: httpNotifyTimeout}}
+}
+
+func (n *WebhookNotifier) Channel() string { return "webhook" }
+
+func (n *WebhookNotifier) Send(ctx context.Context, user UserRef, msg Message) (MessageRef, error) {
+	if user.Target == "" {
+		return MessageRef{}, fmt.Errorf("webhook notifier: no URL for user")
+	}
+
+	body, err := json.Marshal(map[string]string{"text": msg.Text, "html": msg.HTML})
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("webhook notifier: encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, user.Target, bytes.NewReader(body))
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("webhook notifier: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("webhook notifier: request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return MessageRef{}, fmt.Errorf("webhook notifier: unexpected status %d", resp.StatusCode)
+	}
+
+	return MessageRef{Channel: n.Channel(), ID: user.Target}, nil
+}
+
+func (n *WebhookNotifier) Edit(ctx context.Context, ref MessageRef, msg Message) error { return nil }
+func (n *WebhookNotifier) Delete(ctx context.Context, ref MessageRef) error            { return nil }
+
+// SlackNotifier posts to a per-user Slack incoming-webhook URL (stored as
+// UserRef.Target), using the same payload shape Slack's incoming webhooks
+// expect ({"text": ...}).
+type SlackNotifier struct {
+	client *http.Client
+}
+
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{client: &http.Client{Timeout: httpNotifyTimeout}}
+}
+
+func (n *SlackNotifier) Channel() string { return "slack" }
+
+func (n *SlackNotifier) Send(ctx context.Context, user UserRef, msg Message) (MessageRef, error) {
+	if user.Target == "" {
+		return MessageRef{}, fmt.Errorf("slack notifier: no webhook URL for user")
+	}
+
+	body, err := json.Marshal(map[string]string{"text": msg.Text})
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("slack notifier: encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, user.Target, bytes.NewReader(body))
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("slack notifier: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return MessageRef{}, fmt.Errorf("slack notifier: request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return MessageRef{}, fmt.Errorf("slack notifier: unexpected status %d", resp.StatusCode)
+	}
+
+	return MessageRef{Channel: n.Channel(), ID: user.Target}, nil
+}
+
+func (n *SlackNotifier) Edit(ctx context.Context, ref MessageRef, msg Message) error { return nil }
+func (n *SlackNotifier) Delete(ctx context.Context, ref MessageRef) error           { return nil }
@@ -0,0 +1,92 @@
+// Package epay is a client for 易支付-style (EPay) payment aggregators: a
+// merchant submits an MD5-signed set of order parameters as a query string
+// to the gateway's hosted submit page, and the gateway later POSTs an
+// MD5-signed async notification back to NotifyURL once the buyer pays.
+package epay
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Client talks to a single epay-compatible gateway, identified by merchant
+// PID and signing key.
+type Client struct {
+	pid     string
+	key     string
+	gateway string
+}
+
+// NewClient returns a Client for the gateway at gatewayURL, authenticating
+// as merchant pid and signing requests with key.
+func NewClient(pid, key, gatewayURL string) *Client {
+	return &Client{pid: pid, key: key, gateway: strings.TrimRight(gatewayURL, "/")}
+}
+
+// CreateOrderParams describes one payment order to submit to the gateway.
+type CreateOrderParams struct {
+	OutTradeNo string
+	Name       string
+	Money      float64
+	NotifyURL  string
+	ReturnURL  string
+	Param      string
+}
+
+// CreateSubmitURL returns the URL that redirects the user to the gateway's
+// hosted payment page for params, signed with the client's key.
+func (c *Client) CreateSubmitURL(params CreateOrderParams) string {
+	values := url.Values{
+		"pid":          {c.pid},
+		"type":         {"alipay"},
+		"out_trade_no": {params.OutTradeNo},
+		"notify_url":   {params.NotifyURL},
+		"return_url":   {params.ReturnURL},
+		"name":         {params.Name},
+		"money":        {fmt.Sprintf("%.2f", params.Money)},
+		"param":        {params.Param},
+	}
+	values.Set("sign", c.sign(values))
+	values.Set("sign_type", "MD5")
+	return fmt.Sprintf("%s/submit.php?%s", c.gateway, values.Encode())
+}
+
+// VerifyNotification reports whether values carries a valid sign for the
+// client's key, as found in the gateway's async notify callback.
+func (c *Client) VerifyNotification(values url.Values) bool {
+	sign := values.Get("sign")
+	if sign == "" {
+		return false
+	}
+	return sign == c.sign(values)
+}
+
+// sign computes the epay MD5 signature: every non-empty param except sign
+// and sign_type, sorted by key, joined as "k=v&...", with the signing key
+// appended, then MD5-hex-encoded.
+func (c *Client) sign(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k == "sign" || k == "sign_type" || values.Get(k) == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(values.Get(k))
+		b.WriteByte('&')
+	}
+	b.WriteString(c.key)
+
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
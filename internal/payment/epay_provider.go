@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"shop-bot/internal/money"
+	"shop-bot/internal/payment/epay"
+)
+
+// EpayProvider adapts an epay.Client to the Provider interface.
+type EpayProvider struct {
+	client *epay.Client
+}
+
+// NewEpayProvider wraps client as a Provider.
+func NewEpayProvider(client *epay.Client) *EpayProvider {
+	return &EpayProvider{client: client}
+}
+
+func (p *EpayProvider) Name() string { return "epay" }
+
+// CreateOrder submits params to the epay gateway and returns its hosted
+// payment page URL. The out_trade_no doubles as the provider reference,
+// since that's what the gateway's async notification carries back.
+func (p *EpayProvider) CreateOrder(ctx context.Context, params OrderParams) (string, string, error) {
+	payURL := p.client.CreateSubmitURL(epay.CreateOrderParams{
+		OutTradeNo: params.OutTradeNo,
+		Name:       params.Name,
+		Money:      float64(params.AmountCents) / 100,
+		NotifyURL:  params.NotifyURL,
+		ReturnURL:  params.ReturnURL,
+		Param:      params.Param,
+	})
+	return payURL, params.OutTradeNo, nil
+}
+
+// Refund always fails: epay's submit-page protocol has no refund API, so
+// epay orders have to be refunded manually outside the shop.
+func (p *EpayProvider) Refund(ctx context.Context, outTradeNo string, amountCents int) (string, error) {
+	return "", fmt.Errorf("epay does not support programmatic refunds")
+}
+
+func (p *EpayProvider) VerifyNotification(raw map[string]string) (NotificationResult, bool) {
+	values := make(url.Values, len(raw))
+	for k, v := range raw {
+		values.Set(k, v)
+	}
+	if !p.client.VerifyNotification(values) {
+		return NotificationResult{}, false
+	}
+
+	amountCents := 0
+	if amount, err := money.ParseAmount(values.Get("money")); err == nil {
+		amountCents = money.ToCents(amount)
+	}
+
+	return NotificationResult{
+		OutTradeNo:  values.Get("out_trade_no"),
+		AmountCents: amountCents,
+		GatewayTxNo: values.Get("trade_no"),
+	}, true
+}
@@ -0,0 +1,59 @@
+// Package payment abstracts the bot's checkout over multiple payment
+// gateways (epay's hosted redirect page, a crypto exchange invoice, ...) so
+// handleBuyCallback and the deposit flow can offer every configured method
+// as its own "Pay Now" button instead of hard-wiring a single gateway.
+package payment
+
+import "context"
+
+// OrderParams describes one payment order, independent of which Provider
+// ultimately fulfills it. AmountCents is the amount to charge in the
+// shop's own currency and smallest unit (cents); a Provider that settles in
+// something else (crypto) converts it internally.
+type OrderParams struct {
+	OutTradeNo string
+	Name       string
+	AmountCents int
+	NotifyURL  string
+	ReturnURL  string
+	Param      string
+}
+
+// NotificationResult is what a Provider extracts from an inbound payment
+// notification once its signature/authenticity has checked out, so the
+// caller has everything store.SettleOrderTx needs (out_trade_no, the
+// amount the gateway says it collected, and the gateway's own reference)
+// without having to know each gateway's wire format.
+type NotificationResult struct {
+	OutTradeNo  string
+	AmountCents int
+	GatewayTxNo string
+}
+
+// Provider is a single payment gateway the bot can present to a buyer.
+type Provider interface {
+	// Name is the provider's short identifier (e.g. "epay", "crypto"),
+	// used to label its "Pay Now" button and to attribute an inbound
+	// notification to the provider that should verify it.
+	Name() string
+
+	// CreateOrder starts a payment for params, returning the URL to send
+	// the buyer to and a provider-specific reference for reconciling the
+	// eventual notification against this order.
+	CreateOrder(ctx context.Context, params OrderParams) (payURL string, providerRef string, err error)
+
+	// VerifyNotification checks an inbound payment notification's
+	// authenticity, and if it's valid and reports a successful payment,
+	// returns the details of what was paid. paid is false both when the
+	// signature doesn't check out and when it checks out but the gateway is
+	// reporting something other than success (e.g. a pending or failed
+	// trade state) - either way there's nothing for the caller to settle.
+	VerifyNotification(raw map[string]string) (result NotificationResult, paid bool)
+
+	// Refund asks the gateway to return amountCents of a previously settled
+	// order (identified by its out_trade_no) to the buyer, returning the
+	// gateway's own refund reference. A provider with no refund API returns
+	// a non-nil error so the caller can surface it to the admin instead of
+	// silently marking the order refunded with nothing actually sent back.
+	Refund(ctx context.Context, outTradeNo string, amountCents int) (refundRef string, err error)
+}
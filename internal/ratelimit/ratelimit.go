@@ -0,0 +1,115 @@
+// Package ratelimit provides small in-memory primitives for throttling
+// per-user activity: a token-bucket Limiter for steady-rate flood control,
+// and an IdempotencyCache for collapsing near-duplicate actions (e.g. a
+// fast double tap on a callback button) into one.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a single token bucket: it holds up to capacity tokens, refilling
+// at refillPerSec tokens/second, and is not safe for concurrent use on its
+// own (Limiter guards access with its own mutex).
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// Limiter hands out a token bucket per key (typically a Telegram user ID),
+// so each user is throttled independently under one shared capacity/refill
+// rate.
+type Limiter struct {
+	mu           sync.Mutex
+	buckets      map[string]*bucket
+	capacity     float64
+	refillPerSec float64
+}
+
+// NewLimiter returns a Limiter where each key may burst up to capacity
+// requests before being throttled, refilling at refillPerSec tokens/second.
+func NewLimiter(capacity float64, refillPerSec float64) *Limiter {
+	return &Limiter{
+		buckets:      make(map[string]*bucket),
+		capacity:     capacity,
+		refillPerSec: refillPerSec,
+	}
+}
+
+// Allow reports whether key may proceed now, consuming one token if so.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	now := time.Now()
+	if !ok {
+		b = &bucket{tokens: l.capacity - 1, lastRefill: now}
+		l.buckets[key] = b
+		return true
+	}
+
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.tokens = min(l.capacity, b.tokens+elapsed*l.refillPerSec)
+	b.lastRefill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func min(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// IdempotencyCache remembers keys for a short window so a caller can tell a
+// genuinely new action from a duplicate (e.g. the same button clicked twice
+// before the first click's response arrives).
+type IdempotencyCache struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	ttl    time.Duration
+	lastGC time.Time
+}
+
+// NewIdempotencyCache returns an IdempotencyCache that remembers each
+// claimed key for ttl.
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{seen: make(map[string]time.Time), ttl: ttl}
+}
+
+// Claim reports whether key is new (not claimed within ttl). A duplicate
+// claim within the window returns false and does not extend the window.
+func (c *IdempotencyCache) Claim(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.gc(now)
+
+	if expiresAt, ok := c.seen[key]; ok && now.Before(expiresAt) {
+		return false
+	}
+	c.seen[key] = now.Add(c.ttl)
+	return true
+}
+
+// gc drops expired entries, at most once per ttl, so the map doesn't grow
+// unbounded under sustained traffic.
+func (c *IdempotencyCache) gc(now time.Time) {
+	if now.Sub(c.lastGC) < c.ttl {
+		return
+	}
+	c.lastGC = now
+	for key, expiresAt := range c.seen {
+		if now.After(expiresAt) {
+			delete(c.seen, key)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package router parses admin-facing ticket commands (/reply, /close,
+// /assign, /list, /search) and ranks fuzzy ticket search results, so admins
+// can act on a ticket without reply-quoting its notification message. It
+// stays free of shop-bot/internal/bot and shop-bot/internal/store so the bot
+// package can wire it up without an import cycle; callers translate its
+// ParsedCommand/TicketCandidate types into actual store lookups.
+package router
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sahilm/fuzzy"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Kind identifies which admin command a message parsed as.
+type Kind string
+
+const (
+	KindReply  Kind = "reply"
+	KindClose  Kind = "close"
+	KindAssign Kind = "assign"
+	KindList   Kind = "list"
+	KindSearch Kind = "search"
+)
+
+// ParsedCommand is the structured result of parsing an admin command
+// message, ready for the caller to resolve TicketNumber against storage.
+type ParsedCommand struct {
+	Kind         Kind
+	TicketNumber string
+	// Admin is the "@username" argument to /assign, without the "@".
+	Admin string
+	// Query is the free-text argument to /list and /search (e.g. "open", or
+	// the search terms).
+	Query string
+	// Body is the remaining text after the ticket number for /reply, i.e.
+	// the message to relay to the buyer.
+	Body string
+}
+
+// Parse recognizes the admin command a message contains, returning ok=false
+// for anything else (including a plain reply-to-notification message, which
+// the caller should keep handling the existing way).
+func Parse(message *tgbotapi.Message) (*ParsedCommand, bool) {
+	if message == nil || !message.IsCommand() {
+		return nil, false
+	}
+
+	args := strings.TrimSpace(message.CommandArguments())
+
+	switch message.Command() {
+	case "reply":
+		ticketNumber, body, ok := splitFirstField(args)
+		if !ok {
+			return nil, false
+		}
+		return &ParsedCommand{Kind: KindReply, TicketNumber: ticketNumber, Body: body}, true
+
+	case "close":
+		ticketNumber := strings.TrimSpace(args)
+		if ticketNumber == "" {
+			return nil, false
+		}
+		return &ParsedCommand{Kind: KindClose, TicketNumber: ticketNumber}, true
+
+	case "assign":
+		ticketNumber, admin, ok := splitFirstField(args)
+		if !ok {
+			return nil, false
+		}
+		return &ParsedCommand{Kind: KindAssign, TicketNumber: ticketNumber, Admin: strings.TrimPrefix(admin, "@")}, true
+
+	case "list":
+		return &ParsedCommand{Kind: KindList, Query: strings.TrimSpace(args)}, true
+
+	case "search":
+		query := strings.TrimSpace(args)
+		if query == "" {
+			return nil, false
+		}
+		return &ParsedCommand{Kind: KindSearch, Query: query}, true
+	}
+
+	return nil, false
+}
+
+// splitFirstField splits "TK-20260101-001 rest of the text" into its first
+// whitespace-delimited field and the remainder, trimmed.
+func splitFirstField(s string) (first, rest string, ok bool) {
+	if s == "" {
+		return "", "", false
+	}
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, "", true
+	}
+	return s[:i], strings.TrimSpace(s[i:]), true
+}
+
+// TicketCandidate is the subset of a ticket the fuzzy search ranks against
+// and renders into a result row.
+type TicketCandidate struct {
+	ID          uint
+	Number      string
+	Subject     string
+	Username    string
+	LastMessage string
+}
+
+// source is what fuzzy actually matches against: subject, user handle, and
+// last message concatenated, so "/search refund john" matches on any of them.
+func (c TicketCandidate) source() string {
+	return c.Subject + " " + c.Username + " " + c.LastMessage
+}
+
+// Search ranks candidates against query using fuzzy subsequence matching,
+// best match first.
+func Search(query string, candidates []TicketCandidate) []TicketCandidate {
+	sources := make([]string, len(candidates))
+	for i, c := range candidates {
+		sources[i] = c.source()
+	}
+
+	matches := fuzzy.Find(query, sources)
+	ranked := make([]TicketCandidate, len(matches))
+	for i, m := range matches {
+		ranked[i] = candidates[m.Index]
+	}
+	return ranked
+}
+
+// ResultKeyboard renders a ticket search/list result as one inline-keyboard
+// row per ticket (Reply, Close, View), with CallbackData carrying the
+// ticket's ID so the bot can act on it directly.
+func ResultKeyboard(candidates []TicketCandidate) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(candidates))
+	for _, c := range candidates {
+		id := strconv.FormatUint(uint64(c.ID), 10)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Reply "+c.Number, "router_reply:"+id),
+			tgbotapi.NewInlineKeyboardButtonData("Close", "router_close:"+id),
+			tgbotapi.NewInlineKeyboardButtonData("View", "router_view:"+id),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
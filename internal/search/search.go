@@ -0,0 +1,142 @@
+// Package search provides lightweight fuzzy subsequence matching over an
+// in-memory index, used for product/command lookup in the bot and catalog
+// search in the admin panel.
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Item is a single searchable entry (a product name, a SKU, a message key, ...).
+type Item struct {
+	ID   uint
+	Kind string
+	Text string
+}
+
+// Result is a scored match with the rune-index ranges [start, end) in Text
+// that matched the query, suitable for highlighting.
+type Result struct {
+	Item   Item
+	Score  int
+	Ranges [][2]int
+}
+
+// Index is a rebuildable, concurrency-safe collection of Items.
+type Index struct {
+	mu    sync.RWMutex
+	items []Item
+}
+
+// NewIndex returns an empty index; call Build to populate it.
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Build replaces the index contents atomically, so readers never see a
+// partially-rebuilt index. Call this on startup and after product mutations.
+func (idx *Index) Build(items []Item) {
+	idx.mu.Lock()
+	idx.items = items
+	idx.mu.Unlock()
+}
+
+// Search returns up to limit items ranked by fuzzy score against query,
+// highest score first. Non-matching items are excluded entirely.
+func (idx *Index) Search(query string, limit int) []Result {
+	idx.mu.RLock()
+	items := idx.items
+	idx.mu.RUnlock()
+
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	results := make([]Result, 0, len(items))
+	for _, item := range items {
+		score, ranges, ok := Match(query, item.Text)
+		if !ok {
+			continue
+		}
+		results = append(results, Result{Item: item, Score: score, Ranges: ranges})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Match scores target against query as a case-insensitive ordered subsequence
+// match (Smith-Waterman-style): consecutive matches and word/camel-case
+// boundary matches are bonused, gaps between matches are penalized. Returns
+// ok=false when query isn't a subsequence of target at all.
+func Match(query, target string) (score int, ranges [][2]int, ok bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(target))
+
+	if len(q) == 0 {
+		return 0, nil, false
+	}
+
+	qi := 0
+	lastMatch := -2 // far enough back that the first match gets no consecutive bonus
+	var rangeStart = -1
+
+	flushRange := func(end int) {
+		if rangeStart >= 0 {
+			ranges = append(ranges, [2]int{rangeStart, end})
+			rangeStart = -1
+		}
+	}
+
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			flushRange(ti)
+			continue
+		}
+
+		points := 1
+		if ti == lastMatch+1 {
+			points += 3 // consecutive run bonus
+		}
+		if ti == 0 || isBoundary(t[ti-1]) {
+			points += 2 // start-of-word / camelCase boundary bonus
+		}
+		if ti > lastMatch+1 && lastMatch >= 0 {
+			points -= min(ti-lastMatch-1, 3) // gap penalty, capped so long strings aren't crushed
+		}
+
+		score += points
+		if rangeStart < 0 {
+			rangeStart = ti
+		}
+		lastMatch = ti
+		qi++
+	}
+	flushRange(len(t))
+
+	if qi < len(q) {
+		return 0, nil, false
+	}
+
+	// Prefer shorter targets among equal matches (tighter match).
+	score -= len(t) / 20
+
+	return score, ranges, true
+}
+
+func isBoundary(r rune) bool {
+	return r == ' ' || r == '-' || r == '_' || r == '.' || r == '/'
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,296 @@
+// Package secrets implements KMS-style envelope encryption for values that
+// would otherwise be written to the database in plaintext (bot/admin
+// tokens, payment gateway keys). Each secret gets its own randomly
+// generated data-encryption key (DEK); the DEK is wrapped with a single
+// master key so rotating the master key only means re-wrapping DEKs, not
+// re-encrypting every stored value.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// envelopePrefix marks a value as an encrypted envelope so Decrypt can tell
+// it apart from a plaintext value written before this package existed (or
+// by a deployment that never set MASTER_KEY).
+const envelopePrefix = "enc:v1:"
+
+// envelope is the JSON payload behind envelopePrefix, base64-encoded.
+type envelope struct {
+	WrappedDEK string `json:"wrapped_dek"`
+	DEKNonce   string `json:"dek_nonce"`
+	Ciphertext string `json:"ciphertext"`
+	Nonce      string `json:"nonce"`
+}
+
+// Manager encrypts and decrypts values under a single master key.
+type Manager struct {
+	masterKey []byte
+}
+
+// NewManager loads the master key from source, which is one of:
+//   - a file path prefixed "file://" (the file's raw bytes are the key)
+//   - a HashiCorp Vault KV v2 URL prefixed "vault://" (read via Vault's
+//     HTTP API; VAULT_ADDR and VAULT_TOKEN must be set in the environment)
+//   - anything else: the key material itself, base64-decoded if it parses
+//     as base64, otherwise used as raw bytes
+//
+// A resulting key of exactly 32 bytes is used as-is; any other length is
+// SHA-256'd down to 32 bytes, so any source length works with AES-256.
+func NewManager(source string) (*Manager, error) {
+	if source == "" {
+		return nil, fmt.Errorf("secrets: master key source is empty")
+	}
+
+	raw, err := loadMasterKeyMaterial(source)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: load master key: %w", err)
+	}
+	return &Manager{masterKey: deriveKey(raw)}, nil
+}
+
+// NewManagerFromEnv is a convenience wrapper that loads the master key
+// source from the MASTER_KEY environment variable.
+func NewManagerFromEnv() (*Manager, error) {
+	return NewManager(os.Getenv("MASTER_KEY"))
+}
+
+func loadMasterKeyMaterial(source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "file://"):
+		return os.ReadFile(strings.TrimPrefix(source, "file://"))
+	case strings.HasPrefix(source, "vault://"):
+		return readVaultSecret(source)
+	default:
+		if decoded, err := base64.StdEncoding.DecodeString(source); err == nil {
+			return decoded, nil
+		}
+		return []byte(source), nil
+	}
+}
+
+// vaultKVResponse is the subset of Vault's KV v2 read response we need.
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]string `json:"data"`
+	} `json:"data"`
+}
+
+// readVaultSecret fetches a master key from Vault's KV v2 HTTP API. The URL
+// is "vault://<path>#<field>", e.g. "vault://secret/data/shop-bot#master_key";
+// the field defaults to "value" if omitted. VAULT_ADDR and VAULT_TOKEN come
+// from the environment, matching how every other external API client in
+// this repo takes its credentials.
+func readVaultSecret(source string) ([]byte, error) {
+	rest := strings.TrimPrefix(source, "vault://")
+	path, field, _ := strings.Cut(rest, "#")
+	if field == "" {
+		field = "value"
+	}
+
+	vaultAddr := os.Getenv("VAULT_ADDR")
+	vaultToken := os.Getenv("VAULT_TOKEN")
+	if vaultAddr == "" || vaultToken == "" {
+		return nil, fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set to resolve a vault:// master key")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(vaultAddr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", vaultToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault request: unexpected status %d", resp.StatusCode)
+	}
+
+	var parsed vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("decode vault response: %w", err)
+	}
+	value, ok := parsed.Data.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("vault secret has no field %q", field)
+	}
+	return []byte(value), nil
+}
+
+// IsEncrypted reports whether value is an envelope produced by Encrypt,
+// as opposed to a plaintext value written before encryption was enabled.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// Encrypt wraps plaintext in a freshly generated DEK, itself wrapped under
+// the manager's master key, and returns the envelope as a string safe to
+// store in a single text column.
+func (m *Manager) Encrypt(plaintext string) (string, error) {
+	dek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", fmt.Errorf("secrets: generate dek: %w", err)
+	}
+
+	ciphertext, nonce, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("secrets: encrypt value: %w", err)
+	}
+	wrappedDEK, dekNonce, err := seal(m.masterKey, dek)
+	if err != nil {
+		return "", fmt.Errorf("secrets: wrap dek: %w", err)
+	}
+
+	env := envelope{
+		WrappedDEK: base64.StdEncoding.EncodeToString(wrappedDEK),
+		DEKNonce:   base64.StdEncoding.EncodeToString(dekNonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+	}
+	encoded, err := json.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("secrets: marshal envelope: %w", err)
+	}
+	return envelopePrefix + base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+// Decrypt unwraps an envelope produced by Encrypt. Values that aren't
+// envelopes (no envelopePrefix) are returned unchanged, so rows written
+// before encryption was enabled keep working.
+func (m *Manager) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	encoded := strings.TrimPrefix(value, envelopePrefix)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decode envelope: %w", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return "", fmt.Errorf("secrets: unmarshal envelope: %w", err)
+	}
+
+	dek, err := m.unwrapDEK(env)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(env.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decode ciphertext: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(env.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decode nonce: %w", err)
+	}
+	plaintext, err := open(dek, ciphertext, nonce)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (m *Manager) unwrapDEK(env envelope) ([]byte, error) {
+	wrappedDEK, err := base64.StdEncoding.DecodeString(env.WrappedDEK)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: decode wrapped dek: %w", err)
+	}
+	dekNonce, err := base64.StdEncoding.DecodeString(env.DEKNonce)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: decode dek nonce: %w", err)
+	}
+	dek, err := open(m.masterKey, wrappedDEK, dekNonce)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: unwrap dek: %w", err)
+	}
+	return dek, nil
+}
+
+// Rewrap re-encrypts value's DEK under newManager's master key without
+// touching the ciphertext or the DEK itself, so rotation never re-encrypts
+// the bulk of stored data - only the (much smaller) wrapped key.
+func (m *Manager) Rewrap(value string, newManager *Manager) (string, error) {
+	if !IsEncrypted(value) {
+		// A never-encrypted legacy value: encrypt it now under the new key
+		// so rotation also finishes migrating anything left in plaintext.
+		return newManager.Encrypt(value)
+	}
+
+	encoded := strings.TrimPrefix(value, envelopePrefix)
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("secrets: decode envelope: %w", err)
+	}
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return "", fmt.Errorf("secrets: unmarshal envelope: %w", err)
+	}
+
+	dek, err := m.unwrapDEK(env)
+	if err != nil {
+		return "", err
+	}
+	wrappedDEK, dekNonce, err := seal(newManager.masterKey, dek)
+	if err != nil {
+		return "", fmt.Errorf("secrets: rewrap dek: %w", err)
+	}
+	env.WrappedDEK = base64.StdEncoding.EncodeToString(wrappedDEK)
+	env.DEKNonce = base64.StdEncoding.EncodeToString(dekNonce)
+
+	encodedEnv, err := json.Marshal(env)
+	if err != nil {
+		return "", fmt.Errorf("secrets: marshal envelope: %w", err)
+	}
+	return envelopePrefix + base64.StdEncoding.EncodeToString(encodedEnv), nil
+}
+
+func deriveKey(material []byte) []byte {
+	if len(material) == 32 {
+		return material
+	}
+	sum := sha256.Sum256(material)
+	return sum[:]
+}
+
+func seal(key, plaintext []byte) (ciphertext, nonce []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, nil, err
+	}
+	return gcm.Seal(nil, nonce, plaintext, nil), nonce, nil
+}
+
+func open(key, ciphertext, nonce []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminUser is one human operator of the admin dashboard and the Telegram
+// admin commands in internal/bot. Password holds a bcrypt hash (the field
+// predates this file and is named Password, not PasswordHash, because
+// existing callers already construct AdminUser{Password: ...} that way).
+// TelegramID links the dashboard account to its Telegram admin identity;
+// it's optional since a dashboard-only account doesn't need one.
+type AdminUser struct {
+	ID                   uint `gorm:"primaryKey"`
+	Username             string `gorm:"uniqueIndex"`
+	Password             string
+	Role                 string
+	TelegramID           *int64
+	ReceiveNotifications bool
+	IsActive             bool
+	LastLoginAt          *time.Time
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (AdminUser) TableName() string { return "admin_users" }
+
+// Permission is one bit of a Role's permission bitmask.
+type Permission uint64
+
+const (
+	PermProductsWrite Permission = 1 << iota
+	PermOrdersRead
+	PermOrdersWrite
+	PermTicketsRead
+	PermTicketsReply
+	PermUsersManage
+	PermSettingsWrite
+	PermBroadcastSend
+	PermAdminsManage
+)
+
+// permissionKeys maps the dotted keys RequirePermission is called with
+// (e.g. "orders.read") to a single bit.
+var permissionKeys = map[string]Permission{
+	"products.write": PermProductsWrite,
+	"orders.read":    PermOrdersRead,
+	"orders.write":   PermOrdersWrite,
+	"tickets.read":   PermTicketsRead,
+	"tickets.reply":  PermTicketsReply,
+	"users.manage":   PermUsersManage,
+	"settings.write": PermSettingsWrite,
+	"broadcast.send": PermBroadcastSend,
+	"admins.manage":  PermAdminsManage,
+}
+
+// permissionGroups maps a "<category>.*" key to every bit in that
+// category, so a route can require e.g. "tickets.*" instead of enumerating
+// tickets.read and tickets.reply separately.
+var permissionGroups = map[string]Permission{
+	"products.*":  PermProductsWrite,
+	"orders.*":    PermOrdersRead | PermOrdersWrite,
+	"tickets.*":   PermTicketsRead | PermTicketsReply,
+	"settings.*":  PermSettingsWrite,
+	"broadcast.*": PermBroadcastSend,
+	"admins.*":    PermAdminsManage,
+}
+
+// PermissionByKey resolves a dotted permission key to its bitmask, looking
+// in permissionKeys first and falling back to a permissionGroups wildcard.
+// ok is false for an unrecognized key.
+func PermissionByKey(key string) (Permission, bool) {
+	if p, ok := permissionKeys[key]; ok {
+		return p, true
+	}
+	p, ok := permissionGroups[key]
+	return p, ok
+}
+
+// Role is a named, reusable set of permissions assigned to an AdminUser via
+// its Role field. Role stays a plain string on AdminUser (rather than a
+// foreign key) so existing code comparing it to a literal like
+// "superadmin" keeps working unchanged; this table is what gives that
+// string a concrete, editable meaning.
+type Role struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex"`
+	Permissions Permission
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (Role) TableName() string { return "roles" }
+
+// Has reports whether r grants every bit set in p.
+func (r Role) Has(p Permission) bool {
+	return r.Permissions&p == p
+}
+
+// defaultRoles seeds the roles every deployment needs out of the box:
+// superadmin (everything, including managing other admin accounts), admin
+// (day-to-day operations, no admin-account management), and support
+// (read-only plus ticket replies, for agents who shouldn't touch products
+// or settings).
+var defaultRoles = []Role{
+	{Name: "superadmin", Permissions: PermProductsWrite | PermOrdersRead | PermOrdersWrite |
+		PermTicketsRead | PermTicketsReply | PermUsersManage | PermSettingsWrite |
+		PermBroadcastSend | PermAdminsManage},
+	{Name: "admin", Permissions: PermProductsWrite | PermOrdersRead | PermOrdersWrite |
+		PermTicketsRead | PermTicketsReply | PermUsersManage | PermSettingsWrite | PermBroadcastSend},
+	{Name: "support", Permissions: PermOrdersRead | PermTicketsRead | PermTicketsReply},
+}
+
+// EnsureDefaultRoles inserts any of defaultRoles not already present by
+// name. Safe to call on every startup.
+func EnsureDefaultRoles(db *gorm.DB) error {
+	for _, role := range defaultRoles {
+		var existing Role
+		err := db.Where("name = ?", role.Name).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := db.Create(&role).Error; err != nil {
+				return fmt.Errorf("ensure default roles: create %s: %w", role.Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ensure default roles: load %s: %w", role.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetRoleByName returns the Role named name.
+func GetRoleByName(db *gorm.DB, name string) (*Role, error) {
+	var role Role
+	if err := db.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, fmt.Errorf("get role %q: %w", name, err)
+	}
+	return &role, nil
+}
+
+// GetAdminUserByUsername looks up an admin account for login.
+func GetAdminUserByUsername(db *gorm.DB, username string) (*AdminUser, error) {
+	var admin AdminUser
+	if err := db.Where("username = ?", username).First(&admin).Error; err != nil {
+		return nil, fmt.Errorf("get admin user %q: %w", username, err)
+	}
+	return &admin, nil
+}
+
+// GetAdminUserByTelegramID looks up an admin account by its linked Telegram
+// user id, for Telegram Login Widget SSO.
+func GetAdminUserByTelegramID(db *gorm.DB, telegramID int64) (*AdminUser, error) {
+	var admin AdminUser
+	if err := db.Where("telegram_id = ?", telegramID).First(&admin).Error; err != nil {
+		return nil, fmt.Errorf("get admin user by telegram id %d: %w", telegramID, err)
+	}
+	return &admin, nil
+}
+
+// ListAdminUsers returns every admin account, newest first.
+func ListAdminUsers(db *gorm.DB) ([]AdminUser, error) {
+	var admins []AdminUser
+	if err := db.Order("created_at DESC").Find(&admins).Error; err != nil {
+		return nil, fmt.Errorf("list admin users: %w", err)
+	}
+	return admins, nil
+}
+
+// CreateAdminUser inserts a new admin account. passwordHash must already be
+// hashed (e.g. via auth.PasswordService) - this package has no opinion on
+// hashing, only on storage.
+func CreateAdminUser(db *gorm.DB, username, passwordHash, role string, telegramID *int64) (*AdminUser, error) {
+	admin := &AdminUser{
+		Username:             username,
+		Password:             passwordHash,
+		Role:                 role,
+		TelegramID:           telegramID,
+		ReceiveNotifications: true,
+		IsActive:             true,
+	}
+	if err := db.Create(admin).Error; err != nil {
+		return nil, fmt.Errorf("create admin user: %w", err)
+	}
+	return admin, nil
+}
+
+// UpdateAdminUserRole changes id's role.
+func UpdateAdminUserRole(db *gorm.DB, id uint, role string) error {
+	if err := db.Model(&AdminUser{}).Where("id = ?", id).Update("role", role).Error; err != nil {
+		return fmt.Errorf("update admin user role: %w", err)
+	}
+	return nil
+}
+
+// SetAdminUserActive enables or disables id's account without deleting it.
+func SetAdminUserActive(db *gorm.DB, id uint, active bool) error {
+	if err := db.Model(&AdminUser{}).Where("id = ?", id).Update("is_active", active).Error; err != nil {
+		return fmt.Errorf("set admin user active: %w", err)
+	}
+	return nil
+}
+
+// RecordAdminLogin stamps id's LastLoginAt to now.
+func RecordAdminLogin(db *gorm.DB, id uint) error {
+	now := time.Now()
+	if err := db.Model(&AdminUser{}).Where("id = ?", id).Update("last_login_at", &now).Error; err != nil {
+		return fmt.Errorf("record admin login: %w", err)
+	}
+	return nil
+}
+
+// SetAdminUserTelegramID binds id's dashboard account to a Telegram chat,
+// completing the bot's /link enrollment flow (see
+// store.GetTelegramLinkToken and httpadmin's handleTelegramLink).
+func SetAdminUserTelegramID(db *gorm.DB, id uint, telegramID int64) error {
+	if err := db.Model(&AdminUser{}).Where("id = ?", id).Update("telegram_id", telegramID).Error; err != nil {
+		return fmt.Errorf("set admin user telegram id: %w", err)
+	}
+	return nil
+}
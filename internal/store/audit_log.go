@@ -0,0 +1,45 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog is one forensic record of a mutating admin request: who made
+// it (AdminUserID, nil for an unauthenticated/legacy-token request),
+// what they hit, what it did to the data, and how long it took. Written
+// by httpadmin's audit-trail middleware for every /admin request whose
+// method is not GET, HEAD or OPTIONS.
+type AuditLog struct {
+	ID            uint   `gorm:"primaryKey"`
+	RequestID     string `gorm:"index"`
+	AdminUserID   *uint  `gorm:"index"`
+	Method        string
+	Path          string
+	Status        int
+	IP            string
+	UserAgent     string
+	DurationMS    int64
+	ChangedEntity string `gorm:"index"`
+	ChangedID     string
+	DiffJSON      string `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+func (AuditLog) TableName() string { return "audit_logs" }
+
+// CreateAuditLog inserts one audit trail row.
+func CreateAuditLog(db *gorm.DB, entry *AuditLog) error {
+	return db.Create(entry).Error
+}
+
+// ListAuditLogs returns audit trail rows newest-first, limit/offset paged.
+func ListAuditLogs(db *gorm.DB, limit, offset int) ([]AuditLog, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var logs []AuditLog
+	err := db.Order("created_at desc").Limit(limit).Offset(offset).Find(&logs).Error
+	return logs, err
+}
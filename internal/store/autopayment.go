@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutoPayment is a recurring charge against a user's balance: either a plain
+// top-up (ProductID nil) or a standing order to rebuy a specific product,
+// fired every PeriodSeconds until MaxRuns is reached or it's cancelled.
+type AutoPayment struct {
+	ID              uint       `gorm:"primaryKey"`
+	UserID          uint       `gorm:"not null;index"`
+	AmountCents     int        `gorm:"not null"`
+	Currency        string     `gorm:"not null"`
+	PeriodSeconds   int        `gorm:"not null"`
+	NextRunAt       time.Time  `gorm:"not null;index"`
+	LastPaymentTime *time.Time
+	ProductID       *uint
+	Enabled         bool `gorm:"not null;default:true;index"`
+	RunCount        int  `gorm:"not null;default:0"`
+	MaxRuns         *int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+// Common period presets offered by the bot's subscription menus.
+const (
+	PeriodDaily   = 24 * 60 * 60
+	PeriodWeekly  = 7 * 24 * 60 * 60
+	PeriodMonthly = 30 * 24 * 60 * 60
+)
+
+// CreateAutoPayment schedules a new recurring charge, due to first run one
+// period from now.
+func CreateAutoPayment(db *gorm.DB, userID uint, amountCents int, currency string, periodSeconds int, productID *uint, maxRuns *int) (*AutoPayment, error) {
+	autoPayment := &AutoPayment{
+		UserID:        userID,
+		AmountCents:   amountCents,
+		Currency:      currency,
+		PeriodSeconds: periodSeconds,
+		NextRunAt:     time.Now().Add(time.Duration(periodSeconds) * time.Second),
+		ProductID:     productID,
+		Enabled:       true,
+		MaxRuns:       maxRuns,
+	}
+
+	if err := db.Create(autoPayment).Error; err != nil {
+		return nil, err
+	}
+
+	return autoPayment, nil
+}
+
+// GetActiveAutoPaymentsForUser returns userID's non-cancelled subscriptions,
+// enabled or paused, for display in /subscriptions.
+func GetActiveAutoPaymentsForUser(db *gorm.DB, userID uint) ([]AutoPayment, error) {
+	var autoPayments []AutoPayment
+	err := db.Where("user_id = ?", userID).Order("id").Find(&autoPayments).Error
+	return autoPayments, err
+}
+
+// GetAutoPayment fetches one subscription owned by userID.
+func GetAutoPayment(db *gorm.DB, id uint, userID uint) (*AutoPayment, error) {
+	var autoPayment AutoPayment
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&autoPayment).Error
+	return &autoPayment, err
+}
+
+// SetAutoPaymentEnabled pauses or resumes a subscription. Pausing leaves
+// NextRunAt untouched, so a run that fell due while paused is claimed on the
+// first scheduler tick after resuming.
+func SetAutoPaymentEnabled(db *gorm.DB, id uint, userID uint, enabled bool) error {
+	result := db.Model(&AutoPayment{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("enabled", enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CancelAutoPayment soft-deletes a subscription so it's no longer claimed by
+// the scheduler, while keeping its history for the owning user.
+func CancelAutoPayment(db *gorm.DB, id uint, userID uint) error {
+	result := db.Where("id = ? AND user_id = ?", id, userID).Delete(&AutoPayment{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClaimDueAutoPayments atomically claims every enabled subscription whose
+// NextRunAt has passed, setting NextRunAt to now plus one period in the same
+// transaction so a slow scheduler tick can't double-fire a run. Mirrors
+// ReserveCodeForOrder's dialect-specific locking.
+func ClaimDueAutoPayments(ctx context.Context, db *gorm.DB, limit int) ([]AutoPayment, error) {
+	var claimed []AutoPayment
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []AutoPayment
+
+		if IsPostgres(db) {
+			err := tx.Raw(`
+				SELECT * FROM auto_payments
+				WHERE enabled = true AND next_run_at < ? AND deleted_at IS NULL
+				ORDER BY next_run_at
+				LIMIT ?
+				FOR UPDATE SKIP LOCKED
+			`, time.Now(), limit).Scan(&due).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Where("enabled = ? AND next_run_at < ? AND deleted_at IS NULL", true, time.Now()).
+				Order("next_run_at").
+				Limit(limit).
+				Find(&due).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, autoPayment := range due {
+			nextRunAt := time.Now().Add(time.Duration(autoPayment.PeriodSeconds) * time.Second)
+			if err := tx.Model(&AutoPayment{}).Where("id = ?", autoPayment.ID).
+				Update("next_run_at", nextRunAt).Error; err != nil {
+				return err
+			}
+			autoPayment.NextRunAt = nextRunAt
+			claimed = append(claimed, autoPayment)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim due auto payments: %w", err)
+	}
+
+	return claimed, nil
+}
+
+// RecordAutoPaymentRun stamps a successful run and, once MaxRuns is reached,
+// disables the subscription so the scheduler stops claiming it.
+func RecordAutoPaymentRun(db *gorm.DB, id uint) error {
+	now := time.Now()
+	var autoPayment AutoPayment
+	if err := db.First(&autoPayment, id).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"last_payment_time": now,
+		"run_count":         autoPayment.RunCount + 1,
+	}
+	if autoPayment.MaxRuns != nil && autoPayment.RunCount+1 >= *autoPayment.MaxRuns {
+		updates["enabled"] = false
+	}
+
+	return db.Model(&AutoPayment{}).Where("id = ?", id).Updates(updates).Error
+}
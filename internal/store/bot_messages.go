@@ -0,0 +1,47 @@
+package store
+
+import "gorm.io/gorm"
+
+// BotMessage is an admin-editable override for a single message-catalog entry.
+// Rows here are layered on top of the embedded catalog by messages.Manager.Reload.
+type BotMessage struct {
+	gorm.Model
+	Lang  string `gorm:"column:language;index:idx_bot_messages_lang_key,unique"`
+	MsgKey   string `gorm:"column:key;index:idx_bot_messages_lang_key,unique"`
+	MsgValue string `gorm:"column:value"`
+}
+
+func (BotMessage) TableName() string {
+	return "bot_messages"
+}
+
+// Language satisfies messages.overrideSource.
+func (b BotMessage) Language() string { return b.Lang }
+
+// Key satisfies messages.overrideSource.
+func (b BotMessage) Key() string { return b.MsgKey }
+
+// Value satisfies messages.overrideSource.
+func (b BotMessage) Value() string { return b.MsgValue }
+
+// GetBotMessageOverrides returns every admin-edited message override.
+func GetBotMessageOverrides(db *gorm.DB) ([]BotMessage, error) {
+	var rows []BotMessage
+	err := db.Find(&rows).Error
+	return rows, err
+}
+
+// UpsertBotMessage creates or updates the override for (lang, key).
+func UpsertBotMessage(db *gorm.DB, lang, key, value string) error {
+	var existing BotMessage
+	err := db.Where("language = ? AND key = ?", lang, key).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return db.Create(&BotMessage{Lang: lang, MsgKey: key, MsgValue: value}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.MsgValue = value
+	return db.Save(&existing).Error
+}
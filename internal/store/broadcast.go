@@ -0,0 +1,144 @@
+package store
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// BroadcastCampaign is one admin-authored broadcast: a named set of A/B
+// variant templates (serialized as JSON, since the variant set is
+// open-ended) plus their send split and lifecycle status.
+type BroadcastCampaign struct {
+	gorm.Model
+	Name         string
+	VariantsJSON string `gorm:"type:text"`
+	SplitJSON    string `gorm:"type:text"`
+	Status       string `gorm:"default:draft"` // draft, sent
+}
+
+func (BroadcastCampaign) TableName() string { return "broadcast_campaigns" }
+
+// Variants decodes the campaign's variant-name -> template-source map.
+func (c BroadcastCampaign) Variants() (map[string]string, error) {
+	variants := make(map[string]string)
+	if c.VariantsJSON == "" {
+		return variants, nil
+	}
+	err := json.Unmarshal([]byte(c.VariantsJSON), &variants)
+	return variants, err
+}
+
+// Split decodes the campaign's variant-name -> weight map.
+func (c BroadcastCampaign) Split() (map[string]int, error) {
+	split := make(map[string]int)
+	if c.SplitJSON == "" {
+		return split, nil
+	}
+	err := json.Unmarshal([]byte(c.SplitJSON), &split)
+	return split, err
+}
+
+// CreateBroadcastCampaign stores a new draft campaign.
+func CreateBroadcastCampaign(db *gorm.DB, name string, variants map[string]string, split map[string]int) (*BroadcastCampaign, error) {
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return nil, err
+	}
+	splitJSON, err := json.Marshal(split)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign := &BroadcastCampaign{
+		Name:         name,
+		VariantsJSON: string(variantsJSON),
+		SplitJSON:    string(splitJSON),
+		Status:       "draft",
+	}
+	if err := db.Create(campaign).Error; err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// GetBroadcastCampaigns lists every campaign, most recent first.
+func GetBroadcastCampaigns(db *gorm.DB) ([]BroadcastCampaign, error) {
+	var campaigns []BroadcastCampaign
+	err := db.Order("created_at DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// GetBroadcastCampaign loads a single campaign by ID.
+func GetBroadcastCampaign(db *gorm.DB, id uint) (*BroadcastCampaign, error) {
+	var campaign BroadcastCampaign
+	if err := db.First(&campaign, id).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// MarkBroadcastCampaignSent flips a campaign's status to "sent".
+func MarkBroadcastCampaignSent(db *gorm.DB, id uint) error {
+	return db.Model(&BroadcastCampaign{}).Where("id = ?", id).Update("status", "sent").Error
+}
+
+// BroadcastDelivery records that a broadcast campaign sent a particular A/B
+// variant to a particular user, so operators can compare delivery and
+// click-through rates across variants afterwards.
+type BroadcastDelivery struct {
+	gorm.Model
+	CampaignID uint   `gorm:"index"`
+	UserID     uint   `gorm:"index"`
+	Variant    string `gorm:"index"`
+	Clicked    bool
+}
+
+func (BroadcastDelivery) TableName() string { return "broadcast_deliveries" }
+
+// BroadcastVariantStat is the aggregate delivery/CTR count for one variant
+// of one campaign.
+type BroadcastVariantStat struct {
+	Variant string
+	Sent    int64
+	Clicked int64
+}
+
+// RecordBroadcastDelivery logs that campaignID sent variant to userID.
+func RecordBroadcastDelivery(db *gorm.DB, campaignID, userID uint, variant string) error {
+	return db.Create(&BroadcastDelivery{
+		CampaignID: campaignID,
+		UserID:     userID,
+		Variant:    variant,
+	}).Error
+}
+
+// RecordBroadcastClick marks the most recent delivery of campaignID to
+// userID as clicked, for CTR tracking.
+func RecordBroadcastClick(db *gorm.DB, campaignID, userID uint) error {
+	var delivery BroadcastDelivery
+	if err := db.Where("campaign_id = ? AND user_id = ?", campaignID, userID).
+		Order("created_at DESC").
+		First(&delivery).Error; err != nil {
+		return err
+	}
+	return db.Model(&delivery).Update("clicked", true).Error
+}
+
+// GetBroadcastStats returns sent/clicked counts for campaignID, grouped by variant.
+func GetBroadcastStats(db *gorm.DB, campaignID uint) ([]BroadcastVariantStat, error) {
+	var stats []BroadcastVariantStat
+	err := db.Model(&BroadcastDelivery{}).
+		Select("variant, count(*) as sent, sum(case when clicked then 1 else 0 end) as clicked").
+		Where("campaign_id = ?", campaignID).
+		Group("variant").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// GetAllUsers returns every known user, for broadcast fan-out.
+func GetAllUsers(db *gorm.DB) ([]User, error) {
+	var users []User
+	err := db.Find(&users).Error
+	return users, err
+}
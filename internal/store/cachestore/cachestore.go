@@ -0,0 +1,174 @@
+// Package cachestore decorates a store.Store with an in-process cache for
+// the reads that happen on nearly every incoming message - system settings,
+// currency settings, FAQs, and the active product list - none of which
+// change often enough to justify a database round trip per message.
+// SetSystemSetting on the wrapped Store invalidates the whole cache, since
+// there's no cheap way to know which cached reads a given setting key
+// affects.
+package cachestore
+
+import (
+	"context"
+	"sync"
+
+	"shop-bot/internal/store"
+)
+
+// Store wraps an inner store.Store, memoizing its read-mostly methods.
+type Store struct {
+	inner store.Store
+
+	mu          sync.RWMutex
+	settings    map[string]string
+	haveCurrency bool
+	currency    string
+	currencySym string
+	faqs        map[string][]store.FAQ
+	haveProducts bool
+	products    []store.Product
+
+	subsMu sync.Mutex
+	subs   []func()
+}
+
+// New returns a Store that serves reads from inner's results, memoized
+// until the next SetSystemSetting call.
+func New(inner store.Store) *Store {
+	return &Store{
+		inner:    inner,
+		settings: make(map[string]string),
+		faqs:     make(map[string][]store.FAQ),
+	}
+}
+
+// Subscribe registers fn to be called whenever the cache is invalidated
+// (i.e. after every successful SetSystemSetting). It's for callers that
+// hold their own derived state (e.g. a precomputed keyboard) and need to
+// know when to recompute it, rather than polling.
+func (s *Store) Subscribe(fn func()) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	s.subs = append(s.subs, fn)
+}
+
+func (s *Store) invalidate() {
+	s.mu.Lock()
+	s.settings = make(map[string]string)
+	s.haveCurrency = false
+	s.faqs = make(map[string][]store.FAQ)
+	s.haveProducts = false
+	s.products = nil
+	s.mu.Unlock()
+
+	s.subsMu.Lock()
+	subs := append([]func(){}, s.subs...)
+	s.subsMu.Unlock()
+	for _, fn := range subs {
+		fn()
+	}
+}
+
+func (s *Store) ClaimOneCodeTx(ctx context.Context, productID uint, orderID uint) (string, error) {
+	return s.inner.ClaimOneCodeTx(ctx, productID, orderID)
+}
+
+func (s *Store) GetOrCreateUser(tgUserID int64, username string) (*store.User, error) {
+	return s.inner.GetOrCreateUser(tgUserID, username)
+}
+
+func (s *Store) CreateOrder(userID, productID uint, amountCents int) (*store.Order, error) {
+	return s.inner.CreateOrder(userID, productID, amountCents)
+}
+
+func (s *Store) CreateOrderWithBalance(userID, productID uint, amountCents int, useBalance bool, voucherCode string) (*store.Order, error) {
+	return s.inner.CreateOrderWithBalance(userID, productID, amountCents, useBalance, voucherCode)
+}
+
+func (s *Store) CreateDepositOrder(userID uint, amountCents int) (*store.Order, error) {
+	return s.inner.CreateDepositOrder(userID, amountCents)
+}
+
+func (s *Store) GetSystemSetting(key string) (string, error) {
+	s.mu.RLock()
+	if v, ok := s.settings[key]; ok {
+		s.mu.RUnlock()
+		return v, nil
+	}
+	s.mu.RUnlock()
+
+	v, err := s.inner.GetSystemSetting(key)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.settings[key] = v
+	s.mu.Unlock()
+	return v, nil
+}
+
+func (s *Store) SetSystemSetting(key, value string) error {
+	if err := s.inner.SetSystemSetting(key, value); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *Store) GetCurrencySettings() (string, string) {
+	s.mu.RLock()
+	if s.haveCurrency {
+		defer s.mu.RUnlock()
+		return s.currency, s.currencySym
+	}
+	s.mu.RUnlock()
+
+	currency, symbol := s.inner.GetCurrencySettings()
+
+	s.mu.Lock()
+	s.currency, s.currencySym, s.haveCurrency = currency, symbol, true
+	s.mu.Unlock()
+	return currency, symbol
+}
+
+func (s *Store) GetActiveFAQs(language string) ([]store.FAQ, error) {
+	s.mu.RLock()
+	if faqs, ok := s.faqs[language]; ok {
+		s.mu.RUnlock()
+		return faqs, nil
+	}
+	s.mu.RUnlock()
+
+	faqs, err := s.inner.GetActiveFAQs(language)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.faqs[language] = faqs
+	s.mu.Unlock()
+	return faqs, nil
+}
+
+func (s *Store) GetActiveProducts() ([]store.Product, error) {
+	s.mu.RLock()
+	if s.haveProducts {
+		defer s.mu.RUnlock()
+		return s.products, nil
+	}
+	s.mu.RUnlock()
+
+	products, err := s.inner.GetActiveProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.products, s.haveProducts = products, true
+	s.mu.Unlock()
+	return products, nil
+}
+
+func (s *Store) GetProduct(productID uint) (*store.Product, error) {
+	return s.inner.GetProduct(productID)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FSMSession is the persisted state of one user's in-progress conversation
+// (custom deposit amount, multi-step ticket submission, ...), so a restart
+// doesn't drop what they were in the middle of doing.
+type FSMSession struct {
+	gorm.Model
+	TgUserID  int64  `gorm:"uniqueIndex"`
+	State     string `gorm:"index"`
+	DataJSON  string `gorm:"type:text"`
+	ExpiresAt time.Time
+}
+
+func (FSMSession) TableName() string { return "fsm_sessions" }
+
+// GetFSMSession loads the session for tgUserID, or gorm.ErrRecordNotFound if none exists.
+func GetFSMSession(db *gorm.DB, tgUserID int64) (*FSMSession, error) {
+	var session FSMSession
+	if err := db.Where("tg_user_id = ?", tgUserID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpsertFSMSession creates or replaces the session for tgUserID.
+func UpsertFSMSession(db *gorm.DB, tgUserID int64, state, dataJSON string, expiresAt time.Time) error {
+	var session FSMSession
+	err := db.Where("tg_user_id = ?", tgUserID).First(&session).Error
+	if err == gorm.ErrRecordNotFound {
+		return db.Create(&FSMSession{
+			TgUserID:  tgUserID,
+			State:     state,
+			DataJSON:  dataJSON,
+			ExpiresAt: expiresAt,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&session).Updates(map[string]interface{}{
+		"state":      state,
+		"data_json":  dataJSON,
+		"expires_at": expiresAt,
+	}).Error
+}
+
+// DeleteFSMSession removes any session for tgUserID.
+func DeleteFSMSession(db *gorm.DB, tgUserID int64) error {
+	return db.Where("tg_user_id = ?", tgUserID).Delete(&FSMSession{}).Error
+}
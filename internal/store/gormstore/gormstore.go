@@ -0,0 +1,72 @@
+// Package gormstore is the production implementation of store.Store: a
+// thin adapter over the package-level functions in internal/store, which
+// still operate on *gorm.DB directly so the many existing call sites that
+// use them that way don't have to change. New or refactored code should
+// prefer depending on store.Store (via this package's Store) instead of a
+// bare *gorm.DB.
+package gormstore
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"shop-bot/internal/config"
+	"shop-bot/internal/store"
+)
+
+// Store adapts internal/store's free functions to store.Store.
+type Store struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+// New returns a Store backed by db. cfg is used only for
+// GetCurrencySettings' config fallback; it may be nil.
+func New(db *gorm.DB, cfg *config.Config) *Store {
+	return &Store{db: db, cfg: cfg}
+}
+
+func (s *Store) ClaimOneCodeTx(ctx context.Context, productID uint, orderID uint) (string, error) {
+	return store.ClaimOneCodeTx(ctx, s.db, productID, orderID)
+}
+
+func (s *Store) GetOrCreateUser(tgUserID int64, username string) (*store.User, error) {
+	return store.GetOrCreateUser(s.db, tgUserID, username)
+}
+
+func (s *Store) CreateOrder(userID, productID uint, amountCents int) (*store.Order, error) {
+	return store.CreateOrder(s.db, userID, productID, amountCents)
+}
+
+func (s *Store) CreateOrderWithBalance(userID, productID uint, amountCents int, useBalance bool, voucherCode string) (*store.Order, error) {
+	return store.CreateOrderWithBalance(s.db, userID, productID, amountCents, useBalance, voucherCode)
+}
+
+func (s *Store) CreateDepositOrder(userID uint, amountCents int) (*store.Order, error) {
+	return store.CreateDepositOrder(s.db, userID, amountCents)
+}
+
+func (s *Store) GetSystemSetting(key string) (string, error) {
+	return store.GetSystemSetting(s.db, key)
+}
+
+func (s *Store) SetSystemSetting(key, value string) error {
+	return store.SetSystemSetting(s.db, key, value)
+}
+
+func (s *Store) GetCurrencySettings() (string, string) {
+	return store.GetCurrencySettings(s.db, s.cfg)
+}
+
+func (s *Store) GetActiveFAQs(language string) ([]store.FAQ, error) {
+	return store.GetActiveFAQs(s.db, language)
+}
+
+func (s *Store) GetActiveProducts() ([]store.Product, error) {
+	return store.GetActiveProducts(s.db)
+}
+
+func (s *Store) GetProduct(productID uint) (*store.Product, error) {
+	return store.GetProduct(s.db, productID)
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"shop-bot/internal/config"
+	"shop-bot/internal/store/migrations"
+)
+
+// InitDB opens the database described by cfg and brings its schema up to
+// date via internal/store/migrations, so callers never need to run
+// cmd/fix-constraint-style one-off scripts by hand again. Callers still run
+// their own AutoMigrate for the model structs afterward; this only owns the
+// versioned, hand-written changes migrations can't express.
+func InitDB(cfg *config.Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch strings.ToLower(cfg.DBType) {
+	case "postgres", "postgresql":
+		dialector = postgres.Open(cfg.GetDBDSN())
+	default:
+		dialector = sqlite.Open(cfg.GetDBDSN())
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	if err := migrations.Migrate(db); err != nil {
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// IsPostgres reports whether db is backed by the Postgres dialector, for
+// call sites (ReserveCodeForOrder, ClaimOneCodeTx, ...) that need Postgres's
+// "FOR UPDATE SKIP LOCKED" to avoid contending with concurrent claims and
+// fall back to a plain row lock on SQLite, which doesn't support it.
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InviteGrant is the single-use Telegram invite link issued for one
+// invite_link product order, so support can see exactly which link a buyer
+// was given, revoke it, and issue a fresh one if the buyer reports it didn't
+// work.
+type InviteGrant struct {
+	ID         uint `gorm:"primaryKey"`
+	OrderID    uint `gorm:"not null;uniqueIndex"`
+	ChatID     int64  `gorm:"not null"`
+	InviteLink string `gorm:"not null"`
+	ExpiresAt  time.Time
+	Consumed   bool `gorm:"not null;default:false"`
+	Revoked    bool `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CreateInviteGrant records a newly issued invite link for orderID,
+// replacing any earlier grant for the same order (e.g. after a reissue).
+func CreateInviteGrant(db *gorm.DB, orderID uint, chatID int64, inviteLink string, expiresAt time.Time) (*InviteGrant, error) {
+	if err := db.Where("order_id = ?", orderID).Delete(&InviteGrant{}).Error; err != nil {
+		return nil, err
+	}
+
+	grant := &InviteGrant{
+		OrderID:    orderID,
+		ChatID:     chatID,
+		InviteLink: inviteLink,
+		ExpiresAt:  expiresAt,
+	}
+	if err := db.Create(grant).Error; err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// GetInviteGrantByOrderID fetches the invite grant issued for orderID, if any.
+func GetInviteGrantByOrderID(db *gorm.DB, orderID uint) (*InviteGrant, error) {
+	var grant InviteGrant
+	err := db.Where("order_id = ?", orderID).First(&grant).Error
+	return &grant, err
+}
+
+// MarkInviteGrantConsumed flags the grant for inviteLink as used, once a
+// ChatMemberUpdated event confirms the buyer actually joined with it.
+func MarkInviteGrantConsumed(db *gorm.DB, inviteLink string) error {
+	return db.Model(&InviteGrant{}).
+		Where("invite_link = ?", inviteLink).
+		Update("consumed", true).Error
+}
+
+// RevokeInviteGrant marks orderID's grant as revoked, so an admin can follow
+// up by issuing a fresh link via the bot's reissue command.
+func RevokeInviteGrant(db *gorm.DB, orderID uint) error {
+	result := db.Model(&InviteGrant{}).
+		Where("order_id = ?", orderID).
+		Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
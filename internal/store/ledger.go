@@ -0,0 +1,121 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LedgerEntry is one posting against a user's balance. Every
+// balance-affecting operation (deposit, purchase, refund, admin adjustment)
+// writes a single row for the user's account, carrying either DebitCents or
+// CreditCents, in the same transaction that changes User.BalanceCents; no
+// counter-account row is written. The balance is reconstructible from this table alone instead of
+// being a bare mutation with no forensic trail.
+type LedgerEntry struct {
+	ID          uint `gorm:"primaryKey"`
+	AccountID   uint `gorm:"index"`
+	OrderID     *uint
+	DebitCents  int
+	CreditCents int
+	Currency    string
+	RefType     string // e.g. "deposit", "purchase", "refund", "admin_adjustment"
+	RefID       uint
+	CreatedAt   time.Time
+}
+
+func (LedgerEntry) TableName() string { return "ledger_entries" }
+
+// ErrBalanceDrift is returned by ReconcileBalance, and by any write that
+// posts a ledger entry, when User.BalanceCents disagrees with
+// SUM(credit_cents)-SUM(debit_cents) for that user's ledger entries.
+var ErrBalanceDrift = errors.New("user balance does not match ledger total")
+
+// ledgerTotalCents returns SUM(credit_cents)-SUM(debit_cents) for userID.
+func ledgerTotalCents(tx *gorm.DB, userID uint) (int, error) {
+	var total int
+	err := tx.Model(&LedgerEntry{}).
+		Where("account_id = ?", userID).
+		Select("COALESCE(SUM(credit_cents) - SUM(debit_cents), 0)").
+		Row().Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("ledger total: %w", err)
+	}
+	return total, nil
+}
+
+// postLedgerEntry appends the debit or credit leg for a balance change of
+// amount (positive credits the account, negative debits it), then
+// recomputes User.BalanceCents from the ledger and writes that back as a
+// materialised view - asserting the recomputed total actually matches what
+// the caller's delta implies, so a missed or duplicated entry is caught
+// immediately rather than silently drifting. Must run inside tx.
+func postLedgerEntry(tx *gorm.DB, userID uint, amount Money, refType string, refID uint, orderID *uint) error {
+	var user User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return fmt.Errorf("post ledger entry: load user: %w", err)
+	}
+
+	currency, _ := GetCurrencySettings(tx, nil)
+	cents := amount.Cents()
+	entry := LedgerEntry{AccountID: userID, OrderID: orderID, Currency: currency, RefType: refType, RefID: refID}
+	if cents >= 0 {
+		entry.CreditCents = cents
+	} else {
+		entry.DebitCents = -cents
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("post ledger entry: %w", err)
+	}
+
+	total, err := ledgerTotalCents(tx, userID)
+	if err != nil {
+		return err
+	}
+	if total != user.BalanceCents+cents {
+		return fmt.Errorf("post ledger entry: user %d expected balance %d after posting, ledger totals %d: %w",
+			userID, user.BalanceCents+cents, total, ErrBalanceDrift)
+	}
+
+	if err := tx.Model(&user).Update("balance_cents", total).Error; err != nil {
+		return fmt.Errorf("post ledger entry: update user balance: %w", err)
+	}
+	return nil
+}
+
+// GetLedger returns userID's ledger entries with CreatedAt on or after from
+// and strictly before to, oldest first.
+func GetLedger(db *gorm.DB, userID uint, from, to time.Time) ([]LedgerEntry, error) {
+	var entries []LedgerEntry
+	err := db.Where("account_id = ? AND created_at >= ? AND created_at < ?", userID, from, to).
+		Order("created_at ASC, id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("get ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// ReconcileBalance recomputes userID's balance from their ledger entries
+// and compares it against the materialised User.BalanceCents. It returns
+// the ledger-derived total and a non-nil error wrapping ErrBalanceDrift if
+// the two disagree; it never writes anything.
+func ReconcileBalance(db *gorm.DB, userID uint) (ledgerCents int, err error) {
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		return 0, fmt.Errorf("reconcile balance: load user: %w", err)
+	}
+
+	total, err := ledgerTotalCents(db, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if total != user.BalanceCents {
+		return total, fmt.Errorf("reconcile balance: user %d balance_cents=%d ledger total=%d: %w",
+			userID, user.BalanceCents, total, ErrBalanceDrift)
+	}
+	return total, nil
+}
@@ -0,0 +1,234 @@
+// Package memstore is an in-memory implementation of store.Store, intended
+// for handler code (and its tests) that wants a real Store without standing
+// up a database, without inventing a second interface.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"shop-bot/internal/store"
+)
+
+// Store is a goroutine-safe, in-memory store.Store. The zero value is not
+// usable; construct one with New.
+type Store struct {
+	mu sync.Mutex
+
+	users       map[uint]*store.User
+	usersByTgID map[int64]uint
+	nextUserID  uint
+	orders      map[uint]*store.Order
+	nextOrderID uint
+	products    map[uint]*store.Product
+	codes       map[uint][]string // productID -> unclaimed code values
+	faqs        []store.FAQ
+	settings    map[string]string
+	currency    string
+	currencySym string
+}
+
+// New returns an empty Store. currency/symbol seed GetCurrencySettings,
+// since there's no config to fall back to in memory.
+func New(currency, currencySymbol string) *Store {
+	return &Store{
+		users:       make(map[uint]*store.User),
+		usersByTgID: make(map[int64]uint),
+		orders:      make(map[uint]*store.Order),
+		products:    make(map[uint]*store.Product),
+		codes:       make(map[uint][]string),
+		settings:    make(map[string]string),
+		currency:    currency,
+		currencySym: currencySymbol,
+	}
+}
+
+// SeedProduct registers a product so GetProduct/GetActiveProducts can return
+// it; it is test/setup wiring, not part of store.Store.
+func (s *Store) SeedProduct(p *store.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.products[p.ID] = p
+}
+
+// SeedCodes adds unclaimed codes for productID, available to ClaimOneCodeTx.
+func (s *Store) SeedCodes(productID uint, codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[productID] = append(s.codes[productID], codes...)
+}
+
+// SeedFAQs registers the FAQs returned by GetActiveFAQs.
+func (s *Store) SeedFAQs(faqs []store.FAQ) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faqs = faqs
+}
+
+func (s *Store) ClaimOneCodeTx(ctx context.Context, productID uint, orderID uint) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	available := s.codes[productID]
+	if len(available) == 0 {
+		return "", store.ErrNoStock
+	}
+	claimed := available[0]
+	s.codes[productID] = available[1:]
+	return claimed, nil
+}
+
+func (s *Store) GetOrCreateUser(tgUserID int64, username string) (*store.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.usersByTgID[tgUserID]; ok {
+		return s.users[id], nil
+	}
+	s.nextUserID++
+	u := &store.User{ID: s.nextUserID, TgUserID: tgUserID, Username: username}
+	s.users[u.ID] = u
+	s.usersByTgID[tgUserID] = u.ID
+	return u, nil
+}
+
+func (s *Store) createOrderLocked(userID, productID uint, amountCents, paymentAmount, balanceUsed int) *store.Order {
+	s.nextOrderID++
+	o := &store.Order{
+		ID:            s.nextOrderID,
+		UserID:        userID,
+		ProductID:     &productID,
+		AmountCents:   amountCents,
+		PaymentAmount: paymentAmount,
+		BalanceUsed:   balanceUsed,
+		Status:        "pending",
+	}
+	s.orders[o.ID] = o
+	return o
+}
+
+func (s *Store) CreateOrder(userID, productID uint, amountCents int) (*store.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createOrderLocked(userID, productID, amountCents, amountCents, 0), nil
+}
+
+// CreateOrderWithBalance does not model vouchers - voucherCode is accepted
+// for interface compatibility but always ignored.
+func (s *Store) CreateOrderWithBalance(userID, productID uint, amountCents int, useBalance bool, voucherCode string) (*store.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("create order with balance: user %d not found", userID)
+	}
+
+	amount := store.MoneyFromCents(amountCents)
+	balance := store.MoneyFromCents(u.BalanceCents)
+	balanceUsedMoney := store.MoneyFromCents(0)
+	paymentAmountMoney := amount
+
+	if useBalance && balance.Decimal.IsPositive() {
+		if balance.Cmp(amount) >= 0 {
+			balanceUsedMoney = amount
+			paymentAmountMoney = store.MoneyFromCents(0)
+		} else {
+			balanceUsedMoney = balance
+			paymentAmountMoney = amount.Sub(balance)
+		}
+	}
+
+	balanceUsed := balanceUsedMoney.Cents()
+	paymentAmount := paymentAmountMoney.Cents()
+
+	o := s.createOrderLocked(userID, productID, amountCents, paymentAmount, balanceUsed)
+	if balanceUsed > 0 {
+		u.BalanceCents = store.MoneyFromCents(u.BalanceCents).Sub(balanceUsedMoney).Cents()
+		if paymentAmount == 0 {
+			o.Status = "paid"
+		}
+	}
+	return o, nil
+}
+
+func (s *Store) CreateDepositOrder(userID uint, amountCents int) (*store.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextOrderID++
+	o := &store.Order{
+		ID:            s.nextOrderID,
+		UserID:        userID,
+		ProductID:     nil,
+		AmountCents:   amountCents,
+		PaymentAmount: amountCents,
+		Status:        "pending",
+	}
+	s.orders[o.ID] = o
+	return o, nil
+}
+
+func (s *Store) GetSystemSetting(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.settings[key]
+	if !ok {
+		return "", fmt.Errorf("system setting %q not found", key)
+	}
+	return v, nil
+}
+
+func (s *Store) SetSystemSetting(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[key] = value
+	return nil
+}
+
+func (s *Store) GetCurrencySettings() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.currency, s.currencySym
+}
+
+func (s *Store) GetActiveFAQs(language string) ([]store.FAQ, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.FAQ
+	for _, f := range s.faqs {
+		if f.Language == language {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetActiveProducts() ([]store.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []store.Product
+	for _, p := range s.products {
+		if p.IsActive {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) GetProduct(productID uint) (*store.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[productID]
+	if !ok {
+		return nil, fmt.Errorf("product %d not found", productID)
+	}
+	cp := *p
+	return &cp, nil
+}
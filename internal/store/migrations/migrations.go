@@ -0,0 +1,181 @@
+// Package migrations replaces one-off scripts like cmd/fix-constraint with a
+// small, versioned migration runner: each schema change ships as a numbered
+// .sql file under sql/, tracked in a schema_migrations table so it's applied
+// exactly once per database. A migration can ship a single dialect-agnostic
+// file (NNNN_name.sql) or split per dialect (NNNN_name.postgres.sql /
+// NNNN_name.sqlite.sql) when the SQL itself isn't portable.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+var filenamePattern = regexp.MustCompile(`^(\d{4})_([a-zA-Z0-9_]+?)(?:\.(postgres|sqlite))?\.sql$`)
+
+// Migration is one numbered schema change, already resolved to the SQL
+// that applies on the target database's dialect.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// schemaMigration is the gorm model backing the schema_migrations table.
+// It's created via AutoMigrate rather than its own .sql file so the runner
+// never depends on itself having already run.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// dialect returns "postgres" or "sqlite" for db, matching the values used
+// in per-dialect migration filenames.
+func dialect(db *gorm.DB) string {
+	if db.Dialector != nil && db.Dialector.Name() == "postgres" {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+// Load reads every embedded migration, picking the dialect-specific SQL
+// file for a version when one exists and falling back to the
+// dialect-agnostic file otherwise, and returns them sorted by version.
+func Load(dialectName string) ([]Migration, error) {
+	entries, err := sqlFiles.ReadDir("sql")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir: %w", err)
+	}
+
+	type candidate struct {
+		name    string
+		dialect string // "" means dialect-agnostic
+	}
+	byVersion := map[int]map[string]candidate{}
+	names := map[int]string{}
+
+	for _, entry := range entries {
+		match := filenamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+		if byVersion[version] == nil {
+			byVersion[version] = map[string]candidate{}
+		}
+		byVersion[version][match[3]] = candidate{name: entry.Name(), dialect: match[3]}
+		names[version] = match[2]
+	}
+
+	versions := make([]int, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+
+	migrations := make([]Migration, 0, len(versions))
+	for _, v := range versions {
+		candidates := byVersion[v]
+		chosen, ok := candidates[dialectName]
+		if !ok {
+			chosen, ok = candidates[""]
+		}
+		if !ok {
+			return nil, fmt.Errorf("migration %04d has no file for dialect %q and no dialect-agnostic fallback", v, dialectName)
+		}
+		content, err := sqlFiles.ReadFile("sql/" + chosen.name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", chosen.name, err)
+		}
+		migrations = append(migrations, Migration{Version: v, Name: names[v], SQL: string(content)})
+	}
+	return migrations, nil
+}
+
+// Migrate applies every migration whose version is not yet recorded in
+// schema_migrations, in order, each in its own transaction so a failure partway
+// through leaves already-applied steps in place.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+
+	all, err := Load(dialect(db))
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	var applied []int
+	if err := db.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+	appliedSet := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		appliedSet[v] = true
+	}
+
+	for _, m := range all {
+		if appliedSet[m.Version] {
+			continue
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.SQL).Error; err != nil {
+				return fmt.Errorf("apply migration %04d_%s: %w", m.Version, m.Name, err)
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status reports the schema version currently applied to db, the highest
+// version known to this build, and the names of migrations still pending.
+func Status(db *gorm.DB) (current int, target int, pending []string, err error) {
+	if err = db.AutoMigrate(&schemaMigration{}); err != nil {
+		return 0, 0, nil, fmt.Errorf("create schema_migrations table: %w", err)
+	}
+
+	all, err := Load(dialect(db))
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("load migrations: %w", err)
+	}
+	if len(all) > 0 {
+		target = all[len(all)-1].Version
+	}
+
+	var applied []int
+	if err = db.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return 0, 0, nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	appliedSet := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		appliedSet[v] = true
+		if v > current {
+			current = v
+		}
+	}
+
+	for _, m := range all {
+		if !appliedSet[m.Version] {
+			pending = append(pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+	return current, target, pending, nil
+}
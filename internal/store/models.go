@@ -0,0 +1,147 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User is one Telegram user the bot has seen - created on first /start via
+// GetOrCreateUser. BalanceCents is a materialised view of this user's
+// ledger entries (see ledger.go's postLedgerEntry) rather than a field any
+// caller should mutate directly. NotifyChannel/NotifyChannelRef select the
+// delivery backend (and address within it) ticket replies and admin test
+// notifications use when it isn't plain Telegram DM - see internal/notify.
+type User struct {
+	ID               uint   `gorm:"primaryKey"`
+	TgUserID         int64  `gorm:"uniqueIndex"`
+	Username         string
+	Language         string
+	BalanceCents     int
+	Banned           bool `gorm:"not null;default:false"`
+	NotifyChannel    string
+	NotifyChannelRef string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (User) TableName() string { return "users" }
+
+// Order is one purchase (a specific Product, ProductID set) or balance
+// deposit (ProductID nil) a User initiated. EpayOutTradeNo is the unique
+// reference a payment gateway's async notification carries back, which is
+// what SettleOrderTx looks orders up by. PaymentAmount is what's actually
+// owed to the gateway after BalanceUsed was deducted at creation time - see
+// CreateOrderWithBalance.
+type Order struct {
+	ID             uint  `gorm:"primaryKey"`
+	UserID         uint  `gorm:"not null;index"`
+	ProductID      *uint `gorm:"index"`
+	AmountCents    int
+	PaymentAmount  int
+	BalanceUsed    int
+	Status         string `gorm:"not null;default:pending;index"`
+	EpayOutTradeNo string `gorm:"uniqueIndex"`
+	GatewayTxNo    string
+	PaidAt         *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	User User `gorm:"foreignKey:UserID"`
+}
+
+func (Order) TableName() string { return "orders" }
+
+// Product is one catalog item the bot sells: either a stock of redemption
+// Codes (Kind "" / "code") or a Telegram invite link issued on the fly
+// (Kind "invite_link", using the InviteChatID/InviteTTLSeconds/
+// InviteRequiresApproval fields - see internal/bot/invite.go). Soft-deleted
+// via DeletedAt so a product can be taken off the storefront (and later
+// restored) without losing its order history.
+type Product struct {
+	ID                     uint `gorm:"primaryKey"`
+	Name                   string
+	Description            string
+	PriceCents             int
+	Kind                   string
+	InviteChatID           int64
+	InviteTTLSeconds       int
+	InviteRequiresApproval bool
+	IsActive               bool `gorm:"not null;default:true"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	DeletedAt              gorm.DeletedAt `gorm:"index"`
+}
+
+func (Product) TableName() string { return "products" }
+
+// Code is one redemption code in a Product's stock. Claiming (ClaimOneCodeTx)
+// and reserving (ReserveCodeForOrder) are both modeled as column updates on
+// this table rather than a separate reservation table, so the dialect-
+// specific locking in repository.go only has one row to contend over per
+// code.
+type Code struct {
+	ID                 uint   `gorm:"primaryKey"`
+	ProductID          uint   `gorm:"not null;index"`
+	Code               string `gorm:"not null"`
+	IsSold             bool   `gorm:"not null;default:false;index"`
+	SoldAt             *time.Time
+	OrderID            *uint `gorm:"index"`
+	Reserved           bool  `gorm:"not null;default:false"`
+	ReservedUntil      *time.Time
+	ReservedForOrderID *uint `gorm:"index"`
+	CreatedAt          time.Time
+}
+
+func (Code) TableName() string { return "codes" }
+
+// FAQ is one question/answer pair shown by the bot's FAQ menu, localized
+// per Language and ordered within it by SortOrder.
+type FAQ struct {
+	ID        uint `gorm:"primaryKey"`
+	Question  string
+	Answer    string
+	Language  string `gorm:"index"`
+	SortOrder int
+	IsActive  bool `gorm:"not null;default:true"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (FAQ) TableName() string { return "faqs" }
+
+// SystemSetting is one key/value row in the admin-configurable settings
+// table. Value is stored as-is for most keys, or as a secrets.Manager-
+// encrypted blob for anything in sensitiveSettingKeys - see
+// GetSystemSetting/SetSystemSetting in repository.go and secrets.go.
+type SystemSetting struct {
+	ID        uint   `gorm:"primaryKey"`
+	Key       string `gorm:"uniqueIndex"`
+	Value     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (SystemSetting) TableName() string { return "system_settings" }
+
+// Ticket is one support ticket opened by a User, identified to admins by
+// the human-facing TicketID (e.g. "TK-20260727-001") rather than the
+// numeric ID. Username/UserID duplicate the owning User's identity rather
+// than requiring a join, since ticket listings and admin notifications
+// need them far more often than the rest of the User row.
+type Ticket struct {
+	ID            uint   `gorm:"primaryKey"`
+	TicketID      string `gorm:"uniqueIndex"`
+	UserID        int64  `gorm:"index"`
+	Username      string
+	Subject       string
+	Category      string
+	Content       string
+	Status        string `gorm:"not null;default:open;index"`
+	AssignedAdmin string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (Ticket) TableName() string { return "tickets" }
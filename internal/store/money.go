@@ -0,0 +1,132 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"shop-bot/internal/money"
+)
+
+// Money is a currency amount backed by decimal.Decimal instead of a raw int
+// cent count, so arithmetic on it (splitting a purchase across balance and
+// gateway payment, crediting a deposit) can't silently overflow on a large
+// amount or drift the way float64 would. The *_cents DB columns it's read
+// from and written back to are untouched by this - Money is the type
+// callers like AddBalance compute with, converting at the boundary via
+// MoneyFromCents/Cents.
+type Money struct {
+	decimal.Decimal
+}
+
+// NewMoney wraps an already-computed decimal.Decimal as Money.
+func NewMoney(d decimal.Decimal) Money { return Money{d} }
+
+// MoneyFromCents converts a stored integer cent amount (e.g. a *_cents
+// column) into Money.
+func MoneyFromCents(cents int) Money { return Money{money.FromCents(cents)} }
+
+// Cents rounds m to the nearest cent and returns it as an int, for writing
+// back to a *_cents column.
+func (m Money) Cents() int { return money.ToCents(m.Decimal) }
+
+// Add returns m + other.
+func (m Money) Add(other Money) Money { return Money{m.Decimal.Add(other.Decimal)} }
+
+// Sub returns m - other.
+func (m Money) Sub(other Money) Money { return Money{m.Decimal.Sub(other.Decimal)} }
+
+// Mul returns m * other.
+func (m Money) Mul(other Money) Money { return Money{m.Decimal.Mul(other.Decimal)} }
+
+// Cmp compares m to other the way decimal.Decimal.Cmp does: -1, 0, or 1.
+func (m Money) Cmp(other Money) int { return m.Decimal.Cmp(other.Decimal) }
+
+// Scan implements sql.Scanner, so Money can be used directly as a GORM
+// model field backed by a NUMERIC (Postgres) or TEXT (SQLite) column.
+func (m *Money) Scan(value interface{}) error {
+	if value == nil {
+		m.Decimal = decimal.Zero
+		return nil
+	}
+	switch v := value.(type) {
+	case string:
+		d, err := decimal.NewFromString(v)
+		if err != nil {
+			return fmt.Errorf("scan money: %w", err)
+		}
+		m.Decimal = d
+	case []byte:
+		d, err := decimal.NewFromString(string(v))
+		if err != nil {
+			return fmt.Errorf("scan money: %w", err)
+		}
+		m.Decimal = d
+	case float64:
+		m.Decimal = decimal.NewFromFloat(v)
+	case int64:
+		m.Decimal = decimal.New(v, 0)
+	default:
+		return fmt.Errorf("scan money: unsupported type %T", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, storing Money as its fixed-point string
+// representation so precision survives the round trip through the DB.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal.String(), nil
+}
+
+// MarshalJSON renders m as a plain decimal string (e.g. "10.50"), not
+// cents, so API responses don't leak the storage representation.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Decimal.String())
+}
+
+// UnmarshalJSON accepts either a quoted decimal string ("10.50") or a bare
+// JSON number (10.5), since API clients vary on which they send.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return fmt.Errorf("unmarshal money: %w", err)
+		}
+		m.Decimal = d
+		return nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("unmarshal money: %w", err)
+	}
+	m.Decimal = decimal.NewFromFloat(f)
+	return nil
+}
+
+// currencyMinorUnits maps a currency code to how many fractional digits
+// it's conventionally displayed with - most are 2, but not all (JPY has
+// none, BHD has 3, and USDT is commonly shown to 6).
+var currencyMinorUnits = map[string]int32{
+	"CNY":  2,
+	"USD":  2,
+	"EUR":  2,
+	"JPY":  0,
+	"BHD":  3,
+	"USDT": 6,
+}
+
+// FormatCurrency renders m with the minor-unit precision conventional for
+// currency, e.g. for 10.50 FormatCurrency("JPY") -> "11",
+// FormatCurrency("USDT") -> "10.500000". Falls back to 2 decimal places for an unrecognized code.
+func (m Money) FormatCurrency(currency string) string {
+	decimals, ok := currencyMinorUnits[strings.ToUpper(currency)]
+	if !ok {
+		decimals = 2
+	}
+	return m.Decimal.StringFixed(decimals)
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Order statuses an admin action can move an order into, on top of the
+// usual pending/paid/delivered/cancelled lifecycle.
+const (
+	OrderStatusRefunded = "refunded"
+	OrderStatusFrozen   = "frozen"
+)
+
+// OrderAuditLog records one admin-initiated state transition on an order,
+// so refunds/freezes/reissues have the same kind of trail as the existing
+// two-factor audit log for ticket actions.
+type OrderAuditLog struct {
+	ID            uint `gorm:"primaryKey"`
+	OrderID       uint
+	OperatorID    uint
+	Action        string
+	PreviousState string
+	NewState      string
+	Reason        string
+	GatewayResponse string
+	CreatedAt     time.Time
+}
+
+// ListRecentOrders returns the most recent limit orders, newest first, for
+// the bot's /orders command. An empty status lists every order regardless
+// of state.
+func ListRecentOrders(db *gorm.DB, status string, limit int) ([]Order, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	q := db.Order("created_at desc").Limit(limit)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var orders []Order
+	err := q.Find(&orders).Error
+	return orders, err
+}
+
+// CountOrdersByStatus returns how many orders are currently in status, for
+// the bot's /stats command.
+func CountOrdersByStatus(db *gorm.DB, status string) (int64, error) {
+	var count int64
+	err := db.Model(&Order{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
+
+func recordOrderAudit(tx *gorm.DB, orderID, operatorID uint, action, previousState, newState, reason, gatewayResponse string) error {
+	return tx.Create(&OrderAuditLog{
+		OrderID:         orderID,
+		OperatorID:      operatorID,
+		Action:          action,
+		PreviousState:   previousState,
+		NewState:        newState,
+		Reason:          reason,
+		GatewayResponse: gatewayResponse,
+	}).Error
+}
+
+// RefundOrder transitions order to OrderStatusRefunded and releases every
+// Code sold against it back to the pool (unsold, unlinked), all inside one
+// transaction so a failure partway through never leaves codes marked sold
+// for an order whose payment was already reversed gatewayRefundRef is the
+// gateway's refund reference, stored on the audit row for reconciliation.
+func RefundOrder(db *gorm.DB, orderID, operatorID uint, reason, gatewayRefundRef string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return fmt.Errorf("refund order: load order: %w", err)
+		}
+		previousState := order.Status
+
+		if err := tx.Model(&Code{}).
+			Where("order_id = ?", orderID).
+			Updates(map[string]interface{}{
+				"is_sold":  false,
+				"sold_at":  nil,
+				"order_id": nil,
+			}).Error; err != nil {
+			return fmt.Errorf("refund order: release codes: %w", err)
+		}
+
+		if err := tx.Model(&order).Update("status", OrderStatusRefunded).Error; err != nil {
+			return fmt.Errorf("refund order: update status: %w", err)
+		}
+
+		return recordOrderAudit(tx, orderID, operatorID, "refund", previousState, OrderStatusRefunded, reason, gatewayRefundRef)
+	})
+}
+
+// FreezeOrder transitions order to OrderStatusFrozen, e.g. while an admin
+// investigates a suspected fraudulent purchase. It doesn't touch the
+// order's codes - a frozen order can still be unfrozen back to its
+// original state.
+func FreezeOrder(db *gorm.DB, orderID, operatorID uint, reason string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return fmt.Errorf("freeze order: load order: %w", err)
+		}
+		previousState := order.Status
+
+		if err := tx.Model(&order).Update("status", OrderStatusFrozen).Error; err != nil {
+			return fmt.Errorf("freeze order: update status: %w", err)
+		}
+
+		return recordOrderAudit(tx, orderID, operatorID, "freeze", previousState, OrderStatusFrozen, reason, "")
+	})
+}
+
+// UnfreezeOrder restores a frozen order to restoreState (the state it was
+// in before FreezeOrder was called, e.g. "paid").
+func UnfreezeOrder(db *gorm.DB, orderID, operatorID uint, restoreState, reason string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return fmt.Errorf("unfreeze order: load order: %w", err)
+		}
+		if order.Status != OrderStatusFrozen {
+			return fmt.Errorf("unfreeze order: order %d is not frozen", orderID)
+		}
+
+		if err := tx.Model(&order).Update("status", restoreState).Error; err != nil {
+			return fmt.Errorf("unfreeze order: update status: %w", err)
+		}
+
+		return recordOrderAudit(tx, orderID, operatorID, "unfreeze", OrderStatusFrozen, restoreState, reason, "")
+	})
+}
+
+// ReissueCode voids whatever code is currently sold against orderID (if
+// any) and claims a fresh one in its place, for when the original code
+// turns out to be bad. Returns the newly issued code.
+func ReissueCode(db *gorm.DB, orderID, operatorID uint, reason string) (string, error) {
+	var newCode string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		if err := tx.First(&order, orderID).Error; err != nil {
+			return fmt.Errorf("reissue code: load order: %w", err)
+		}
+		if order.ProductID == nil {
+			return fmt.Errorf("reissue code: order %d has no product", orderID)
+		}
+
+		if err := tx.Model(&Code{}).
+			Where("order_id = ?", orderID).
+			Update("order_id", nil).Error; err != nil {
+			return fmt.Errorf("reissue code: void previous code: %w", err)
+		}
+
+		var code Code
+		result := tx.Exec(`
+			UPDATE codes
+			SET is_sold = 1, sold_at = CURRENT_TIMESTAMP, order_id = ?
+			WHERE id IN (
+				SELECT id FROM codes
+				WHERE product_id = ? AND is_sold = 0
+				LIMIT 1
+			)
+		`, orderID, *order.ProductID)
+		if result.Error != nil {
+			return fmt.Errorf("reissue code: claim new code: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoStock
+		}
+		if err := tx.Where("order_id = ?", orderID).First(&code).Error; err != nil {
+			return fmt.Errorf("reissue code: fetch claimed code: %w", err)
+		}
+		newCode = code.Code
+
+		return recordOrderAudit(tx, orderID, operatorID, "reissue_code", order.Status, order.Status, reason, "")
+	})
+	return newCode, err
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken tracks one issued refresh token in a rotation chain: logging
+// in starts a new family (FamilyID == JTI, ParentJTI empty); every
+// subsequent RefreshToken call in httpadmin revokes the presented token and
+// inserts a new row with the same FamilyID and ParentJTI set to the token it
+// replaced. If a revoked token is ever presented again, the whole family is
+// revoked (see RevokeRefreshFamily) since that can only happen if the token
+// was stolen and used after the legitimate client already rotated past it.
+type RefreshToken struct {
+	ID                uint   `gorm:"primaryKey"`
+	JTI               string `gorm:"not null;uniqueIndex"`
+	FamilyID          string `gorm:"not null;index"`
+	ParentJTI         string
+	AdminUserID       uint `gorm:"not null;index"`
+	DeviceFingerprint string
+	IP                string
+	Revoked           bool `gorm:"not null;default:false"`
+	RevokedAt         *time.Time
+	ExpiresAt         time.Time
+	CreatedAt         time.Time
+}
+
+// CreateRefreshToken inserts the first row of a new family (login) when
+// parentJTI is empty, or the next link in an existing family (rotation)
+// otherwise - familyID is the same for every token a given login session
+// ever rotates through.
+func CreateRefreshToken(db *gorm.DB, jti, familyID, parentJTI string, adminUserID uint, deviceFingerprint, ip string, expiresAt time.Time) error {
+	return db.Create(&RefreshToken{
+		JTI:               jti,
+		FamilyID:          familyID,
+		ParentJTI:         parentJTI,
+		AdminUserID:       adminUserID,
+		DeviceFingerprint: deviceFingerprint,
+		IP:                ip,
+		ExpiresAt:         expiresAt,
+	}).Error
+}
+
+// GetRefreshTokenByJTI looks up a token by its JTI, for RefreshToken's
+// validate-then-rotate logic and reuse detection.
+func GetRefreshTokenByJTI(db *gorm.DB, jti string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := db.Where("jti = ?", jti).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken marks a single token revoked, e.g. the old token at the
+// end of a successful rotation.
+func RevokeRefreshToken(db *gorm.DB, jti string) error {
+	now := time.Now()
+	return db.Model(&RefreshToken{}).Where("jti = ?", jti).Updates(map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": &now,
+	}).Error
+}
+
+// RevokeRefreshTokenIfActive atomically revokes jti only if it wasn't
+// already revoked, in a single "UPDATE ... WHERE revoked = false" statement
+// so two concurrent requests presenting the same token can't both observe
+// it as still-active: the row lock the UPDATE itself takes serializes them,
+// and whichever one loses the race gets ok=false instead of rotating a
+// token reuse should have caught. Mirrors ClaimOneCodeTx/ApplyVoucherTx's
+// "let the affected-rows count gate the decision" pattern rather than a
+// separate read-then-write.
+func RevokeRefreshTokenIfActive(db *gorm.DB, jti string) (ok bool, err error) {
+	now := time.Now()
+	result := db.Model(&RefreshToken{}).Where("jti = ? AND revoked = ?", jti, false).Updates(map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": &now,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeRefreshFamily revokes every token in familyID at once - used both
+// when reuse of an already-rotated token is detected, and when an admin
+// revokes one of a user's devices from handleRevokeUserSession.
+func RevokeRefreshFamily(db *gorm.DB, familyID string) error {
+	now := time.Now()
+	return db.Model(&RefreshToken{}).Where("family_id = ? AND revoked = ?", familyID, false).Updates(map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": &now,
+	}).Error
+}
+
+// ListActiveRefreshTokensForUser returns adminUserID's currently-valid
+// tokens, one per active device/session - rotation keeps exactly one
+// non-revoked row per family, so this is effectively "active sessions".
+func ListActiveRefreshTokensForUser(db *gorm.DB, adminUserID uint) ([]RefreshToken, error) {
+	var tokens []RefreshToken
+	err := db.Where("admin_user_id = ? AND revoked = ?", adminUserID, false).
+		Order("created_at desc").
+		Find(&tokens).Error
+	return tokens, err
+}
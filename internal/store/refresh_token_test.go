@@ -0,0 +1,59 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TestRevokeRefreshTokenIfActive_ConcurrentReuse is the regression guard
+// for the refresh-rotation TOCTOU: two requests presenting the same
+// still-active refresh token concurrently must not both be told they won
+// the rotation, or the reuse detection built on top of this never fires.
+func TestRevokeRefreshTokenIfActive_ConcurrentReuse(t *testing.T) {
+	db := openTestRefreshTokenDB(t)
+
+	jti := "race-jti"
+	if err := CreateRefreshToken(db, jti, jti, "", 1, "ua-hash", "127.0.0.1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	results := make([]bool, attempts)
+	errs := make([]error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok, err := RevokeRefreshTokenIfActive(db, jti)
+			results[i], errs[i] = ok, err
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if results[i] {
+			wins++
+		}
+	}
+	if wins != 1 {
+		t.Fatalf("expected exactly 1 caller to win the revoke race out of %d attempts, got %d", attempts, wins)
+	}
+}
+
+func openTestRefreshTokenDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&RefreshToken{}); err != nil {
+		t.Fatalf("automigrate refresh_tokens: %v", err)
+	}
+	return db
+}
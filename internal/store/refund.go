@@ -0,0 +1,113 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Refund request statuses, following the money-back/arbitrator pattern:
+// a buyer's request starts under seller review, escalates to a second
+// admin group if unanswered past its deadline, and ends resolved one way
+// or the other (or expired, if even arbitration goes unanswered).
+const (
+	RefundStatusRequested      = "requested"
+	RefundStatusSellerReview   = "seller_review"
+	RefundStatusArbitration    = "arbitration"
+	RefundStatusResolvedRefund = "resolved_refund"
+	RefundStatusResolvedReject = "resolved_reject"
+	RefundStatusExpired        = "expired"
+)
+
+// RefundRequest tracks a buyer's refund/dispute over a specific order,
+// routed through a support ticket so the conversation history lives
+// alongside it.
+type RefundRequest struct {
+	ID                   uint `gorm:"primaryKey"`
+	OrderID              uint `gorm:"not null;index"`
+	TicketID             uint `gorm:"not null;index"`
+	UserID               uint `gorm:"not null;index"`
+	Status               string `gorm:"not null;default:'requested';index"`
+	RequestedAmountCents int    `gorm:"not null"`
+	ApprovedAmountCents  int
+	Reason               string
+	AdminNote            string
+	// ArbitrationDeadline is when this request's current stage
+	// (seller_review, then arbitration) auto-escalates or auto-expires if
+	// no admin has acted on it yet.
+	ArbitrationDeadline time.Time `gorm:"index"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// CreateRefundRequest opens a refund request against orderID, linked to
+// ticketID, due to escalate to arbitration after deadline if unanswered.
+func CreateRefundRequest(db *gorm.DB, orderID, ticketID, userID uint, amountCents int, reason string, deadline time.Duration) (*RefundRequest, error) {
+	request := &RefundRequest{
+		OrderID:              orderID,
+		TicketID:             ticketID,
+		UserID:               userID,
+		Status:               RefundStatusRequested,
+		RequestedAmountCents: amountCents,
+		Reason:               reason,
+		ArbitrationDeadline:  time.Now().Add(deadline),
+	}
+	if err := db.Create(request).Error; err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// GetRefundRequest fetches one refund request by ID.
+func GetRefundRequest(db *gorm.DB, id uint) (*RefundRequest, error) {
+	var request RefundRequest
+	err := db.First(&request, id).Error
+	return &request, err
+}
+
+// ApproveRefundRequest resolves a refund request in the buyer's favor for
+// approvedAmountCents (which may be less than what was requested, for a
+// partial refund).
+func ApproveRefundRequest(db *gorm.DB, id uint, approvedAmountCents int, adminNote string) error {
+	return db.Model(&RefundRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":                RefundStatusResolvedRefund,
+		"approved_amount_cents": approvedAmountCents,
+		"admin_note":            adminNote,
+	}).Error
+}
+
+// RejectRefundRequest resolves a refund request against the buyer, with the
+// admin's reason recorded for the auto-reply.
+func RejectRefundRequest(db *gorm.DB, id uint, adminNote string) error {
+	return db.Model(&RefundRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     RefundStatusResolvedReject,
+		"admin_note": adminNote,
+	}).Error
+}
+
+// EscalateRefundRequest moves a request from seller review into arbitration
+// and pushes its deadline out by the same window, so a second, unanswered
+// arbitration also eventually expires instead of hanging forever.
+func EscalateRefundRequest(db *gorm.DB, id uint, deadline time.Duration) error {
+	return db.Model(&RefundRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":               RefundStatusArbitration,
+		"arbitration_deadline": time.Now().Add(deadline),
+	}).Error
+}
+
+// ExpireRefundRequest closes out a request that went unanswered even in
+// arbitration.
+func ExpireRefundRequest(db *gorm.DB, id uint) error {
+	return db.Model(&RefundRequest{}).Where("id = ?", id).Update("status", RefundStatusExpired).Error
+}
+
+// ClaimOverdueRefundRequests returns every open request (requested,
+// seller_review or arbitration) whose current-stage deadline has passed, for the scheduler to
+// escalate or expire. The caller decides which based on request.Status.
+func ClaimOverdueRefundRequests(db *gorm.DB) ([]RefundRequest, error) {
+	var requests []RefundRequest
+	err := db.Where("status IN ? AND arbitration_deadline < ?",
+		[]string{RefundStatusRequested, RefundStatusSellerReview, RefundStatusArbitration}, time.Now()).
+		Find(&requests).Error
+	return requests, err
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 	"shop-bot/internal/config"
+	"shop-bot/internal/money"
+
+	"github.com/shopspring/decimal"
 )
 
 var (
@@ -15,15 +19,142 @@ var (
 	ErrClaimFailed = errors.New("failed to claim code")
 )
 
-// CountAvailableCodes returns the number of unsold codes for a product
+// CountAvailableCodes returns the number of codes for a product that are
+// neither sold nor currently held by a live reservation, so the count shown
+// to users matches what they can actually still buy.
 func CountAvailableCodes(db *gorm.DB, productID uint) (int64, error) {
 	var count int64
 	err := db.Model(&Code{}).
-		Where("product_id = ? AND is_sold = ?", productID, false).
+		Where("product_id = ? AND is_sold = ? AND (reserved = ? OR reserved_until < ?)",
+			productID, false, false, time.Now()).
 		Count(&count).Error
 	return count, err
 }
 
+// ReserveCodeForOrder atomically reserves one unsold, unreserved (or
+// reservation-expired) code for productID on behalf of orderID, holding it
+// until ttl passes. Mirrors ClaimOneCodeTx's dialect-specific locking so a
+// reservation and a final claim can't both land on the same code.
+func ReserveCodeForOrder(ctx context.Context, db *gorm.DB, productID uint, orderID uint, ttl time.Duration) error {
+	reservedUntil := time.Now().Add(ttl)
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if IsPostgres(db) {
+			var code Code
+			err := tx.Raw(`
+				SELECT * FROM codes
+				WHERE product_id = ? AND is_sold = false AND (reserved = false OR reserved_until < ?)
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			`, productID, time.Now()).Scan(&code).Error
+			if err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return ErrNoStock
+				}
+				return err
+			}
+
+			result := tx.Model(&Code{}).
+				Where("id = ?", code.ID).
+				Updates(map[string]interface{}{
+					"reserved":              true,
+					"reserved_until":        reservedUntil,
+					"reserved_for_order_id": orderID,
+				})
+			return result.Error
+		}
+
+		result := tx.Exec(`
+			UPDATE codes
+			SET reserved = 1, reserved_until = ?, reserved_for_order_id = ?
+			WHERE id IN (
+				SELECT id FROM codes
+				WHERE product_id = ? AND is_sold = 0 AND (reserved = 0 OR reserved_until < ?)
+				LIMIT 1
+			)
+		`, reservedUntil, orderID, productID, time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoStock
+		}
+		return nil
+	})
+}
+
+// FinalizeReservedCode marks the code reserved for orderID as sold and
+// returns it, for when the order's payment succeeds.
+func FinalizeReservedCode(ctx context.Context, db *gorm.DB, orderID uint) (string, error) {
+	var code Code
+	if err := db.WithContext(ctx).Where("reserved_for_order_id = ?", orderID).First(&code).Error; err != nil {
+		return "", fmt.Errorf("no reservation found for order %d: %w", orderID, err)
+	}
+
+	err := db.Model(&Code{}).Where("id = ?", code.ID).Updates(map[string]interface{}{
+		"is_sold":               true,
+		"sold_at":               time.Now(),
+		"order_id":              orderID,
+		"reserved":              false,
+		"reserved_until":        nil,
+		"reserved_for_order_id": nil,
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	return code.Code, nil
+}
+
+// ReleaseReservationForOrder frees the code held for orderID back to the
+// pool, without marking it sold. Safe to call even if orderID holds no
+// reservation.
+func ReleaseReservationForOrder(db *gorm.DB, orderID uint) error {
+	return db.Model(&Code{}).
+		Where("reserved_for_order_id = ?", orderID).
+		Updates(map[string]interface{}{
+			"reserved":              false,
+			"reserved_until":        nil,
+			"reserved_for_order_id": nil,
+		}).Error
+}
+
+// ExpiredReservation identifies one reservation that timed out, so the
+// caller can cancel its order and notify the buyer.
+type ExpiredReservation struct {
+	OrderID uint
+}
+
+// ReleaseExpiredReservations frees every code whose reservation has passed
+// its TTL and returns the orders that held them, so the caller can cancel
+// those orders and notify their buyers.
+func ReleaseExpiredReservations(db *gorm.DB) ([]ExpiredReservation, error) {
+	var codes []Code
+	if err := db.Where("reserved = ? AND reserved_until < ?", true, time.Now()).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+
+	expired := make([]ExpiredReservation, 0, len(codes))
+	for _, code := range codes {
+		if code.ReservedForOrderID == nil {
+			continue
+		}
+		expired = append(expired, ExpiredReservation{OrderID: *code.ReservedForOrderID})
+	}
+
+	if err := db.Model(&Code{}).
+		Where("reserved = ? AND reserved_until < ?", true, time.Now()).
+		Updates(map[string]interface{}{
+			"reserved":              false,
+			"reserved_until":        nil,
+			"reserved_for_order_id": nil,
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
 // ClaimOneCodeTx claims one available code for an order with concurrency safety
 func ClaimOneCodeTx(ctx context.Context, db *gorm.DB, productID uint, orderID uint) (string, error) {
 	var claimedCode string
@@ -101,6 +232,105 @@ func ClaimOneCodeTx(ctx context.Context, db *gorm.DB, productID uint, orderID ui
 	return claimedCode, nil
 }
 
+var (
+	// ErrAlreadySettled is returned by SettleOrderTx when the order has
+	// already left the "pending" state, so a gateway's duplicate
+	// notification is a no-op instead of double-claiming a code or
+	// double-crediting a balance.
+	ErrAlreadySettled = errors.New("order already settled")
+	// ErrAmountMismatch is returned by SettleOrderTx when the amount a
+	// gateway says it collected doesn't match the order's PaymentAmount.
+	ErrAmountMismatch = errors.New("settled amount does not match order payment amount")
+)
+
+// settleLocks serializes concurrent SettleOrderTx calls for the same
+// out_trade_no on SQLite, which has no real per-row locking: two duplicate
+// gateway notifications arriving back-to-back in the same process must not
+// both observe the order as still "pending". Postgres doesn't need this;
+// it gets a real row lock via SELECT ... FOR UPDATE instead.
+var settleLocks sync.Map
+
+// SettleOrderTx is the single idempotent entry point every payment gateway
+// callback should settle an order through. It locks the order row (Postgres:
+// SELECT ... FOR UPDATE; SQLite: an in-process mutex keyed by outTradeNo),
+// rejects re-entry with ErrAlreadySettled once the order has left "pending",
+// rejects a mismatched amount with ErrAmountMismatch, then claims a code for
+// product orders or credits balance for deposit orders (ProductID == nil) -
+// all inside one transaction, so two duplicate notifications for the same
+// order can never each claim a code or credit a balance.
+func SettleOrderTx(ctx context.Context, db *gorm.DB, outTradeNo string, factAmountCents int, gatewayTxNo string) (*Order, string, error) {
+	if !IsPostgres(db) {
+		lock, _ := settleLocks.LoadOrStore(outTradeNo, &sync.Mutex{})
+		mu := lock.(*sync.Mutex)
+		mu.Lock()
+		defer mu.Unlock()
+	}
+
+	var order Order
+	var code string
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if IsPostgres(db) {
+			if err := tx.Raw(`SELECT * FROM orders WHERE epay_out_trade_no = ? FOR UPDATE`, outTradeNo).Scan(&order).Error; err != nil {
+				return fmt.Errorf("settle order: load order: %w", err)
+			}
+		} else {
+			if err := tx.Where("epay_out_trade_no = ?", outTradeNo).First(&order).Error; err != nil {
+				return fmt.Errorf("settle order: load order: %w", err)
+			}
+		}
+
+		if order.Status != "pending" {
+			return ErrAlreadySettled
+		}
+		if factAmountCents != order.PaymentAmount {
+			return ErrAmountMismatch
+		}
+
+		now := time.Now()
+		if err := tx.Model(&order).Updates(map[string]interface{}{
+			"status":        "paid",
+			"paid_at":       &now,
+			"gateway_tx_no": gatewayTxNo,
+		}).Error; err != nil {
+			return fmt.Errorf("settle order: mark paid: %w", err)
+		}
+
+		if order.ProductID == nil {
+			if err := AddBalance(tx, order.UserID, MoneyFromCents(order.AmountCents), "deposit",
+				fmt.Sprintf("Order #%d", order.ID), nil, &order.ID); err != nil {
+				return fmt.Errorf("settle order: credit balance: %w", err)
+			}
+		} else {
+			// The order normally already holds a reservation from
+			// ReserveCodeForOrder (made when it was created), so finalize
+			// that instead of claiming a fresh code - otherwise the
+			// reserved code would never be released and a second one would
+			// be sold for the same order. Orders that skipped reservation
+			// entirely (e.g. settled for free with PaymentAmount 0) fall
+			// back to claiming directly.
+			claimed, err := FinalizeReservedCode(ctx, tx, order.ID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				claimed, err = ClaimOneCodeTx(ctx, tx, *order.ProductID, order.ID)
+			}
+			if err != nil {
+				return fmt.Errorf("settle order: claim code: %w", err)
+			}
+			code = claimed
+		}
+
+		return tx.Model(&order).Updates(map[string]interface{}{
+			"status":       "delivered",
+			"delivered_at": &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &order, code, nil
+}
+
 // GetProduct fetches a product by ID
 func GetProduct(db *gorm.DB, productID uint) (*Product, error) {
 	var product Product
@@ -190,31 +420,49 @@ func CreateOrder(db *gorm.DB, userID, productID uint, amountCents int) (*Order,
 	return order, nil
 }
 
-// CreateOrderWithBalance creates an order with balance deduction
-func CreateOrderWithBalance(db *gorm.DB, userID, productID uint, amountCents int, useBalance bool) (*Order, error) {
+// CreateOrderWithBalance creates an order with balance deduction. voucherCode
+// is optional (pass "" for none); if set, it's validated and redeemed
+// atomically via ApplyVoucherTx before the balance split is computed, so the
+// balance/payment amounts reflect the discounted price.
+func CreateOrderWithBalance(db *gorm.DB, userID, productID uint, amountCents int, useBalance bool, voucherCode string) (*Order, error) {
 	var order *Order
-	
+
 	err := db.Transaction(func(tx *gorm.DB) error {
 		// Get user balance
 		var user User
 		if err := tx.First(&user, userID).Error; err != nil {
 			return err
 		}
-		
-		balanceUsed := 0
-		paymentAmount := amountCents
-		
-		if useBalance && user.BalanceCents > 0 {
+
+		var redemption *VoucherRedemption
+		if voucherCode != "" {
+			discounted, r, err := ApplyVoucherTx(tx, userID, productID, amountCents, voucherCode)
+			if err != nil {
+				return err
+			}
+			amountCents = discounted
+			redemption = r
+		}
+
+		amount := MoneyFromCents(amountCents)
+		balance := MoneyFromCents(user.BalanceCents)
+		balanceUsedMoney := MoneyFromCents(0)
+		paymentAmountMoney := amount
+
+		if useBalance && balance.Decimal.IsPositive() {
 			// Calculate how much balance can be used
-			if user.BalanceCents >= amountCents {
-				balanceUsed = amountCents
-				paymentAmount = 0
+			if balance.Cmp(amount) >= 0 {
+				balanceUsedMoney = amount
+				paymentAmountMoney = MoneyFromCents(0)
 			} else {
-				balanceUsed = user.BalanceCents
-				paymentAmount = amountCents - user.BalanceCents
+				balanceUsedMoney = balance
+				paymentAmountMoney = amount.Sub(balance)
 			}
 		}
-		
+
+		balanceUsed := balanceUsedMoney.Cents()
+		paymentAmount := paymentAmountMoney.Cents()
+
 		// Create order
 		// Generate unique out_trade_no at creation time
 		tempID := fmt.Sprintf("%d-%d-%d", userID, productID, time.Now().UnixNano())
@@ -232,10 +480,17 @@ func CreateOrderWithBalance(db *gorm.DB, userID, productID uint, amountCents int
 		if err := tx.Create(order).Error; err != nil {
 			return err
 		}
-		
+
+		if redemption != nil {
+			redemption.OrderID = order.ID
+			if err := tx.Create(redemption).Error; err != nil {
+				return fmt.Errorf("create order with balance: persist voucher redemption: %w", err)
+			}
+		}
+
 		// If using balance, deduct it immediately
 		if balanceUsed > 0 {
-			if err := AddBalance(tx, userID, -balanceUsed, "purchase", 
+			if err := AddBalance(tx, userID, MoneyFromCents(0).Sub(balanceUsedMoney), "purchase",
 				fmt.Sprintf("Order #%d", order.ID), nil, &order.ID); err != nil {
 				return err
 			}
@@ -288,7 +543,44 @@ func CreateDepositOrder(db *gorm.DB, userID uint, amountCents int) (*Order, erro
 	return order, nil
 }
 
-// GetSystemSetting retrieves a system setting by key
+// AddBalance credits (or, with a negative amount, debits) userID's balance
+// by amount inside its own transaction, posting a single ledger entry
+// (see ledger.go) rather than mutating User.BalanceCents directly, so the
+// materialised balance always has a reconstructible trail behind it.
+// reason becomes the ledger entry's RefType; description and adminID are
+// accepted for call-site compatibility (recording who/what triggered the
+// change) but have no column to land in yet.
+func AddBalance(db *gorm.DB, userID uint, amount Money, reason string, description string, adminID *uint, orderID *uint) error {
+	var refID uint
+	if orderID != nil {
+		refID = *orderID
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return postLedgerEntry(tx, userID, amount, reason, refID, orderID)
+	})
+}
+
+// AmountDecimal returns the order's total amount as a Decimal in the major
+// currency unit, e.g. AmountCents=1050 -> 10.50.
+func (o *Order) AmountDecimal() decimal.Decimal {
+	return money.FromCents(o.AmountCents)
+}
+
+// PaymentAmountDecimal returns the order's remaining payment amount (after
+// any balance deduction) as a Decimal.
+func (o *Order) PaymentAmountDecimal() decimal.Decimal {
+	return money.FromCents(o.PaymentAmount)
+}
+
+// BalanceUsedDecimal returns the balance amount deducted against this order
+// as a Decimal.
+func (o *Order) BalanceUsedDecimal() decimal.Decimal {
+	return money.FromCents(o.BalanceUsed)
+}
+
+// GetSystemSetting retrieves a system setting by key, transparently
+// decrypting it first if it's one of sensitiveSettingKeys and a secrets
+// manager has been configured via ConfigureSecrets.
 func GetSystemSetting(db *gorm.DB, key string) (string, error) {
 	var setting SystemSetting
 	err := db.Where("key = ?", key).First(&setting).Error
@@ -298,14 +590,21 @@ func GetSystemSetting(db *gorm.DB, key string) (string, error) {
 		}
 		return "", err
 	}
-	return setting.Value, nil
+	return decryptSettingValue(key, setting.Value)
 }
 
-// SetSystemSetting sets a system setting value
+// SetSystemSetting sets a system setting value, transparently encrypting it
+// first if it's one of sensitiveSettingKeys and a secrets manager has been
+// configured via ConfigureSecrets.
 func SetSystemSetting(db *gorm.DB, key, value string) error {
+	value, err := encryptSettingValue(key, value)
+	if err != nil {
+		return err
+	}
+
 	var setting SystemSetting
-	err := db.Where("key = ?", key).First(&setting).Error
-	
+	err = db.Where("key = ?", key).First(&setting).Error
+
 	if err == gorm.ErrRecordNotFound {
 		// Create new setting
 		setting = SystemSetting{
@@ -314,11 +613,11 @@ func SetSystemSetting(db *gorm.DB, key, value string) error {
 		}
 		return db.Create(&setting).Error
 	}
-	
+
 	if err != nil {
 		return err
 	}
-	
+
 	// Update existing setting
 	return db.Model(&setting).Update("value", value).Error
 }
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestSettleOrderTx_ConcurrentNotifications is the regression guard for
+// SettleOrderTx's core claim: two duplicate payment notifications for the
+// same order (gateways routinely retry until they get a 200) must settle
+// it exactly once - one call transitions pending -> paid -> delivered and
+// credits balance/claims a code, every other concurrent call for the same
+// out_trade_no gets ErrAlreadySettled instead of double-crediting.
+//
+func TestSettleOrderTx_ConcurrentNotifications(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&User{}, &Order{}, &LedgerEntry{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+
+	user := User{TgUserID: 1}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	order := Order{
+		UserID:         user.ID,
+		AmountCents:    1000,
+		PaymentAmount:  1000,
+		Status:         "pending",
+		EpayOutTradeNo: "race-order-1",
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	errs := make([]error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := SettleOrderTx(context.Background(), db, "race-order-1", 1000, "gw-tx-1")
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	settled := 0
+	for _, err := range errs {
+		if err == nil {
+			settled++
+		} else if err != ErrAlreadySettled {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if settled != 1 {
+		t.Fatalf("expected exactly 1 successful settlement out of %d attempts, got %d", attempts, settled)
+	}
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"shop-bot/internal/secrets"
+)
+
+// sensitiveSettingKeys are the SystemSetting keys GetSystemSetting/
+// SetSystemSetting transparently encrypt at rest. Anything not listed here
+// (currency, display preferences, ...) is stored as plaintext, since
+// encrypting it would just add overhead with no confidentiality benefit.
+var sensitiveSettingKeys = map[string]bool{
+	"bot_token":          true,
+	"admin_token":        true,
+	"epay_key":           true,
+	"alipay_private_key": true,
+	"alipay_public_key":  true,
+	"wechat_private_key": true,
+	"wechat_api_v3_key":  true,
+}
+
+// secretsManager is nil until ConfigureSecrets is called, in which case
+// SetSystemSetting/GetSystemSetting fall back to storing sensitive values
+// as plaintext - matching their behavior before this package existed, so a
+// deployment that never sets MASTER_KEY keeps working unencrypted rather
+// than failing to start.
+var secretsManager *secrets.Manager
+
+// ConfigureSecrets installs the secrets manager used to encrypt/decrypt
+// sensitive system settings. Called once at startup from bot.go/server.go
+// after loading MASTER_KEY; passing nil disables encryption.
+func ConfigureSecrets(m *secrets.Manager) {
+	secretsManager = m
+}
+
+func encryptSettingValue(key, value string) (string, error) {
+	if secretsManager == nil || !sensitiveSettingKeys[key] {
+		return value, nil
+	}
+	encrypted, err := secretsManager.Encrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("encrypt setting %q: %w", key, err)
+	}
+	return encrypted, nil
+}
+
+func decryptSettingValue(key, value string) (string, error) {
+	if secretsManager == nil || !sensitiveSettingKeys[key] || !secrets.IsEncrypted(value) {
+		return value, nil
+	}
+	decrypted, err := secretsManager.Decrypt(value)
+	if err != nil {
+		return "", fmt.Errorf("decrypt setting %q: %w", key, err)
+	}
+	return decrypted, nil
+}
+
+// RotateMasterKey re-wraps every encrypted SystemSetting's DEK under
+// newManager's master key, without decrypting and re-encrypting the
+// underlying values themselves. oldManager must be able to unwrap the
+// DEKs currently stored (i.e. it's the manager ConfigureSecrets was set up
+// with before the rotation began).
+func RotateMasterKey(db *gorm.DB, oldManager, newManager *secrets.Manager) error {
+	var settings []SystemSetting
+	if err := db.Find(&settings).Error; err != nil {
+		return fmt.Errorf("rotate master key: list settings: %w", err)
+	}
+
+	for _, setting := range settings {
+		if !sensitiveSettingKeys[setting.Key] || !secrets.IsEncrypted(setting.Value) {
+			continue
+		}
+		rewrapped, err := oldManager.Rewrap(setting.Value, newManager)
+		if err != nil {
+			return fmt.Errorf("rotate master key: rewrap %q: %w", setting.Key, err)
+		}
+		if err := db.Model(&SystemSetting{}).Where("id = ?", setting.ID).Update("value", rewrapped).Error; err != nil {
+			return fmt.Errorf("rotate master key: save %q: %w", setting.Key, err)
+		}
+	}
+
+	secretsManager = newManager
+	return nil
+}
@@ -0,0 +1,26 @@
+package store
+
+import "context"
+
+// Store is the subset of this package's free functions a handler actually
+// needs, pulled out so handlers can depend on an interface instead of a
+// concrete *gorm.DB - gormstore.Store is the production implementation
+// (a thin adapter over the functions in this package), memstore.Store is an
+// in-memory one for tests that shouldn't need a real database, and
+// cachestore.Store decorates either with memoized reads for the settings
+// that get queried on every message. Existing call sites keep using this
+// package's free functions directly; Store is for code written or
+// refactored to take it going forward.
+type Store interface {
+	ClaimOneCodeTx(ctx context.Context, productID uint, orderID uint) (string, error)
+	GetOrCreateUser(tgUserID int64, username string) (*User, error)
+	CreateOrder(userID, productID uint, amountCents int) (*Order, error)
+	CreateOrderWithBalance(userID, productID uint, amountCents int, useBalance bool, voucherCode string) (*Order, error)
+	CreateDepositOrder(userID uint, amountCents int) (*Order, error)
+	GetSystemSetting(key string) (string, error)
+	SetSystemSetting(key, value string) error
+	GetCurrencySettings() (currency string, symbol string)
+	GetActiveFAQs(language string) ([]FAQ, error)
+	GetActiveProducts() ([]Product, error)
+	GetProduct(productID uint) (*Product, error)
+}
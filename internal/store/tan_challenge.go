@@ -0,0 +1,56 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TanChallenge is a single-use challenge issued by the httpadmin 2FA
+// subsystem for a mutating operation (core settings, payment settings,
+// admin deletion, order cleanup). It's bound to the exact request body via
+// PayloadHash so replaying a solved challenge against a modified payload
+// fails, and to one admin/op pair so it can't be reused elsewhere.
+type TanChallenge struct {
+	ID          uint `gorm:"primaryKey"`
+	Op          string
+	AdminID     uint
+	PayloadHash string
+	// Code is the numeric code sent to the admin via Telegram, empty if
+	// delivery failed or the admin has no Telegram ID on file - in that
+	// case only their enrolled TOTP secret can solve the challenge.
+	Code        string
+	Nonce       string `gorm:"uniqueIndex"`
+	Attempts    int
+	MaxAttempts int
+	Solved      bool
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// CreateTanChallenge persists a newly issued challenge.
+func CreateTanChallenge(db *gorm.DB, challenge *TanChallenge) error {
+	return db.Create(challenge).Error
+}
+
+// GetTanChallengeByNonce looks up a challenge by the opaque nonce the
+// client echoes back when solving it.
+func GetTanChallengeByNonce(db *gorm.DB, nonce string) (*TanChallenge, error) {
+	var challenge TanChallenge
+	if err := db.Where("nonce = ?", nonce).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// IncrementTanChallengeAttempts records a failed solve attempt.
+func IncrementTanChallengeAttempts(db *gorm.DB, id uint) error {
+	return db.Model(&TanChallenge{}).Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// MarkTanChallengeSolved flags a challenge used, so it can never be
+// replayed even against the exact same payload.
+func MarkTanChallengeSolved(db *gorm.DB, id uint) error {
+	return db.Model(&TanChallenge{}).Where("id = ?", id).Update("solved", true).Error
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TelegramLinkToken is a one-time enrollment token issued by the bot's
+// /link command: an admin DMs the bot, gets this token back, and pastes it
+// into the web UI to bind their Telegram chat ID to their AdminUser
+// account, the same two-step shape as TanChallenge uses for privileged
+// actions. TelegramID/TelegramUsername are the identity the bot observed
+// when /link was sent, so the web UI side can't be tricked into binding a
+// different chat than the one that actually requested the token.
+type TelegramLinkToken struct {
+	ID               uint `gorm:"primaryKey"`
+	Token            string `gorm:"uniqueIndex"`
+	TelegramID       int64
+	TelegramUsername string
+	Used             bool
+	ExpiresAt        time.Time
+	CreatedAt        time.Time
+}
+
+// CreateTelegramLinkToken persists a newly issued /link token.
+func CreateTelegramLinkToken(db *gorm.DB, token *TelegramLinkToken) error {
+	return db.Create(token).Error
+}
+
+// GetTelegramLinkToken looks up an unused, unexpired token by its value.
+func GetTelegramLinkToken(db *gorm.DB, token string) (*TelegramLinkToken, error) {
+	var t TelegramLinkToken
+	err := db.Where("token = ? AND used = ? AND expires_at > ?", token, false, time.Now()).First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// MarkTelegramLinkTokenUsed flags a token consumed so it can't be replayed.
+func MarkTelegramLinkTokenUsed(db *gorm.DB, id uint) error {
+	return db.Model(&TelegramLinkToken{}).Where("id = ?", id).Update("used", true).Error
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TelegramMessage remembers the Telegram message an order's status
+// notification was last sent as, so later status changes can edit that
+// same message (see bot.sendOrEditOrderMessage) instead of spamming the
+// buyer with a new one per transition, mirroring how TicketNotification
+// tracks the admin-side notification for a ticket.
+type TelegramMessage struct {
+	ID        uint `gorm:"primaryKey"`
+	OrderID   uint `gorm:"not null;uniqueIndex:idx_telegram_message_order_chat"`
+	ChatID    int64 `gorm:"not null;uniqueIndex:idx_telegram_message_order_chat"`
+	MessageID int   `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UpsertTelegramMessage records that orderID's status notification in
+// chatID now lives at messageID, replacing whatever was stored before.
+func UpsertTelegramMessage(db *gorm.DB, orderID uint, chatID int64, messageID int) error {
+	var existing TelegramMessage
+	err := db.Where("order_id = ? AND chat_id = ?", orderID, chatID).First(&existing).Error
+	if err == nil {
+		return db.Model(&existing).Update("message_id", messageID).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Create(&TelegramMessage{OrderID: orderID, ChatID: chatID, MessageID: messageID}).Error
+}
+
+// GetTelegramMessage returns the message ID last stored for orderID in
+// chatID, or gorm.ErrRecordNotFound if the order has never been notified
+// in that chat.
+func GetTelegramMessage(db *gorm.DB, orderID uint, chatID int64) (int, error) {
+	var entry TelegramMessage
+	if err := db.Where("order_id = ? AND chat_id = ?", orderID, chatID).First(&entry).Error; err != nil {
+		return 0, err
+	}
+	return entry.MessageID, nil
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TelegramSendJob is one durable outbound Telegram message, queued by
+// worker.TelegramSendQueue (see internal/worker) so a burst of sends (e.g. a
+// broadcast) can't blow past Telegram's flood limits - jobs sit here until
+// the queue's rate limiter lets them through, and failed 429/5xx responses
+// retry in place instead of being dropped.
+type TelegramSendJob struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChatID    int64  `gorm:"not null;index"`
+	Text      string `gorm:"not null"`
+	ParseMode string
+	// Status is one of "pending", "sent", "failed".
+	Status        string `gorm:"not null;default:pending;index"`
+	Attempts      int    `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	MessageID     int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// EnqueueTelegramSendJob queues text for delivery to chatID and returns the
+// job so its ID can be handed back to the caller for polling.
+func EnqueueTelegramSendJob(db *gorm.DB, chatID int64, text, parseMode string) (*TelegramSendJob, error) {
+	job := &TelegramSendJob{
+		ChatID:        chatID,
+		Text:          text,
+		ParseMode:     parseMode,
+		Status:        "pending",
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimDueTelegramSendJobs returns up to limit pending jobs whose
+// NextAttemptAt has passed, oldest first, for the queue's worker loop to
+// attempt this tick.
+func ClaimDueTelegramSendJobs(db *gorm.DB, limit int) ([]TelegramSendJob, error) {
+	var jobs []TelegramSendJob
+	err := db.Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("id asc").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// MarkTelegramSendJobSent records that jobID was delivered as messageID.
+func MarkTelegramSendJobSent(db *gorm.DB, jobID uint, messageID int) error {
+	return db.Model(&TelegramSendJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":     "sent",
+		"message_id": messageID,
+	}).Error
+}
+
+// MarkTelegramSendJobRetry schedules jobID to be retried at nextAttempt,
+// recording lastErr and bumping its attempt count.
+func MarkTelegramSendJobRetry(db *gorm.DB, jobID uint, nextAttempt time.Time, lastErr string) error {
+	return db.Model(&TelegramSendJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nextAttempt,
+		"last_error":      lastErr,
+	}).Error
+}
+
+// MarkTelegramSendJobFailed gives up on jobID after it exhausted its
+// retries, recording lastErr as the final failure reason.
+func MarkTelegramSendJobFailed(db *gorm.DB, jobID uint, lastErr string) error {
+	return db.Model(&TelegramSendJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"status":     "failed",
+		"last_error": lastErr,
+	}).Error
+}
+
+// GetTelegramSendJob looks up a single job by ID, for the admin to poll the
+// outcome of a job ID handed back by EnqueueTelegramSendJob.
+func GetTelegramSendJob(db *gorm.DB, jobID uint) (*TelegramSendJob, error) {
+	var job TelegramSendJob
+	if err := db.First(&job, jobID).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CountTelegramSendJobsByStatus reports how many jobs are currently in
+// status, for the queue depth/failure counts the admin API exposes.
+func CountTelegramSendJobsByStatus(db *gorm.DB, status string) (int64, error) {
+	var count int64
+	err := db.Model(&TelegramSendJob{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,42 @@
+package store
+
+import "gorm.io/gorm"
+
+// FindTicketByNumber looks up a ticket by its human-facing number (e.g.
+// "TK-20260727-001"), for the admin command router's /reply, /close, and
+// /assign commands.
+func FindTicketByNumber(db *gorm.DB, ticketNumber string) (*Ticket, error) {
+	var ticket Ticket
+	err := db.Where("ticket_id = ?", ticketNumber).First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ListOpenTickets returns every not-yet-closed ticket, newest first, for the
+// "/list open" admin command.
+func ListOpenTickets(db *gorm.DB) ([]Ticket, error) {
+	var tickets []Ticket
+	err := db.Where("status != ?", "closed").Order("created_at desc").Find(&tickets).Error
+	return tickets, err
+}
+
+// SearchableTickets returns every not-yet-closed ticket as candidates for
+// the admin command router's fuzzy /search.
+func SearchableTickets(db *gorm.DB) ([]Ticket, error) {
+	var tickets []Ticket
+	err := db.Where("status != ?", "closed").Find(&tickets).Error
+	return tickets, err
+}
+
+// CloseTicket marks a ticket resolved.
+func CloseTicket(db *gorm.DB, ticketID uint) error {
+	return db.Model(&Ticket{}).Where("id = ?", ticketID).Update("status", "closed").Error
+}
+
+// AssignTicket records which admin is handling a ticket, for the "/assign
+// <ticket> @admin" command.
+func AssignTicket(db *gorm.DB, ticketID uint, adminUsername string) error {
+	return db.Model(&Ticket{}).Where("id = ?", ticketID).Update("assigned_admin", adminUsername).Error
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketNotification links a ticket notification message posted into an
+// admin chat back to the ticket it's about, keyed by where Telegram actually
+// put it. This lets admin replies be resolved by reply-to-message metadata
+// instead of regex-scraping the notification text, which breaks on mojibake,
+// locale changes, or the admin editing the notification.
+type TicketNotification struct {
+	ID        uint `gorm:"primaryKey"`
+	ChatID    int64 `gorm:"not null;uniqueIndex:idx_ticket_notification_message"`
+	MessageID int   `gorm:"not null;uniqueIndex:idx_ticket_notification_message"`
+	TicketID  uint  `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// RecordTicketNotification remembers that a ticket notification for
+// ticketID was posted as messageID in chatID, for later lookup by reply-to.
+func RecordTicketNotification(db *gorm.DB, chatID int64, messageID int, ticketID uint) error {
+	return db.Create(&TicketNotification{
+		ChatID:    chatID,
+		MessageID: messageID,
+		TicketID:  ticketID,
+	}).Error
+}
+
+// GetTicketIDByNotification resolves the ticket a notification message
+// belongs to, given where it was posted.
+func GetTicketIDByNotification(db *gorm.DB, chatID int64, messageID int) (uint, error) {
+	var notification TicketNotification
+	err := db.Where("chat_id = ? AND message_id = ?", chatID, messageID).First(&notification).Error
+	if err != nil {
+		return 0, err
+	}
+	return notification.TicketID, nil
+}
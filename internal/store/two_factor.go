@@ -0,0 +1,74 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorSecret holds an admin's enrolled TOTP secret. Enabled stays false
+// until the admin confirms enrollment with a valid code, so a half-finished
+// /2fa_enroll can't lock them out or be mistaken for an active requirement.
+type TwoFactorSecret struct {
+	ID        uint `gorm:"primaryKey"`
+	AdminID   uint `gorm:"uniqueIndex"`
+	Secret    string
+	Enabled   bool
+	CreatedAt time.Time
+}
+
+// TwoFactorAuditLog records every TOTP check made against a sensitive admin
+// action (ticket close, refund, reply-on-behalf), so a compromised Telegram
+// account can't silently drain refunds without leaving a trail of failed or
+// skipped attempts.
+type TwoFactorAuditLog struct {
+	ID        uint `gorm:"primaryKey"`
+	AdminID   uint
+	Action    string
+	TicketID  *uint
+	Success   bool
+	CreatedAt time.Time
+}
+
+// GetTwoFactorSecret returns the admin's enrolled secret, or
+// gorm.ErrRecordNotFound if they haven't enrolled.
+func GetTwoFactorSecret(db *gorm.DB, adminID uint) (*TwoFactorSecret, error) {
+	var secret TwoFactorSecret
+	if err := db.Where("admin_id = ?", adminID).First(&secret).Error; err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// SaveTwoFactorSecret upserts the admin's pending (unconfirmed) secret,
+// replacing any prior enrollment attempt.
+func SaveTwoFactorSecret(db *gorm.DB, adminID uint, secret string) error {
+	var existing TwoFactorSecret
+	err := db.Where("admin_id = ?", adminID).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return db.Create(&TwoFactorSecret{AdminID: adminID, Secret: secret, Enabled: false}).Error
+	}
+	if err != nil {
+		return err
+	}
+	existing.Secret = secret
+	existing.Enabled = false
+	return db.Save(&existing).Error
+}
+
+// EnableTwoFactorSecret marks an admin's enrollment confirmed after they've
+// proven possession of the authenticator with one valid code.
+func EnableTwoFactorSecret(db *gorm.DB, adminID uint) error {
+	return db.Model(&TwoFactorSecret{}).Where("admin_id = ?", adminID).Update("enabled", true).Error
+}
+
+// RecordTwoFactorAttempt appends an audit entry for a TOTP check made
+// against a sensitive action, regardless of outcome.
+func RecordTwoFactorAttempt(db *gorm.DB, adminID uint, action string, ticketID *uint, success bool) error {
+	return db.Create(&TwoFactorAuditLog{
+		AdminID:  adminID,
+		Action:   action,
+		TicketID: ticketID,
+		Success:  success,
+	}).Error
+}
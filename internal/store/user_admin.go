@@ -0,0 +1,16 @@
+package store
+
+import "gorm.io/gorm"
+
+// BanUser flags a user banned by their Telegram ID, for the bot's /ban
+// admin command. Banned users are rejected wherever the bot checks
+// User.Banned before accepting an order/deposit (out of scope here - this
+// only flips the flag).
+func BanUser(db *gorm.DB, telegramID int64) error {
+	return db.Model(&User{}).Where("tg_user_id = ?", telegramID).Update("banned", true).Error
+}
+
+// UnbanUser clears the ban flag BanUser set.
+func UnbanUser(db *gorm.DB, telegramID int64) error {
+	return db.Model(&User{}).Where("tg_user_id = ?", telegramID).Update("banned", false).Error
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserChannel records one additional delivery channel (beyond Telegram) a
+// user can be reached on for ticket replies - e.g. "email", "slack",
+// "webhook" - along with the channel-specific address/target (an email
+// address, a Slack webhook URL, a generic webhook URL).
+type UserChannel struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Channel   string `gorm:"not null;index"`
+	Target    string `gorm:"not null"`
+	Enabled   bool   `gorm:"not null;default:true"`
+	CreatedAt time.Time
+}
+
+// AddUserChannel registers (or re-enables, if it already exists) a delivery
+// channel for userID.
+func AddUserChannel(db *gorm.DB, userID uint, channel, target string) error {
+	var existing UserChannel
+	err := db.Where("user_id = ? AND channel = ?", userID, channel).First(&existing).Error
+	if err == nil {
+		return db.Model(&existing).Updates(map[string]interface{}{"target": target, "enabled": true}).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return db.Create(&UserChannel{UserID: userID, Channel: channel, Target: target, Enabled: true}).Error
+}
+
+// GetEnabledChannelsForUser returns every channel userID has enabled, for
+// fanning a ticket reply out across all of them.
+func GetEnabledChannelsForUser(db *gorm.DB, userID uint) ([]UserChannel, error) {
+	var channels []UserChannel
+	err := db.Where("user_id = ? AND enabled = ?", userID, true).Find(&channels).Error
+	return channels, err
+}
+
+// GetUserChannel looks up a specific channel for userID regardless of its
+// enabled flag, for failure-recovery fallbacks (e.g. Telegram delivery
+// failing over to email even if email isn't the user's primary channel).
+func GetUserChannel(db *gorm.DB, userID uint, channel string) (*UserChannel, error) {
+	var found UserChannel
+	err := db.Where("user_id = ? AND channel = ?", userID, channel).First(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	return &found, nil
+}
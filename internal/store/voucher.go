@@ -0,0 +1,158 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Voucher is a promotional code redeemable against one order. DiscountType
+// controls how ValueCents is interpreted:
+//   - "percent": ValueCents is the discount in basis points (1/100 of a
+//     percent), e.g. 1000 = 10% off - named ValueCents rather than
+//     ValuePercent so the column stays a plain int across all three
+//     discount types.
+//   - "fixed": ValueCents is knocked straight off the order amount, in
+//     cents.
+//   - "free_slot": the order is fully discounted; ValueCents is ignored.
+//
+// ProductScope restricts the voucher to one product; nil applies it to any
+// product. UsageLimit is the total number of redemptions allowed across all
+// users (0 = unlimited); PerUserLimit caps redemptions per user (0 =
+// unlimited).
+type Voucher struct {
+	ID           uint `gorm:"primaryKey"`
+	Code         string `gorm:"uniqueIndex"`
+	DiscountType string
+	ValueCents   int
+	ProductScope *uint
+	UsageLimit   int
+	PerUserLimit int
+	UsedCount    int
+	ValidFrom    time.Time
+	ValidUntil   time.Time
+	IsActive     bool
+}
+
+func (Voucher) TableName() string { return "vouchers" }
+
+// VoucherRedemption records one use of a Voucher against an order.
+type VoucherRedemption struct {
+	ID        uint `gorm:"primaryKey"`
+	VoucherID uint `gorm:"index"`
+	UserID    uint `gorm:"index"`
+	OrderID   uint `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (VoucherRedemption) TableName() string { return "voucher_redemptions" }
+
+var (
+	ErrVoucherNotFound      = errors.New("voucher not found or inactive")
+	ErrVoucherNotYetValid   = errors.New("voucher is not valid yet")
+	ErrVoucherExpired       = errors.New("voucher has expired")
+	ErrVoucherScopeMismatch = errors.New("voucher does not apply to this product")
+	ErrVoucherExhausted     = errors.New("voucher has reached its usage limit")
+	ErrVoucherPerUserLimit  = errors.New("voucher has already been redeemed the maximum number of times by this user")
+)
+
+// ApplyVoucherTx validates code against userID and productID, atomically
+// claims one redemption slot (so two concurrent requests for a coupon's
+// last slot can't both succeed), and returns the discounted amountCents
+// plus an unsaved VoucherRedemption for the caller to attach an OrderID to
+// and persist once the order row exists. If validation fails, the slot is
+// never claimed and amountCents is returned unchanged. Must run inside tx.
+func ApplyVoucherTx(tx *gorm.DB, userID uint, productID uint, amountCents int, code string) (int, *VoucherRedemption, error) {
+	var voucher Voucher
+	if err := tx.Where("code = ? AND is_active = ?", code, true).First(&voucher).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return amountCents, nil, ErrVoucherNotFound
+		}
+		return amountCents, nil, fmt.Errorf("apply voucher: load voucher: %w", err)
+	}
+
+	now := time.Now()
+	if !voucher.ValidFrom.IsZero() && now.Before(voucher.ValidFrom) {
+		return amountCents, nil, ErrVoucherNotYetValid
+	}
+	if !voucher.ValidUntil.IsZero() && now.After(voucher.ValidUntil) {
+		return amountCents, nil, ErrVoucherExpired
+	}
+	if voucher.ProductScope != nil && *voucher.ProductScope != productID {
+		return amountCents, nil, ErrVoucherScopeMismatch
+	}
+
+	if voucher.PerUserLimit > 0 {
+		var userRedemptions int64
+		if err := tx.Model(&VoucherRedemption{}).
+			Where("voucher_id = ? AND user_id = ?", voucher.ID, userID).
+			Count(&userRedemptions).Error; err != nil {
+			return amountCents, nil, fmt.Errorf("apply voucher: count user redemptions: %w", err)
+		}
+		if int(userRedemptions) >= voucher.PerUserLimit {
+			return amountCents, nil, ErrVoucherPerUserLimit
+		}
+	}
+
+	if voucher.UsageLimit > 0 {
+		result := tx.Model(&Voucher{}).
+			Where("id = ? AND used_count < ?", voucher.ID, voucher.UsageLimit).
+			Update("used_count", gorm.Expr("used_count + 1"))
+		if result.Error != nil {
+			return amountCents, nil, fmt.Errorf("apply voucher: claim slot: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return amountCents, nil, ErrVoucherExhausted
+		}
+	} else {
+		if err := tx.Model(&Voucher{}).Where("id = ?", voucher.ID).
+			Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+			return amountCents, nil, fmt.Errorf("apply voucher: claim slot: %w", err)
+		}
+	}
+
+	discounted := amountCents
+	switch voucher.DiscountType {
+	case "percent":
+		discounted = amountCents - (amountCents*voucher.ValueCents)/10000
+	case "fixed":
+		discounted = amountCents - voucher.ValueCents
+	case "free_slot":
+		discounted = 0
+	}
+	if discounted < 0 {
+		discounted = 0
+	}
+
+	redemption := &VoucherRedemption{VoucherID: voucher.ID, UserID: userID}
+	return discounted, redemption, nil
+}
+
+// ReleaseVoucherRedemptionForOrder undoes a voucher redemption tied to
+// orderID - decrementing the voucher's used_count and removing the
+// redemption row - so cancelling an order returns its coupon slot to the
+// pool instead of leaking it.
+func ReleaseVoucherRedemptionForOrder(db *gorm.DB, orderID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var redemption VoucherRedemption
+		err := tx.Where("order_id = ?", orderID).First(&redemption).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("release voucher redemption: load redemption: %w", err)
+		}
+
+		if err := tx.Model(&Voucher{}).Where("id = ? AND used_count > 0", redemption.VoucherID).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return fmt.Errorf("release voucher redemption: decrement used_count: %w", err)
+		}
+
+		if err := tx.Delete(&redemption).Error; err != nil {
+			return fmt.Errorf("release voucher redemption: delete redemption: %w", err)
+		}
+		return nil
+	})
+}
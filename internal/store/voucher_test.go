@@ -0,0 +1,72 @@
+package store
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open in-memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&Voucher{}, &VoucherRedemption{}); err != nil {
+		t.Fatalf("automigrate: %v", err)
+	}
+	return db
+}
+
+// TestApplyVoucherTx_ConcurrentRedemptions exercises the claim this
+// function exists for: a voucher with exactly one redemption left should
+// never be claimed twice, even when two requests for it race. Regression
+// guard for the atomic "UPDATE ... WHERE used_count < ?" claim - a
+// read-then-write version of this check would let both callers in.
+func TestApplyVoucherTx_ConcurrentRedemptions(t *testing.T) {
+	db := openTestDB(t)
+
+	voucher := Voucher{Code: "RACE10", DiscountType: "fixed", ValueCents: 100, UsageLimit: 1, IsActive: true}
+	if err := db.Create(&voucher).Error; err != nil {
+		t.Fatalf("create voucher: %v", err)
+	}
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	results := make([]error, attempts)
+
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := db.Transaction(func(tx *gorm.DB) error {
+				_, _, err := ApplyVoucherTx(tx, uint(i+1), 1, 1000, "RACE10")
+				return err
+			})
+			results[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range results {
+		if err == nil {
+			succeeded++
+		} else if err != ErrVoucherExhausted {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly 1 successful redemption out of %d attempts, got %d", attempts, succeeded)
+	}
+
+	var reloaded Voucher
+	if err := db.First(&reloaded, voucher.ID).Error; err != nil {
+		t.Fatalf("reload voucher: %v", err)
+	}
+	if reloaded.UsedCount != 1 {
+		t.Fatalf("expected used_count 1, got %d", reloaded.UsedCount)
+	}
+}
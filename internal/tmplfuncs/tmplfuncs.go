@@ -0,0 +1,87 @@
+// Package tmplfuncs holds the html/template helper functions shared by every
+// place that renders admin-facing templates (the main admin router, and the
+// broadcast preview endpoint), so currency formatting and the arithmetic
+// helpers stay consistent wherever a template is rendered.
+package tmplfuncs
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+)
+
+// ToFloat64 converts common numeric (and numeric-string) types to float64,
+// for use inside template helper funcs that receive interface{} operands.
+func ToFloat64(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case int:
+		return float64(val), nil
+	case int32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case uint:
+		return float64(val), nil
+	case uint32:
+		return float64(val), nil
+	case uint64:
+		return float64(val), nil
+	case string:
+		return strconv.ParseFloat(val, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to float64", v)
+	}
+}
+
+// FuncMap returns the template.FuncMap used by the admin router, bound to
+// currencySymbol so templates can call {{currency}} without it being
+// threaded through every render call.
+func FuncMap(currencySymbol string) template.FuncMap {
+	return template.FuncMap{
+		"divf": func(a, b interface{}) float64 {
+			af, _ := ToFloat64(a)
+			bf, _ := ToFloat64(b)
+			if bf == 0 {
+				return 0
+			}
+			return af / bf
+		},
+		"addf": func(a, b interface{}) float64 {
+			af, _ := ToFloat64(a)
+			bf, _ := ToFloat64(b)
+			return af + bf
+		},
+		"subf": func(a, b interface{}) float64 {
+			af, _ := ToFloat64(a)
+			bf, _ := ToFloat64(b)
+			return af - bf
+		},
+		"int": func(a interface{}) int {
+			f, _ := ToFloat64(a)
+			return int(f)
+		},
+		"seq": func(start, end int) []int {
+			var result []int
+			for i := start; i <= end; i++ {
+				result = append(result, i)
+			}
+			return result
+		},
+		"currency": func() string {
+			return currencySymbol
+		},
+		"plus": func(a, b int) int {
+			return a + b
+		},
+		"minus": func(a, b int) int {
+			return a - b
+		},
+		"multiply": func(a, b int) int {
+			return a * b
+		},
+	}
+}
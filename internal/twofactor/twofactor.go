@@ -0,0 +1,61 @@
+// Package twofactor wraps TOTP enrollment and validation for privileged
+// admin actions (closing tickets, issuing refunds, replying on behalf of
+// another admin). It's deliberately thin over pquerna/otp so the bot
+// package only ever deals with secrets/codes as strings.
+package twofactor
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/pquerna/otp/totp"
+)
+
+// Issuer is embedded in the otpauth:// URL so the code shows up under a
+// recognizable name in the admin's authenticator app.
+const Issuer = "SUFE Shop Bot"
+
+// GenerateSecret creates a new TOTP secret for accountName (the admin's
+// username) and returns it alongside the otpauth:// URL to render as a QR
+// code for enrollment.
+func GenerateSecret(accountName string) (secret string, otpauthURL string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      Issuer,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("generate totp key: %w", err)
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// Validate checks code against secret for the current time step, accepting
+// the standard pquerna/otp +/-1 step skew for clock drift.
+func Validate(secret, code string) bool {
+	if secret == "" || code == "" {
+		return false
+	}
+	return totp.Validate(code, secret)
+}
+
+// QRCodePNG renders otpauthURL as a PNG QR code of size x size pixels, for
+// sending as a Telegram photo during enrollment.
+func QRCodePNG(otpauthURL string, size int) ([]byte, error) {
+	code, err := qr.Encode(otpauthURL, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+	scaled, err := barcode.Scale(code, size, size)
+	if err != nil {
+		return nil, fmt.Errorf("scale qr code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, fmt.Errorf("encode qr png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
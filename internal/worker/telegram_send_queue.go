@@ -0,0 +1,148 @@
+// Package worker holds background workers that poll the database for due
+// work on a ticker, the same shape as RetryWorker/OrderMaintenanceWorker:
+// a NewXxx(db, ...) constructor and a blocking Start(ctx) loop app.Start
+// runs in its own goroutine.
+package worker
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	logger "shop-bot/internal/log"
+	"shop-bot/internal/ratelimit"
+	"shop-bot/internal/store"
+	"gorm.io/gorm"
+)
+
+const (
+	telegramQueuePollInterval = 200 * time.Millisecond
+	telegramQueueBatchSize    = 50
+	telegramQueueMaxAttempts  = 5
+)
+
+// TelegramSendQueue is a durable, rate-limited worker pool for outbound
+// Telegram sends. Jobs are persisted via store.TelegramSendJob so a crash
+// mid-broadcast doesn't lose queued messages, and two token buckets
+// (ratelimit.Limiter, the same primitive bot uses for per-user throttling)
+// enforce Telegram's global 30 msg/sec and per-chat 1 msg/sec limits so a
+// broadcast can't trip flood control.
+type TelegramSendQueue struct {
+	db      *gorm.DB
+	api     *tgbotapi.BotAPI
+	global  *ratelimit.Limiter
+	perChat *ratelimit.Limiter
+}
+
+// NewTelegramSendQueue builds a queue that sends through api, backed by db.
+func NewTelegramSendQueue(db *gorm.DB, api *tgbotapi.BotAPI) *TelegramSendQueue {
+	return &TelegramSendQueue{
+		db:      db,
+		api:     api,
+		global:  ratelimit.NewLimiter(30, 30),
+		perChat: ratelimit.NewLimiter(1, 1),
+	}
+}
+
+// Enqueue queues text for delivery to chatID and returns the job ID an
+// admin can later poll via store.GetTelegramSendJob.
+func (q *TelegramSendQueue) Enqueue(chatID int64, text, parseMode string) (uint, error) {
+	job, err := store.EnqueueTelegramSendJob(q.db, chatID, text, parseMode)
+	if err != nil {
+		return 0, err
+	}
+	return job.ID, nil
+}
+
+// Start polls for due jobs and sends them until ctx is cancelled.
+func (q *TelegramSendQueue) Start(ctx context.Context) {
+	ticker := time.NewTicker(telegramQueuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.drain()
+		}
+	}
+}
+
+// drain attempts every currently-due job once, skipping (not consuming a
+// retry) any job whose chat is still within its per-chat cooldown, and
+// stopping the tick entirely once the global bucket is empty so Telegram
+// never sees more than 30 msg/sec across all chats.
+func (q *TelegramSendQueue) drain() {
+	jobs, err := store.ClaimDueTelegramSendJobs(q.db, telegramQueueBatchSize)
+	if err != nil {
+		logger.Error("Failed to claim due Telegram send jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if !q.global.Allow("global") {
+			return
+		}
+		if !q.perChat.Allow(strconv.FormatInt(job.ChatID, 10)) {
+			continue
+		}
+		q.send(job)
+	}
+}
+
+func (q *TelegramSendQueue) send(job store.TelegramSendJob) {
+	msg := tgbotapi.NewMessage(job.ChatID, job.Text)
+	msg.ParseMode = job.ParseMode
+
+	resp, err := q.api.Send(msg)
+	if err == nil {
+		if err := store.MarkTelegramSendJobSent(q.db, job.ID, resp.MessageID); err != nil {
+			logger.Error("Failed to mark Telegram send job sent", "error", err, "job_id", job.ID)
+		}
+		return
+	}
+
+	if apiErr, ok := err.(*tgbotapi.Error); ok {
+		if apiErr.Code == 429 {
+			retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+			if retryAfter <= 0 {
+				retryAfter = 5 * time.Second
+			}
+			q.retryOrFail(job, retryAfter, err)
+			return
+		}
+		if apiErr.Code >= 500 {
+			q.retryOrFail(job, backoffFor(job.Attempts), err)
+			return
+		}
+	}
+
+	// Not a rate-limit or transient gateway error - e.g. "chat not found",
+	// retrying won't help, but still back off a couple of times in case
+	// it's a blip, then give up.
+	q.retryOrFail(job, backoffFor(job.Attempts), err)
+}
+
+func (q *TelegramSendQueue) retryOrFail(job store.TelegramSendJob, delay time.Duration, sendErr error) {
+	if job.Attempts+1 >= telegramQueueMaxAttempts {
+		if err := store.MarkTelegramSendJobFailed(q.db, job.ID, sendErr.Error()); err != nil {
+			logger.Error("Failed to mark Telegram send job failed", "error", err, "job_id", job.ID)
+		}
+		return
+	}
+	if err := store.MarkTelegramSendJobRetry(q.db, job.ID, time.Now().Add(delay), sendErr.Error()); err != nil {
+		logger.Error("Failed to reschedule Telegram send job", "error", err, "job_id", job.ID)
+	}
+}
+
+// backoffFor returns an exponential backoff capped at 64s, doubling per
+// attempt already made.
+func backoffFor(attempts int) time.Duration {
+	if attempts > 6 {
+		attempts = 6
+	}
+	return time.Duration(1<<uint(attempts)) * time.Second
+}